@@ -0,0 +1,49 @@
+# Amend mode ('aicommit -amend'): the message of HEAD is written again for
+# HEAD's changes plus whatever is staged now, and git commit --amend
+# replaces the commit. Commits that are already on a remote are left alone
+# unless the user insists, since amending them rewrites published history.
+
+function Get-AICommitAmendBase {
+    # The commit HEAD is compared to: its first parent, or the empty tree
+    # when HEAD is the root commit
+    git rev-parse -q --verify "HEAD~1" 2>$null | Out-Null
+    if ($LASTEXITCODE -eq 0) {
+        return "HEAD~1"
+    }
+    return "$(git hash-object -t tree /dev/null)".Trim()
+}
+
+function Test-AICommitAmendSafe {
+    # $true when there is a commit to amend and it hasn't been pushed (or
+    # the user confirmed amending it anyway)
+    git rev-parse -q --verify HEAD 2>$null | Out-Null
+    if ($LASTEXITCODE -ne 0) {
+        Write-AICommitError -Message "There is no commit to amend yet" -Kind "NoChanges"
+        return $false
+    }
+    git rev-parse -q --verify "HEAD^2" 2>$null | Out-Null
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Error: HEAD is a merge commit; amend it with git commit --amend" -ForegroundColor Red
+        return $false
+    }
+
+    $remoteBranches = @(git branch -r --contains HEAD 2>$null | ForEach-Object { $_.Trim() } | Where-Object { $_ -and $_ -notmatch ' -> ' })
+    if ($remoteBranches.Count -eq 0) {
+        return $true
+    }
+    Write-Host "Warning: HEAD is already pushed to $($remoteBranches -join ', '). Amending rewrites published history and needs a force push" -ForegroundColor Yellow
+    Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "amend_pushed" }
+    $answer = "$(Read-Host "Amend anyway? (y/N)")".Trim().ToLower()
+    if ($answer -notin @('y', 'yes')) {
+        Write-Host "Amend cancelled; make a new commit instead" -ForegroundColor Yellow
+        return $false
+    }
+    return $true
+}
+
+function Get-AICommitAmendContext {
+    # Prompt context: the message being replaced, so the new one keeps
+    # what still applies
+    $previous = ((git log -1 --format=%B HEAD) -join "`n").Trim()
+    return "This diff replaces the last commit: it holds that commit's changes plus newly staged ones. Write one message for all of it. The last commit's message was:`n$previous"
+}
@@ -0,0 +1,68 @@
+# Skipping the review in trusted setups (AI_COMMIT_AUTO_ACCEPT), e.g.
+# personal scratch repositories. Review stays the default, and protected
+# branches and high-impact changes are always reviewed.
+
+function Get-AICommitMessageScore {
+    # Rough quality score from 0 to 1: the header checks the editor uses,
+    # plus a description that says something
+    param([pscustomobject]$Message)
+
+    $score = 1.0
+    $header = Get-AICommitMessageHeader -Message $Message
+    $score -= 0.3 * (Test-AICommitHeader -Header $header).Count
+    # Headers that could describe any commit
+    if ($Message.Subject -match '^(update|fix|change|modify|wip|misc|cleanup)( (files?|code|stuff|bugs?|things|changes))?$') {
+        $score -= 0.3
+    }
+    $words = @("$($Message.Body)" -split '\s+' | Where-Object { $_ }).Count
+    if ($words -eq 0) {
+        $score -= 0.2
+    } elseif ($words -lt 5) {
+        $score -= 0.1
+    }
+    return [Math]::Max(0.0, [Math]::Round($score, 2))
+}
+
+function Test-AICommitProtectedBranch {
+    # AI_COMMIT_PROTECTED_BRANCHES: comma-separated names or wildcards
+    # (default: main,master)
+    $branch = "$(git rev-parse --abbrev-ref HEAD 2>$null)".Trim()
+    $patterns = @("$(Get-AICommitSetting -Name "AI_COMMIT_PROTECTED_BRANCHES" -Default "main,master")" -split ',' | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+    return (@($patterns | Where-Object { $branch -like $_ }).Count -gt 0)
+}
+
+function Test-AICommitAutoAccept {
+    # True when the message may be committed without review.
+    # AI_COMMIT_AUTO_ACCEPT: true, or a minimum score such as "score>=0.8".
+    param([pscustomobject]$Message)
+
+    $setting = "$(Get-AICommitSetting -Name "AI_COMMIT_AUTO_ACCEPT")".Trim().ToLower()
+    if (!$setting -or $setting -in @('false', 'no', 'off', '0')) {
+        return $false
+    }
+    if ($setting -in @('true', 'yes', 'on', '1')) {
+        $threshold = 0.0
+    } elseif ($setting -match '^score\s*>=\s*(\d*\.?\d+)$') {
+        $threshold = [double]::Parse($Matches[1], [System.Globalization.CultureInfo]::InvariantCulture)
+    } else {
+        Write-Host "Warning: Unknown AI_COMMIT_AUTO_ACCEPT '$setting' - use true or score>=0.8" -ForegroundColor Yellow
+        return $false
+    }
+
+    if (Test-AICommitProtectedBranch) {
+        Write-Host "Note: Not auto-accepting on a protected branch (AI_COMMIT_PROTECTED_BRANCHES)" -ForegroundColor Yellow
+        return $false
+    }
+    if (@($Message.Footers | Where-Object { $_.Token -eq "Risk" }).Count -gt 0) {
+        Write-Host "Note: Not auto-accepting a high-impact change" -ForegroundColor Yellow
+        return $false
+    }
+
+    $score = Get-AICommitMessageScore -Message $Message
+    if ($score -lt $threshold) {
+        Write-Host "Note: Message score $score is below $threshold, please review it" -ForegroundColor Yellow
+        return $false
+    }
+    Write-Host "Auto-accepted (score $score, AI_COMMIT_AUTO_ACCEPT)" -ForegroundColor Green
+    return $true
+}
@@ -0,0 +1,147 @@
+# Breaking change detection (AI_COMMIT_BREAKING_DETECTION). Heuristics spot
+# removed or changed public API and major version bumps; the model then
+# decides whether callers really break and describes it in a
+# "BREAKING CHANGE:" footer. 'aicommit -breaking' skips the guessing.
+
+# Public symbols per file type: the pattern's "name" group is the symbol
+$script:AICommitPublicSymbolPatterns = @(
+    @{ Files = @("*.go"); Pattern = '^(func\s*(\([^)]*\)\s*)?|type\s+|var\s+|const\s+)(?<name>[A-Z]\w*)' }
+    @{ Files = @("*.ts", "*.tsx", "*.js", "*.jsx", "*.mjs"); Pattern = '^export\s+(default\s+)?(async\s+)?(function\*?|class|const|let|var|interface|type|enum)\s+(?<name>[A-Za-z_$][\w$]*)' }
+    @{ Files = @("Public/*.ps1", "*/Public/*.ps1"); Pattern = '^function\s+(?<name>[\w-]+)' }
+)
+
+function Get-AICommitPublicSymbols {
+    # Symbol name -> declaration line, from the added (+) or removed (-)
+    # lines of a file's hunks
+    param(
+        [pscustomobject]$File,
+        [string]$Side
+    )
+
+    $symbols = @{}
+    $definition = $script:AICommitPublicSymbolPatterns | Where-Object { $path = $File.Path; @($_.Files | Where-Object { $path -like $_ }).Count -gt 0 } | Select-Object -First 1
+    if ($null -eq $definition) {
+        return $symbols
+    }
+    foreach ($hunk in $File.Hunks) {
+        foreach ($line in $hunk.Lines) {
+            if (!$line.StartsWith($Side)) {
+                continue
+            }
+            $code = $line.Substring(1).Trim()
+            if ($code -cmatch $definition.Pattern -and !$symbols.ContainsKey($Matches.name)) {
+                # Whitespace differences don't change a signature
+                $symbols[$Matches.name] = $code -replace '\s+', ' ' -replace '\s*\{$', ''
+            }
+        }
+    }
+    return $symbols
+}
+
+function Get-AICommitMajorVersion {
+    # The major version on the first added (+) or removed (-) version line
+    # of a version file, package manifest or go.mod; $null if there is none
+    param(
+        [pscustomobject]$File,
+        [string]$Side
+    )
+
+    $leaf = Split-Path $File.Path -Leaf
+    $pattern = if ($leaf -match '^VERSION(\.txt)?$') {
+        '^v?(?<major>\d+)\.\d+'
+    } elseif ($leaf -eq "go.mod") {
+        '^module\s+\S+?(/v(?<major>\d+))?\s*$'
+    } else {
+        '^\s*("version"\s*:\s*"|version\s*=\s*["'']|ModuleVersion\s*=\s*["''])v?(?<major>\d+)\.\d+'
+    }
+    foreach ($hunk in $File.Hunks) {
+        foreach ($line in $hunk.Lines) {
+            if ($line.StartsWith($Side) -and $line.Substring(1) -match $pattern) {
+                # go.mod paths without /vN are version 0 or 1
+                return $(if ($Matches.major) { [int]$Matches.major } else { 1 })
+            }
+        }
+    }
+    return $null
+}
+
+function Get-AICommitBreakingReasons {
+    # Returns why the diff may break callers; empty when nothing points to it
+    param([string]$Diff)
+
+    $removed = @()
+    $changed = @()
+    $bumps = @()
+    foreach ($file in ConvertFrom-AICommitDiff -Diff $Diff) {
+        $before = Get-AICommitPublicSymbols -File $file -Side "-"
+        $after = Get-AICommitPublicSymbols -File $file -Side "+"
+        foreach ($name in $before.Keys) {
+            if (!$after.ContainsKey($name)) {
+                $removed += "$name ($($file.Path))"
+            } elseif ($after[$name] -ne $before[$name]) {
+                $changed += "$name ($($file.Path))"
+            }
+        }
+
+        $oldMajor = Get-AICommitMajorVersion -File $file -Side "-"
+        $newMajor = Get-AICommitMajorVersion -File $file -Side "+"
+        if ($null -ne $oldMajor -and $null -ne $newMajor -and $newMajor -gt $oldMajor) {
+            $bumps += "$($file.Path) ($oldMajor to $newMajor)"
+        }
+    }
+
+    $reasons = @()
+    if ($removed.Count -gt 0) {
+        $reasons += "removes public API: $(($removed | Select-Object -First 5) -join ', ')"
+    }
+    if ($changed.Count -gt 0) {
+        $reasons += "changes public signatures: $(($changed | Select-Object -First 5) -join ', ')"
+    }
+    if ($bumps.Count -gt 0) {
+        $reasons += "bumps the major version in $($bumps -join ', ')"
+    }
+    return ,$reasons
+}
+
+function Add-AICommitBreakingFooter {
+    # Marks the message as breaking with the model's "BREAKING CHANGE:"
+    # sentence. A "none" answer adds nothing unless -Force (-breaking).
+    param(
+        [pscustomobject]$Message,
+        [string]$Breaking,
+        [switch]$Force
+    )
+
+    if ("$Breaking".Trim() -match '^(none|no|n/?a)?\.?$') {
+        if (!$Force) {
+            return
+        }
+        $Breaking = "This change is not backwards compatible"
+    }
+    $Message.Breaking = $true
+    if (@($Message.Footers | Where-Object { $_.Token -in @("BREAKING CHANGE", "BREAKING-CHANGE") }).Count -eq 0) {
+        Add-AICommitMessageFooter -Message $Message -Token "BREAKING CHANGE" -Value $Breaking
+    }
+}
+
+function Get-AICommitBreakingCheck {
+    # The reasons to hand to New-AICommitPrompt: the heuristics' findings,
+    # or the author's word with -Force
+    param(
+        [string]$Diff,
+        [switch]$Force
+    )
+
+    if ($Force) {
+        return ,@("is marked as breaking by the author, so do not answer none")
+    }
+    $setting = "$(Get-AICommitSetting -Name "AI_COMMIT_BREAKING_DETECTION" -Default "true")".Trim().ToLower()
+    if ($setting -in @('false', 'no', 'off', '0')) {
+        return ,@()
+    }
+    $reasons = Get-AICommitBreakingReasons -Diff $Diff
+    if ($reasons.Count -gt 0) {
+        Write-Host "Possible breaking change, asking the AI to check: $($reasons -join '; ')" -ForegroundColor Yellow
+    }
+    return ,$reasons
+}
@@ -0,0 +1,46 @@
+# Co-authored-by trailers for pairing and mobbing: -coAuthor for this run
+# plus the AI_COMMIT_CO_AUTHORS list, in the "Name <email>" form GitHub
+# and GitLab recognize.
+
+function Get-AICommitCoAuthors {
+    # -CoAuthor entries first, then AI_COMMIT_CO_AUTHORS (separated by ';'),
+    # without duplicate addresses. Entries that aren't "Name <email>" are
+    # skipped with a warning.
+    param([string[]]$CoAuthor)
+
+    $configured = @("$(Get-AICommitSetting -Name "AI_COMMIT_CO_AUTHORS")" -split ';')
+    $coAuthors = New-Object System.Collections.Generic.List[string]
+    $emails = @()
+    foreach ($entry in @($CoAuthor) + $configured) {
+        if ([string]::IsNullOrWhiteSpace($entry)) {
+            continue
+        }
+        if ($entry.Trim() -notmatch '^(?<name>[^<>]+?)\s*<(?<email>[^<>\s]+@[^<>\s]+)>$') {
+            Write-Host "Warning: Ignoring co-author '$($entry.Trim())' - use the form 'Name <email>'" -ForegroundColor Yellow
+            continue
+        }
+        if ($emails -contains $Matches.email.ToLower()) {
+            continue
+        }
+        $emails += $Matches.email.ToLower()
+        $coAuthors.Add("$($Matches.name.Trim()) <$($Matches.email)>")
+    }
+    return ,$coAuthors.ToArray()
+}
+
+function Add-AICommitCoAuthors {
+    # Adds a Co-authored-by trailer per co-author the message doesn't
+    # already credit (e.g. after an edit in review)
+    param(
+        [pscustomobject]$Message,
+        [string[]]$CoAuthors
+    )
+
+    foreach ($coAuthor in $CoAuthors) {
+        $email = [regex]::Match($coAuthor, '<([^>]+)>').Groups[1].Value
+        $credited = @($Message.Footers | Where-Object { $_.Token -eq "Co-authored-by" -and $_.Value -match "<$([regex]::Escape($email))>" }).Count -gt 0
+        if (!$credited) {
+            Add-AICommitMessageFooter -Message $Message -Token "Co-authored-by" -Value $coAuthor
+        }
+    }
+}
@@ -0,0 +1,840 @@
+function Test-AICommitReasoningModel {
+    param([string]$Model)
+
+    # o1/o3/o4 and gpt-5 think before answering and take a reasoning effort
+    return ($Model -match '^o\d' -or $Model -like "gpt-5*")
+}
+
+function Get-AICommitGenerationSetting {
+    # TEMPERATURE, TOP_P or MAX_TOKENS for a provider: AI_COMMIT_<PROVIDER>_<NAME>
+    # (e.g. AI_COMMIT_OPENAI_TEMPERATURE), else AI_COMMIT_<NAME>. $null when
+    # neither is set, so the model's own default applies.
+    param(
+        [string]$Carrier,
+        [string]$Name
+    )
+
+    $names = @("AI_COMMIT_$($Carrier.ToUpper())_$Name")
+    if ($Carrier -in @("google", "vertex")) {
+        # The older Gemini names keep working
+        $legacy = if ($Name -eq "MAX_TOKENS") { "MAX_OUTPUT_TOKENS" } else { $Name }
+        $names += "AI_COMMIT_GEMINI_$legacy"
+    }
+    $names += "AI_COMMIT_$Name"
+
+    foreach ($settingName in $names) {
+        $value = Get-AICommitSetting -Name $settingName
+        if (![string]::IsNullOrWhiteSpace($value)) {
+            if ($Name -eq "MAX_TOKENS") {
+                return [int]$value
+            }
+            return [double]::Parse($value, [System.Globalization.CultureInfo]::InvariantCulture)
+        }
+    }
+    return $null
+}
+
+function Get-AICommitFakeSuggestion {
+    # Deterministic answer built from the file names in the prompt, so the
+    # whole pipeline can run without network access or an API key
+    param([string]$Prompt)
+
+    $files = @()
+    foreach ($line in $Prompt -split "`n") {
+        if ($line -match '^diff --git a/.+ b/(.+)$') {
+            $files += $Matches[1].Trim()
+        }
+    }
+    $files = @($files | Select-Object -Unique)
+
+    if ($files.Count -eq 0) {
+        return "HEADER: Update project files`n`nDESCRIPTION: Generated by the fake provider without analyzing the diff"
+    }
+    $noun = if ($files.Count -eq 1) { "file" } else { "files" }
+    return "HEADER: Update $($files.Count) $noun`n`nDESCRIPTION: Changes $($files -join ', ') (generated by the fake provider)"
+}
+
+function Get-AICommitOllamaUrl {
+    return (Get-AICommitSetting -Name "AI_COMMIT_OLLAMA_URL" -Default "http://localhost:11434").TrimEnd("/")
+}
+
+function Get-AICommitOpenRouterUrl {
+    return (Get-AICommitSetting -Name "AI_COMMIT_OPENROUTER_URL" -Default "https://openrouter.ai/api/v1").TrimEnd("/")
+}
+
+function Get-AICommitCustomUrl {
+    # Base URL of the OpenAI-compatible server, e.g. http://localhost:8000/v1
+    $baseUrl = Get-AICommitSetting -Name "AI_COMMIT_CUSTOM_BASE_URL"
+    if ([string]::IsNullOrWhiteSpace($baseUrl)) {
+        Write-Host "Error: Set AI_COMMIT_CUSTOM_BASE_URL for the custom provider (e.g. http://localhost:8000/v1)" -ForegroundColor Red
+        return $null
+    }
+    return $baseUrl.TrimEnd("/")
+}
+
+function Get-AICommitVertexEndpoint {
+    # Project and region for Vertex AI; the project defaults to gcloud's
+    param([string]$Model)
+
+    $project = Get-AICommitSetting -Name "AI_COMMIT_VERTEX_PROJECT"
+    if ([string]::IsNullOrWhiteSpace($project) -and (Get-Command gcloud -ErrorAction SilentlyContinue)) {
+        $project = "$(gcloud config get-value project 2>$null)".Trim()
+    }
+    if ([string]::IsNullOrWhiteSpace($project)) {
+        Write-Host "Error: Set AI_COMMIT_VERTEX_PROJECT to the Google Cloud project for Vertex AI" -ForegroundColor Red
+        return $null
+    }
+    $location = Get-AICommitSetting -Name "AI_COMMIT_VERTEX_LOCATION" -Default "us-central1"
+
+    $modelName = $Model -replace "^models/", ""
+    $hostName = if ($location -eq "global") { "aiplatform.googleapis.com" } else { "$location-aiplatform.googleapis.com" }
+    return "https://$hostName/v1/projects/$project/locations/$location/publishers/google/models/$($modelName):generateContent"
+}
+
+function Get-AICommitVertexToken {
+    # Application Default Credentials, as set up by
+    # 'gcloud auth application-default login' or a service account
+    if (!(Get-Command gcloud -ErrorAction SilentlyContinue)) {
+        Write-Host "Error: The vertex provider needs the gcloud CLI for Application Default Credentials (https://cloud.google.com/sdk)" -ForegroundColor Red
+        return $null
+    }
+    $token = "$(gcloud auth application-default print-access-token 2>$null)".Trim()
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($token)) {
+        Write-Host "Error: No Application Default Credentials found" -ForegroundColor Red
+        Write-Host "Run 'gcloud auth application-default login' or set GOOGLE_APPLICATION_CREDENTIALS" -ForegroundColor Yellow
+        return $null
+    }
+    return $token
+}
+
+function Get-AICommitAwsArguments {
+    # Region and profile for the aws CLI; unset ones come from the AWS config
+    $awsArgs = @()
+    $region = Get-AICommitSetting -Name "AI_COMMIT_BEDROCK_REGION"
+    if ($region) {
+        $awsArgs += @("--region", $region)
+    }
+    $awsProfile = Get-AICommitSetting -Name "AI_COMMIT_AWS_PROFILE"
+    if ($awsProfile) {
+        $awsArgs += @("--profile", $awsProfile)
+    }
+    return ,$awsArgs
+}
+
+function Invoke-AICommitBedrockCompletion {
+    # Bedrock requests are SigV4-signed, so they go through the aws CLI, which
+    # also resolves credentials the way every other AWS tool does. The Converse
+    # API takes the same request for Claude and Titan models.
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt
+    )
+
+    if (!(Get-Command aws -ErrorAction SilentlyContinue)) {
+        Write-Host "Error: The bedrock provider needs the AWS CLI (https://aws.amazon.com/cli/)" -ForegroundColor Red
+        return $null
+    }
+
+    $requestObj = @{
+        modelId         = $Provider.Model
+        messages        = @(
+            @{
+                role    = "user"
+                content = @(
+                    @{ text = $Prompt }
+                )
+            }
+        )
+        inferenceConfig = @{ maxTokens = 1000 }
+    }
+    $maxTokens = Get-AICommitGenerationSetting -Carrier "bedrock" -Name "MAX_TOKENS"
+    if ($null -ne $maxTokens) {
+        $requestObj.inferenceConfig.maxTokens = $maxTokens
+    }
+    $temperature = Get-AICommitGenerationSetting -Carrier "bedrock" -Name "TEMPERATURE"
+    if ($null -ne $temperature) {
+        $requestObj.inferenceConfig.temperature = $temperature
+    }
+    $topP = Get-AICommitGenerationSetting -Carrier "bedrock" -Name "TOP_P"
+    if ($null -ne $topP) {
+        $requestObj.inferenceConfig.topP = $topP
+    }
+    $jsonRequest = $requestObj | ConvertTo-Json -Depth 12 -Compress
+
+    Write-Host "Using model: $($Provider.Model) ($($Provider.Carrier))" -ForegroundColor Cyan
+    Write-Host "Request size: $($jsonRequest.Length) characters" -ForegroundColor Cyan
+    Write-Host "Getting AI suggestion..." -ForegroundColor Yellow
+
+    # The diff is too large for the command line, pass the request as a file
+    $requestFile = New-AICommitTempFile -Prefix "bedrock-request" -Extension ".json"
+    try {
+        [System.IO.File]::WriteAllText($requestFile, $jsonRequest, (New-Object System.Text.UTF8Encoding $false))
+        $awsArgs = Get-AICommitAwsArguments
+        $output = aws bedrock-runtime converse --cli-input-json "file://$requestFile" --output json @awsArgs 2>&1
+        if ($LASTEXITCODE -ne 0) {
+            $errorText = ($output | Out-String).Trim()
+            Write-Host "Error calling bedrock API:" -ForegroundColor Red
+            Write-Host "Message: $errorText" -ForegroundColor Red
+            # The CLI has no status code, its error names tell the same
+            if ($errorText -match 'ThrottlingException|TooManyRequests') {
+                $script:AICommitLastFailure = 429
+            } elseif ($errorText -match 'ServiceUnavailable|InternalServer|ModelTimeout|ModelNotReady') {
+                $script:AICommitLastFailure = 503
+            }
+            Write-Host "Check your AWS credentials (aws sts get-caller-identity) and that the model is enabled in this region" -ForegroundColor Yellow
+
+            Save-AICommitFailedRequest -Request $jsonRequest -Provider $Provider
+            return $null
+        }
+        $response = ($output | Out-String) | ConvertFrom-Json
+    }
+    finally {
+        Remove-Item $requestFile -Force -ErrorAction SilentlyContinue
+    }
+
+    $script:AICommitLastUsage = Get-AICommitUsage -Carrier "bedrock" -Response $response
+
+    # Only text blocks - Claude's reasoning blocks are not part of the answer
+    return ($response.output.message.content | Where-Object { $_.text } | ForEach-Object { $_.text }) -join ""
+}
+
+function Invoke-AICommitProviderCompletion {
+    # One call to one provider. On failure $script:AICommitLastFailure holds
+    # the HTTP status, 0 when there was no response (timeout, connection),
+    # and $script:AICommitLastRetryAfter the server's Retry-After in seconds.
+    # $script:AICommitLastRequestId is the provider's ID for the request.
+    # Token usage of a successful call is left in $script:AICommitLastUsage.
+    # -Schema (a JSON schema) constrains the answer where JsonMode is supported.
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt,
+        [hashtable]$Schema
+    )
+
+    $AI_MODEL = $Provider.Model
+    $carrier = $Provider.Carrier
+    $apiKey = $Provider.ApiKey
+    $script:AICommitLastFailure = $null
+    $script:AICommitLastRetryAfter = $null
+    $script:AICommitLastRequestId = $null
+    $script:AICommitLastUsage = $null
+
+    if ($carrier -eq "fake") {
+        Write-Host "Using model: $AI_MODEL ($carrier)" -ForegroundColor Cyan
+        return Get-AICommitFakeSuggestion -Prompt $Prompt
+    }
+    if ($carrier -eq "bedrock") {
+        return Invoke-AICommitBedrockCompletion -Provider $Provider -Prompt $Prompt
+    }
+
+    # Providers added with Register-AICommitProvider bring their own call
+    $registered = $script:AICommitProviders[$carrier].Completion
+    if ($null -ne $registered) {
+        Write-Host "Using model: $AI_MODEL ($carrier)" -ForegroundColor Cyan
+        Write-Host "Getting AI suggestion..." -ForegroundColor Yellow
+        try {
+            $answer = & $registered $Provider $Prompt
+            if ($null -eq $answer) {
+                return $null
+            }
+            return ($answer | Out-String).Trim()
+        }
+        catch {
+            Write-Host "Error calling $carrier provider: $($_.Exception.Message)" -ForegroundColor Red
+            return $null
+        }
+    }
+
+    # Generation settings; only the configured ones are sent
+    $temperature = Get-AICommitGenerationSetting -Carrier $carrier -Name "TEMPERATURE"
+    $topP = Get-AICommitGenerationSetting -Carrier $carrier -Name "TOP_P"
+    $maxTokens = Get-AICommitGenerationSetting -Carrier $carrier -Name "MAX_TOKENS"
+
+    # Build request based on carrier
+    if ($carrier -eq "anthropic") {
+        # Claude/Anthropic request format
+        $messages = @(
+            @{
+                role = "user"
+                content = @(
+                    @{ type = "text"; text = $Prompt }
+                )
+            }
+        )
+
+        $answerTokens = if ($null -ne $maxTokens) { $maxTokens } else { 1000 }
+        $requestObj = @{
+            model = $AI_MODEL
+            max_tokens = $answerTokens
+            messages = $messages
+        }
+
+        # Optional extended thinking for complex diffs, skipped in -fast mode
+        $thinkingBudget = Get-AICommitSetting -Name "AI_COMMIT_ANTHROPIC_THINKING_BUDGET"
+        if ($null -ne $thinkingBudget -and [int]$thinkingBudget -gt 0 -and !$script:AICommitFastMode) {
+            # The API requires at least 1024 thinking tokens and a max_tokens above the budget
+            $budget = [Math]::Max(1024, [int]$thinkingBudget)
+            $requestObj.thinking = @{ type = "enabled"; budget_tokens = $budget }
+            $requestObj.max_tokens = $budget + $answerTokens
+        } else {
+            # Thinking only works with the default sampling
+            if ($null -ne $temperature) {
+                $requestObj.temperature = $temperature
+            }
+            if ($null -ne $topP) {
+                $requestObj.top_p = $topP
+            }
+        }
+
+        $apiUrl = "https://api.anthropic.com/v1/messages"
+        $headers = @{
+            "Content-Type"      = "application/json; charset=utf-8"
+            "x-api-key"         = $apiKey
+            "anthropic-version" = "2023-06-01"
+        }
+    } elseif ($carrier -eq "openai") {
+        # OpenAI Responses API format - also accepted by the o-series
+        # reasoning models, which reject chat completions' max_tokens
+        $requestObj = @{
+            model = $AI_MODEL
+            input = $Prompt
+            max_output_tokens = 1000
+        }
+
+        if (Test-AICommitReasoningModel -Model $AI_MODEL) {
+            # Reasoning tokens count against the output limit, leave room for
+            # them. These models reject temperature and top_p.
+            $requestObj.max_output_tokens = if ($null -ne $maxTokens) { $maxTokens } else { 4000 }
+            $effort = Get-AICommitSetting -Name "AI_COMMIT_REASONING_EFFORT" -Default "low"
+            $requestObj.reasoning = @{ effort = $effort.ToLower() }
+        } else {
+            if ($null -ne $maxTokens) {
+                $requestObj.max_output_tokens = $maxTokens
+            }
+            if ($null -ne $temperature) {
+                $requestObj.temperature = $temperature
+            }
+            if ($null -ne $topP) {
+                $requestObj.top_p = $topP
+            }
+        }
+
+        $apiUrl = "https://api.openai.com/v1/responses"
+        $headers = @{
+            "Content-Type"  = "application/json; charset=utf-8"
+            "Authorization" = "Bearer $apiKey"
+        }
+        # Organization and project to bill (see Get-AICommitOrgHeaders)
+        foreach ($name in $Provider.Headers.Keys) {
+            $headers[$name] = $Provider.Headers[$name]
+        }
+    } elseif ($carrier -in @("mistral", "groq", "custom")) {
+        # Chat completions format, served by all of them
+        $requestObj = @{
+            model = $AI_MODEL
+            messages = @(
+                @{ role = "user"; content = $Prompt }
+            )
+            max_tokens = if ($null -ne $maxTokens) { $maxTokens } else { 1000 }
+        }
+        if ($null -ne $temperature) {
+            $requestObj.temperature = $temperature
+        }
+        if ($null -ne $topP) {
+            $requestObj.top_p = $topP
+        }
+
+        if ($carrier -eq "custom") {
+            $baseUrl = Get-AICommitCustomUrl
+            if ($null -eq $baseUrl) {
+                return $null
+            }
+            $apiUrl = "$baseUrl/chat/completions"
+        } else {
+            $apiUrl = if ($carrier -eq "groq") { "https://api.groq.com/openai/v1/chat/completions" } else { "https://api.mistral.ai/v1/chat/completions" }
+        }
+        $headers = @{
+            "Content-Type" = "application/json; charset=utf-8"
+        }
+        # Local servers usually run without a key
+        if ($apiKey) {
+            $headers["Authorization"] = "Bearer $apiKey"
+        }
+    } elseif ($carrier -eq "openrouter") {
+        # OpenRouter speaks the chat completions format for every model
+        $requestObj = @{
+            model = $AI_MODEL
+            messages = @(
+                @{ role = "user"; content = $Prompt }
+            )
+            max_tokens = if ($null -ne $maxTokens) { $maxTokens } else { 1000 }
+        }
+        if ($null -ne $temperature) {
+            $requestObj.temperature = $temperature
+        }
+        if ($null -ne $topP) {
+            $requestObj.top_p = $topP
+        }
+
+        $apiUrl = "$(Get-AICommitOpenRouterUrl)/chat/completions"
+        # OpenRouter identifies the calling app by these two headers
+        $headers = @{
+            "Content-Type"  = "application/json; charset=utf-8"
+            "Authorization" = "Bearer $apiKey"
+            "HTTP-Referer"  = "https://github.com/SCHWAI-AI/aicommit"
+            "X-Title"       = "aicommit"
+        }
+    } elseif ($carrier -eq "ollama") {
+        # Local Ollama server - nothing leaves the machine
+        $requestObj = @{
+            model = $AI_MODEL
+            messages = @(
+                @{ role = "user"; content = $Prompt }
+            )
+            stream = $false
+        }
+        # Ollama takes sampling settings as model options
+        $options = @{}
+        if ($null -ne $temperature) {
+            $options.temperature = $temperature
+        }
+        if ($null -ne $topP) {
+            $options.top_p = $topP
+        }
+        if ($null -ne $maxTokens) {
+            $options.num_predict = $maxTokens
+        }
+        if ($options.Count -gt 0) {
+            $requestObj.options = $options
+        }
+
+        $apiUrl = "$(Get-AICommitOllamaUrl)/api/chat"
+        $headers = @{
+            "Content-Type" = "application/json; charset=utf-8"
+        }
+    } else {
+        # Gemini/Google request format, also used by Vertex AI
+        $requestObj = @{
+            contents = @(
+                @{
+                    role  = "user"
+                    parts = @(
+                        @{ text = $Prompt }
+                    )
+                }
+            )
+        }
+
+        # Optional generation settings; only the ones configured are sent so
+        # the model's own defaults apply otherwise
+        $generationConfig = @{}
+        if ($null -ne $temperature) {
+            $generationConfig.temperature = $temperature
+        }
+        if ($null -ne $topP) {
+            $generationConfig.topP = $topP
+        }
+        if ($null -ne $maxTokens) {
+            $generationConfig.maxOutputTokens = $maxTokens
+        }
+        # Caps the 2.5 models' thinking: 0 turns it off (Flash), -1 lets the model decide
+        $thinkingBudget = Get-AICommitSetting -Name "AI_COMMIT_GEMINI_THINKING_BUDGET"
+        if ($null -ne $thinkingBudget) {
+            $generationConfig.thinkingConfig = @{ thinkingBudget = [int]$thinkingBudget }
+        }
+        if ($generationConfig.Count -gt 0) {
+            $requestObj.generationConfig = $generationConfig
+        }
+
+        if ($carrier -eq "vertex") {
+            $apiUrl = Get-AICommitVertexEndpoint -Model $AI_MODEL
+            if ($null -eq $apiUrl) {
+                return $null
+            }
+            $accessToken = Get-AICommitVertexToken
+            if ($null -eq $accessToken) {
+                return $null
+            }
+            $headers = @{
+                "Content-Type"  = "application/json; charset=utf-8"
+                "Authorization" = "Bearer $accessToken"
+            }
+        } else {
+            # Handle model name format (add "models/" prefix if not present)
+            $modelName = if ($AI_MODEL -like "models/*") { $AI_MODEL } else { "models/$AI_MODEL" }
+            $apiUrl = "https://generativelanguage.googleapis.com/v1beta/$($modelName):generateContent"
+            $headers = @{
+                "Content-Type"     = "application/json; charset=utf-8"
+                "x-goog-api-key"   = $apiKey
+            }
+        }
+    }
+
+    # Structured output: the answer is a JSON object matching -Schema
+    if ($null -ne $Schema -and (Test-AICommitCapability -Provider $Provider -Name "JsonMode")) {
+        if ($carrier -eq "anthropic") {
+            # Through a tool the model has to call; a forced tool call isn't
+            # allowed with extended thinking, so it is only offered then
+            $requestObj.tools = @(
+                @{ name = "commit_message"; description = "Record the commit message"; input_schema = $Schema }
+            )
+            $requestObj.tool_choice = if ($requestObj.thinking) { @{ type = "auto" } } else { @{ type = "tool"; name = "commit_message" } }
+        } elseif ($carrier -eq "openai") {
+            $requestObj.text = @{ format = @{ type = "json_schema"; name = "commit_message"; schema = $Schema; strict = $true } }
+        } elseif ($carrier -in @("mistral", "groq")) {
+            $requestObj.response_format = @{ type = "json_object" }
+        } elseif ($carrier -eq "ollama") {
+            $requestObj.format = $Schema
+        } elseif ($carrier -in @("google", "vertex")) {
+            if ($null -eq $requestObj.generationConfig) {
+                $requestObj.generationConfig = @{}
+            }
+            $requestObj.generationConfig.responseMimeType = "application/json"
+            $requestObj.generationConfig.responseSchema = ConvertTo-AICommitGeminiSchema -Schema $Schema
+        }
+    }
+
+    # Stream the answer into the terminal where the provider supports it
+    $useStream = Test-AICommitStreaming -Provider $Provider
+    if ($useStream) {
+        if ($carrier -in @("google", "vertex")) {
+            $apiUrl = $apiUrl -replace ':generateContent$', ':streamGenerateContent?alt=sse'
+        } else {
+            $requestObj.stream = $true
+        }
+    }
+
+    # Convert to JSON
+    $jsonRequest = $requestObj | ConvertTo-Json -Depth 12 -Compress
+
+    # Validate JSON structure
+    try {
+        $null = $jsonRequest | ConvertFrom-Json
+        Write-Host "JSON validation passed" -ForegroundColor Green
+    }
+    catch {
+        Write-Host "Warning: JSON validation failed - $($_.Exception.Message)" -ForegroundColor Yellow
+        Write-Host "Attempting to continue anyway..." -ForegroundColor Yellow
+    }
+
+    # Debug info
+    Write-Host "Using model: $AI_MODEL ($carrier)" -ForegroundColor Cyan
+    Write-Host "Request size: $($jsonRequest.Length) characters" -ForegroundColor Cyan
+
+    # Call the AI
+    try {
+        Write-Host "Getting AI suggestion..." -ForegroundColor Yellow
+
+        $bodyBytes = [System.Text.Encoding]::UTF8.GetBytes($jsonRequest)
+
+        if ($useStream) {
+            # Tokens are shown as they arrive; the full text is returned
+            $streamed = Invoke-AICommitStream -Uri $apiUrl -Headers $headers -Body $bodyBytes -Carrier $carrier
+            $script:AICommitLastRequestId = $streamed.RequestId
+            if ($streamed.StatusCode -ge 400) {
+                $script:AICommitLastFailure = $streamed.StatusCode
+                $script:AICommitLastRetryAfter = ConvertFrom-AICommitRetryAfter -Value $streamed.RetryAfter
+                Write-Host "HTTP $($streamed.StatusCode)" -ForegroundColor Red
+                Write-AICommitRequestId -Carrier $carrier
+                Write-Host $streamed.ErrorBody -ForegroundColor Red
+                Save-AICommitFailedRequest -Request $jsonRequest -Provider $Provider
+                return $null
+            }
+            $suggestion = $streamed.Text
+            $script:AICommitLastUsage = $streamed.Usage
+            if ($carrier -eq "ollama") {
+                $suggestion = $suggestion -replace '(?s)<think>.*?</think>', ''
+            }
+        } else {
+            $irmParams = @{
+                Uri     = $apiUrl
+                Method  = "Post"
+                Headers = $headers
+                Body    = $bodyBytes
+            }
+
+            $irmCmd   = Get-Command Invoke-RestMethod
+            $hasSkip  = $irmCmd.Parameters.ContainsKey('SkipHttpErrorCheck')
+            $hasSCV   = $irmCmd.Parameters.ContainsKey('StatusCodeVariable')
+            $hasRHV   = $irmCmd.Parameters.ContainsKey('ResponseHeadersVariable')
+
+            if ($hasSkip) { $irmParams['SkipHttpErrorCheck'] = $true }
+            if ($hasSCV)  { $irmParams['StatusCodeVariable'] = 'scv' }
+            if ($hasRHV)  { $irmParams['ResponseHeadersVariable'] = 'rhv' }
+
+            $scv = 0
+            $response = Invoke-RestMethod @irmParams
+            if ($hasRHV) {
+                $script:AICommitLastRequestId = Get-AICommitRequestId -Headers $rhv
+            }
+
+            if ($hasSkip -and $hasSCV -and $scv -ge 400) {
+                $script:AICommitLastFailure = $scv
+                if ($hasRHV -and $rhv -and $rhv['Retry-After']) {
+                    $script:AICommitLastRetryAfter = ConvertFrom-AICommitRetryAfter -Value "$($rhv['Retry-After'])"
+                }
+                Write-Host "HTTP $scv" -ForegroundColor Red
+                Write-AICommitRequestId -Carrier $carrier
+                try {
+                    ($response | ConvertTo-Json -Depth 12) | Write-Host -ForegroundColor Red
+                } catch {
+                    Write-Host "$response" -ForegroundColor Red
+                }
+                Save-AICommitFailedRequest -Request $jsonRequest -Provider $Provider
+                return $null
+            }
+
+            # Extract suggestion based on carrier
+            if ($carrier -eq "anthropic") {
+                # Only text blocks - thinking blocks are not part of the answer.
+                # A structured answer comes as the input of the tool call.
+                $toolUse = $response.content | Where-Object { $_.type -eq "tool_use" } | Select-Object -First 1
+                if ($null -ne $toolUse) {
+                    $suggestion = $toolUse.input | ConvertTo-Json -Compress -Depth 5
+                } else {
+                    $suggestion = ($response.content | Where-Object { $_.type -eq "text" } | ForEach-Object { $_.text }) -join ""
+                }
+            } elseif ($carrier -eq "openai") {
+                # Responses API: reasoning items come first, the text is in the message item
+                $suggestion = ($response.output | Where-Object { $_.type -eq "message" } | ForEach-Object { $_.content } | Where-Object { $_.type -eq "output_text" } | ForEach-Object { $_.text }) -join ""
+            } elseif ($carrier -in @("mistral", "groq", "custom", "openrouter")) {
+                # Chat completions response
+                $suggestion = $response.choices[0].message.content
+            } elseif ($carrier -eq "ollama") {
+                # Reasoning models (deepseek-r1, qwen3) put their thinking in <think> tags
+                $suggestion = $response.message.content -replace '(?s)<think>.*?</think>', ''
+            } else {
+                # Gemini response structure
+                $suggestion = $response.candidates[0].content.parts[0].text
+            }
+            $script:AICommitLastUsage = Get-AICommitUsage -Carrier $carrier -Response $response
+        }
+    }
+    catch {
+        Write-Host "Error calling $carrier API:" -ForegroundColor Red
+        $script:AICommitLastFailure = 0
+        if ($_.Exception.Response) {
+            try {
+                $statusCode = $_.Exception.Response.StatusCode.value__
+                $script:AICommitLastFailure = [int]$statusCode
+                # HttpResponseMessage (PowerShell 7) or WebResponse (5.1)
+                $responseHeaders = $_.Exception.Response.Headers
+                $retryAfter = if ($responseHeaders.RetryAfter) { "$($responseHeaders.RetryAfter)" } else { "$($responseHeaders['Retry-After'])" }
+                $script:AICommitLastRetryAfter = ConvertFrom-AICommitRetryAfter -Value $retryAfter
+                $script:AICommitLastRequestId = Get-AICommitRequestId -Headers $responseHeaders
+                Write-Host "Status: $statusCode" -ForegroundColor Red
+                Write-AICommitRequestId -Carrier $carrier
+            } catch { }
+        } else {
+            Write-Host "Status: (unknown)" -ForegroundColor Red
+        }
+        Write-Host "Message: $($_.Exception.Message)" -ForegroundColor Red
+        if ($carrier -eq "ollama" -and !$_.Exception.Response) {
+            Write-Host "Is Ollama running at $(Get-AICommitOllamaUrl)? Start it with 'ollama serve'" -ForegroundColor Yellow
+        }
+
+        # Try to get detailed error response
+        if ($_.Exception.Response) {
+            try {
+                $responseStream = $_.Exception.Response.GetResponseStream()
+                $reader = New-Object System.IO.StreamReader($responseStream, [System.Text.Encoding]::UTF8)
+                $errorBody = $reader.ReadToEnd()
+                Write-Host "Response body: $errorBody" -ForegroundColor Red
+                $reader.Close()
+                $responseStream.Close()
+            }
+            catch {
+                Write-Host "Could not read error response body" -ForegroundColor Red
+            }
+        }
+
+        # Save request for debugging
+        Save-AICommitFailedRequest -Request $jsonRequest -Provider $Provider
+
+        return $null
+    }
+
+    return $suggestion
+}
+
+function Get-AICommitSuggestions {
+    # -Count alternative messages from one request, asked to differ in focus
+    # and wording. (Sampling several answers natively gives near-identical
+    # messages at the usual low temperatures.) Falls back to a single
+    # suggestion when the alternatives can't be read.
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt,
+        [int]$Count
+    )
+
+    # No prompt: it didn't fit the context window, which was reported
+    if ([string]::IsNullOrWhiteSpace($Prompt)) {
+        $script:AICommitLastFailure = $null
+        return @()
+    }
+    $ask = "Give $Count different alternatives that differ in focus or wording, not just punctuation."
+    $candidates = @()
+    if ((Test-AICommitCapability -Provider $Provider -Name "JsonMode") -and (Get-AICommitSetting -Name "AI_COMMIT_STRUCTURED_OUTPUT" -Default "true").ToLower() -notin @('false', 'no', 'off', '0')) {
+        $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt "$Prompt`n`n$ask Return a JSON object with a candidates array; each entry has the string fields header, description, risk and breaking (empty if no such line is asked for)." -Schema $script:AICommitCandidatesSchema
+        if ($null -eq $answer) {
+            return @()
+        }
+        $start = $answer.IndexOf("{")
+        $end = $answer.LastIndexOf("}")
+        if ($start -ge 0 -and $end -gt $start) {
+            try {
+                $object = $answer.Substring($start, $end - $start + 1) | ConvertFrom-Json
+                $candidates = @($object.candidates | Where-Object { ![string]::IsNullOrWhiteSpace($_.header) } | ForEach-Object {
+                    @{ Header = "$($_.header)".Trim(); Description = "$($_.description)".Trim(); Risk = "$($_.risk)".Trim(); Breaking = "$($_.breaking)".Trim() }
+                })
+            }
+            catch {
+                # Read as text below
+            }
+        }
+    } else {
+        $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt "$Prompt`n`n$ask Write each one in the format above and put a line containing only --- between them."
+        if ($null -eq $answer) {
+            return @()
+        }
+        $candidates = @([regex]::Split($answer, '(?m)^\s*---\s*$') | ForEach-Object { ConvertFrom-AICommitSuggestion -Suggestion $_.Trim() } | Where-Object { ![string]::IsNullOrWhiteSpace($_.Header) })
+    }
+
+    if ($candidates.Count -eq 0) {
+        Write-Host "Warning: Could not read the alternatives, asking for a single message..." -ForegroundColor Yellow
+        $single = Get-AICommitSuggestion -Provider $Provider -Prompt $Prompt
+        return @($single | Where-Object { $null -ne $_ })
+    }
+    foreach ($candidate in $candidates) {
+        $candidate.Description = Limit-AICommitDescription -Description $candidate.Description
+    }
+    return @($candidates | Select-Object -First $Count)
+}
+
+function Get-AICommitSuggestion {
+    # A parsed suggestion whose header follows the style preset
+    # (AI_COMMIT_STYLE_PRESET): one that doesn't is asked for again with the
+    # problem quoted, AI_COMMIT_STYLE_RETRIES times (default 1), and is
+    # otherwise left for the review to flag
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt
+    )
+
+    $parsed = Get-AICommitParsedSuggestion -Provider $Provider -Prompt $Prompt
+    $retries = if ($script:AICommitFastMode) { 0 } else { [int](Get-AICommitSetting -Name "AI_COMMIT_STYLE_RETRIES" -Default 1) }
+    for ($attempt = 0; $null -ne $parsed; $attempt++) {
+        $problems = Test-AICommitStylePreset -Header $parsed.Header
+        if ($problems.Count -eq 0) {
+            break
+        }
+        if ($attempt -ge $retries) {
+            Write-Host "Warning: $($problems -join '; ')" -ForegroundColor Yellow
+            break
+        }
+        Write-Host "Warning: $($problems -join '; '), asking again..." -ForegroundColor Yellow
+        $retry = Get-AICommitParsedSuggestion -Provider $Provider -Prompt "$Prompt`n`nIMPORTANT: Your previous header `"$($parsed.Header)`" was rejected: $($problems -join '; '). Write a header that follows this style."
+        if ($null -eq $retry) {
+            break
+        }
+        $parsed = $retry
+    }
+    return $parsed
+}
+
+function Get-AICommitParsedSuggestion {
+    # Calls the provider and parses the answer. Models that ignore the
+    # HEADER:/DESCRIPTION: format are asked again with a stricter reminder;
+    # after that AI_COMMIT_FORMAT_FALLBACK decides: "lenient" parsing
+    # (default), "none", or another provider ("openai" or "openai:gpt-4.1").
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt
+    )
+
+    # No prompt: it didn't fit the context window, which was reported
+    if ([string]::IsNullOrWhiteSpace($Prompt)) {
+        $script:AICommitLastFailure = $null
+        return $null
+    }
+
+    # Providers with JSON output are asked for a JSON object first; the
+    # HEADER:/DESCRIPTION: text below is the fallback
+    if ((Test-AICommitCapability -Provider $Provider -Name "JsonMode") -and (Get-AICommitSetting -Name "AI_COMMIT_STRUCTURED_OUTPUT" -Default "true").ToLower() -notin @('false', 'no', 'off', '0')) {
+        $suggestion = Invoke-AICommitCompletion -Provider $Provider -Prompt "$Prompt`n`n$script:AICommitJsonInstruction" -Schema $script:AICommitSuggestionSchema
+        if ($null -eq $suggestion) {
+            return $null
+        }
+        $parsed = ConvertFrom-AICommitJsonSuggestion -Suggestion $suggestion
+        if ($null -eq $parsed) {
+            $parsed = ConvertFrom-AICommitSuggestion -Suggestion $suggestion
+        }
+        if (![string]::IsNullOrWhiteSpace($parsed.Header)) {
+            $parsed.Description = Limit-AICommitDescription -Description $parsed.Description
+            return $parsed
+        }
+        Write-Host "Warning: The structured answer could not be read, asking for the text format..." -ForegroundColor Yellow
+    }
+
+    # -fast skips the second attempt and parses whatever came back
+    $retries = if ($script:AICommitFastMode) { 0 } else { [int](Get-AICommitSetting -Name "AI_COMMIT_FORMAT_RETRIES" -Default 1) }
+    $fallback = if ($script:AICommitFastMode) { "lenient" } else { Get-AICommitSetting -Name "AI_COMMIT_FORMAT_FALLBACK" -Default "lenient" }
+
+    $currentPrompt = $Prompt
+    $suggestion = $null
+    for ($attempt = 0; $attempt -le $retries; $attempt++) {
+        $suggestion = Invoke-AICommitCompletion -Provider $Provider -Prompt $currentPrompt
+        if ($null -eq $suggestion) {
+            return $null
+        }
+
+        $parsed = ConvertFrom-AICommitSuggestion -Suggestion $suggestion
+        if (![string]::IsNullOrWhiteSpace($parsed.Header)) {
+            $parsed.Description = Limit-AICommitDescription -Description $parsed.Description
+            return $parsed
+        }
+
+        if ($attempt -lt $retries) {
+            Write-Host "Warning: The response did not follow the HEADER:/DESCRIPTION: format, asking again..." -ForegroundColor Yellow
+            $currentPrompt = "$Prompt`n`nIMPORTANT: Your previous answer did not use the required format. Reply with exactly two lines, the first starting with `"HEADER: `" and the second with `"DESCRIPTION: `", and nothing else."
+        }
+    }
+
+    switch -regex ($fallback.ToLower()) {
+        '^none$' {
+            Write-AICommitError -Message "The model did not return a usable commit message" -Kind "UnusableMessage"
+            return $null
+        }
+        '^lenient$' {
+            Write-Host "Warning: Falling back to lenient parsing of the response" -ForegroundColor Yellow
+            $parsed = ConvertFrom-AICommitSuggestion -Suggestion $suggestion -Lenient
+            if ([string]::IsNullOrWhiteSpace($parsed.Header)) {
+                Write-AICommitError -Message "The model did not return a usable commit message" -Kind "UnusableMessage"
+                return $null
+            }
+            $parsed.Description = Limit-AICommitDescription -Description $parsed.Description
+            return $parsed
+        }
+        default {
+            $fallbackCarrier, $fallbackModel = $fallback -split ":", 2
+            Write-Host "Warning: Falling back to provider '$fallbackCarrier' for a well-formed response" -ForegroundColor Yellow
+            $fallbackProvider = Get-AICommitProvider -Carrier $fallbackCarrier -Model $fallbackModel
+            if ($null -eq $fallbackProvider) {
+                return $null
+            }
+            $suggestion = Invoke-AICommitCompletion -Provider $fallbackProvider -Prompt $Prompt
+            if ($null -eq $suggestion) {
+                return $null
+            }
+            # Last resort, so accept whatever can be salvaged
+            $parsed = ConvertFrom-AICommitSuggestion -Suggestion $suggestion -Lenient
+            if ([string]::IsNullOrWhiteSpace($parsed.Header)) {
+                Write-AICommitError -Message "The model did not return a usable commit message" -Kind "UnusableMessage"
+                return $null
+            }
+            $parsed.Description = Limit-AICommitDescription -Description $parsed.Description
+            return $parsed
+        }
+    }
+}
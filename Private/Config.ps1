@@ -0,0 +1,397 @@
+# Settings from the current repository's .aicommit.env (personal, never
+# committed) and .aicommit.yaml (team conventions, committed), and the
+# user's config.env (managed with 'aicommit config'), loaded per invocation
+$script:AICommitRepoSettings = @{}
+$script:AICommitTeamSettings = @{}
+$script:AICommitUserSettings = @{}
+# Names of settings that hold secrets (ANTHROPIC_API_KEY, GITHUB_TOKEN,
+# AWS_SECRET_ACCESS_KEY); limits such as AI_COMMIT_MAX_TOKENS are not
+$script:AICommitSecretNamePattern = '_API_KEY(_AICOMMIT(_\w+)?)?$|(^|_)(ACCESS_|AUTH_|SESSION_)?TOKEN$|(^|_)SECRET(_ACCESS_KEY)?$'
+
+function Read-AICommitEnvFile {
+    param([string]$Path)
+
+    $settings = [ordered]@{}
+    foreach ($line in Get-Content -Path $Path -Encoding UTF8) {
+        $line = $line.Trim()
+        if ($line -eq "" -or $line.StartsWith("#")) {
+            continue
+        }
+        # Same syntax as direnv/dotenv: [export ]NAME=value, optionally quoted
+        if ($line -match '^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$') {
+            $name = $Matches[1]
+            $value = $Matches[2].Trim()
+            if ($value.Length -ge 2 -and (($value.StartsWith('"') -and $value.EndsWith('"')) -or ($value.StartsWith("'") -and $value.EndsWith("'")))) {
+                $value = $value.Substring(1, $value.Length - 2)
+            }
+            $settings[$name] = $value
+        } else {
+            Write-Host "Warning: Ignoring invalid line in $($Path): $line" -ForegroundColor Yellow
+        }
+    }
+    return $settings
+}
+
+function Read-AICommitYamlFile {
+    # Flat "name: value" pairs. Names are setting names (AI_COMMIT_MODEL) or
+    # their short form (model, prompt_template). Secrets are refused since
+    # the file is meant to be committed.
+    param([string]$Path)
+
+    $settings = [ordered]@{}
+    foreach ($line in Get-Content -Path $Path -Encoding UTF8) {
+        if ($line.Trim() -eq "" -or $line.Trim().StartsWith("#") -or $line.Trim() -eq "---") {
+            continue
+        }
+        if ($line -notmatch '^([A-Za-z_][A-Za-z0-9_]*)\s*:\s*(.*)$') {
+            Write-Host "Warning: Ignoring unsupported line in $($Path) (only 'name: value' pairs): $($line.Trim())" -ForegroundColor Yellow
+            continue
+        }
+        $key = $Matches[1]
+        $value = $Matches[2].Trim()
+        if ($value.Length -ge 2 -and (($value.StartsWith('"') -and $value.EndsWith('"')) -or ($value.StartsWith("'") -and $value.EndsWith("'")))) {
+            $value = $value.Substring(1, $value.Length - 2)
+        } else {
+            # Trailing comment
+            $value = ($value -replace '\s+#.*$', '').Trim()
+        }
+
+        $name = if ($key -cmatch '^[a-z0-9_]+$') { "AI_COMMIT_$($key.ToUpper())" } else { $key }
+        if ($name -match $script:AICommitSecretNamePattern) {
+            Write-Host "Warning: Ignoring $name in $Path - keep keys in .aicommit.env or your environment, not in a committed file" -ForegroundColor Yellow
+            continue
+        }
+        $settings[$name] = $value
+    }
+    return $settings
+}
+
+function Import-AICommitRepoSettings {
+    $script:AICommitRepoSettings = @{}
+    $script:AICommitTeamSettings = @{}
+    $script:AICommitRepoSettingDecisions = @{}
+
+    $repoRoot = git rev-parse --show-toplevel 2>$null
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($repoRoot)) {
+        return
+    }
+
+    # Team conventions shared through the repository
+    $teamFile = Join-Path "$repoRoot".Trim() ".aicommit.yaml"
+    if (Test-Path $teamFile) {
+        $script:AICommitTeamSettings = Read-AICommitYamlFile -Path $teamFile
+    }
+
+    $envFile = Join-Path "$repoRoot".Trim() ".aicommit.env"
+    if (!(Test-Path $envFile)) {
+        return
+    }
+
+    # The file usually holds API keys, so it must never be committed
+    git check-ignore -q -- $envFile 2>$null
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Warning: .aicommit.env is not git-ignored - add it to .gitignore to keep your keys out of the repository" -ForegroundColor Yellow
+    }
+
+    $script:AICommitRepoSettings = Read-AICommitEnvFile -Path $envFile
+}
+
+function Get-AICommitValueHash {
+    param([string]$Value)
+
+    $sha = [System.Security.Cryptography.SHA256]::Create()
+    try {
+        $bytes = $sha.ComputeHash([System.Text.Encoding]::UTF8.GetBytes($Value))
+        return ([BitConverter]::ToString($bytes) -replace "-", "").ToLower()
+    }
+    finally {
+        $sha.Dispose()
+    }
+}
+
+function Write-AICommitAuditLog {
+    param(
+        [string]$Action,
+        [string]$Name,
+        [string]$Source = "aicommit config"
+    )
+
+    # Only ever record names, never values - the file may hold API keys
+    $entry = "{0}`t{1}`t{2}`t{3}" -f (Get-Date).ToString("o"), $Action, $Name, $Source
+    Add-Content -Path (Get-AICommitDataPath "audit.log") -Value $entry -Encoding UTF8
+}
+
+function Save-AICommitConfigState {
+    param($Settings)
+
+    $state = @{}
+    foreach ($name in $Settings.Keys) {
+        $state[$name] = Get-AICommitValueHash -Value $Settings[$name]
+    }
+    $state | ConvertTo-Json | Out-File -FilePath (Get-AICommitDataPath "config.state.json") -Encoding UTF8
+}
+
+function Import-AICommitUserSettings {
+    $script:AICommitUserSettings = @{}
+
+    $configFile = Get-AICommitDataPath "config.env"
+    $settings = if (Test-Path $configFile) { Read-AICommitEnvFile -Path $configFile } else { [ordered]@{} }
+
+    # Compare against the hashes from our last write to catch edits made by
+    # other tools or by hand, so 'aicommit config log' shows those too
+    $stateFile = Get-AICommitDataPath "config.state.json"
+    $known = @{}
+    if (Test-Path $stateFile) {
+        try {
+            $stateJson = Get-Content $stateFile -Raw -Encoding UTF8 | ConvertFrom-Json
+            foreach ($property in $stateJson.PSObject.Properties) {
+                $known[$property.Name] = $property.Value
+            }
+        }
+        catch {
+            # Unreadable state is rebuilt below
+        }
+    }
+
+    $changed = $false
+    foreach ($name in $settings.Keys) {
+        $hash = Get-AICommitValueHash -Value $settings[$name]
+        if (!$known.ContainsKey($name)) {
+            Write-AICommitAuditLog -Action "set" -Name $name -Source "external edit"
+            $changed = $true
+        } elseif ($known[$name] -ne $hash) {
+            Write-AICommitAuditLog -Action "changed" -Name $name -Source "external edit"
+            $changed = $true
+        }
+    }
+    foreach ($name in $known.Keys) {
+        if (!$settings.Contains($name)) {
+            Write-AICommitAuditLog -Action "unset" -Name $name -Source "external edit"
+            $changed = $true
+        }
+    }
+    if ($changed -or !(Test-Path $stateFile)) {
+        Save-AICommitConfigState -Settings $settings
+    }
+
+    $script:AICommitUserSettings = $settings
+}
+
+function Get-AICommitSetting {
+    param(
+        [string]$Name,
+        $Default
+    )
+
+    # .aicommit.env wins over everything so each repo can use its own keys,
+    # then the environment of this shell or CI run, then the team's
+    # .aicommit.yaml over personal defaults (what was remembered for this
+    # repository, then the user config file). Where requests and keys go is
+    # only taken from the repository once the user has confirmed it
+    # (Test-AICommitRepoSettingTrusted)
+    if ($script:AICommitRepoSettings.Contains($Name) -and (Test-AICommitRepoSettingTrusted -Name $Name -Value $script:AICommitRepoSettings[$Name] -File ".aicommit.env")) {
+        return $script:AICommitRepoSettings[$Name]
+    }
+
+    $value = [Environment]::GetEnvironmentVariable($Name)
+    if (![string]::IsNullOrWhiteSpace($value)) {
+        return $value
+    }
+
+    if ($script:AICommitTeamSettings.Contains($Name) -and (Test-AICommitRepoSettingTrusted -Name $Name -Value $script:AICommitTeamSettings[$Name] -File ".aicommit.yaml")) {
+        return $script:AICommitTeamSettings[$Name]
+    }
+
+    if ($script:AICommitRememberedSettings.Contains($Name)) {
+        return $script:AICommitRememberedSettings[$Name]
+    }
+    if ($script:AICommitUserSettings.Contains($Name)) {
+        return $script:AICommitUserSettings[$Name]
+    }
+    return $Default
+}
+
+function Test-AICommitSettingEnabled {
+    # On/off settings accept true/yes/on/1, anything else is off
+    param([string]$Name)
+
+    $value = Get-AICommitSetting -Name $Name -Default ""
+    return ("$value".Trim().ToLower() -in @('true', 'yes', 'on', '1'))
+}
+
+function Set-AICommitConfigValue {
+    param(
+        [string]$Name,
+        [string]$Value,
+        [switch]$Remove
+    )
+
+    $configFile = Get-AICommitDataPath "config.env"
+    $lines = if (Test-Path $configFile) { @(Get-Content -Path $configFile -Encoding UTF8) } else { @() }
+
+    # Rewrite in place so comments and ordering survive
+    $pattern = "^\s*(?:export\s+)?$([regex]::Escape($Name))\s*="
+    $output = New-Object System.Collections.Generic.List[string]
+    $found = $false
+    foreach ($line in $lines) {
+        if ($line -match $pattern) {
+            if (!$Remove -and !$found) {
+                $output.Add("$Name=$Value")
+            }
+            $found = $true
+            continue
+        }
+        $output.Add($line)
+    }
+    if (!$Remove -and !$found) {
+        $output.Add("$Name=$Value")
+    }
+
+    # Written next to it and moved over it, so a key being replaced is never
+    # half-written
+    $tempFile = "$configFile.tmp"
+    Set-Content -Path $tempFile -Value $output -Encoding UTF8
+    Move-Item -Path $tempFile -Destination $configFile -Force
+    return $found
+}
+
+function Save-AICommitConfigSetting {
+    # Sets a value in the config file with an audit log entry, as
+    # 'aicommit config set' does
+    param(
+        [string]$Name,
+        [string]$Value
+    )
+
+    $existed = Set-AICommitConfigValue -Name $Name -Value $Value
+    $auditAction = if ($existed) { "changed" } else { "set" }
+    Write-AICommitAuditLog -Action $auditAction -Name $Name
+    Import-AICommitUserSettings
+    Save-AICommitConfigState -Settings $script:AICommitUserSettings
+}
+
+function Invoke-AICommitConfig {
+    param([string[]]$Arguments)
+
+    $action = if ($Arguments.Count -gt 0) { $Arguments[0].ToLower() } else { "list" }
+    $name = if ($Arguments.Count -gt 1) { $Arguments[1] } else { $null }
+
+    switch ($action) {
+        'set' {
+            if ([string]::IsNullOrWhiteSpace($name)) {
+                Write-Host "Usage: aicommit config set <NAME> [value]" -ForegroundColor Yellow
+                return
+            }
+            $prompted = $Arguments.Count -le 2
+            if (!$prompted) {
+                $value = $Arguments[2..($Arguments.Count - 1)] -join " "
+            } else {
+                # Prompt so keys don't end up in shell history
+                $secureValue = Read-Host "Value for $name" -AsSecureString
+                $bstr = [Runtime.InteropServices.Marshal]::SecureStringToBSTR($secureValue)
+                try {
+                    $value = [Runtime.InteropServices.Marshal]::PtrToStringBSTR($bstr)
+                }
+                finally {
+                    [Runtime.InteropServices.Marshal]::ZeroFreeBSTR($bstr)
+                }
+            }
+            Save-AICommitConfigSetting -Name $name -Value $value
+            Write-Host "$name saved to $(Get-AICommitDataPath 'config.env')" -ForegroundColor Green
+
+            # Keys get a scope check and metadata for 'config list-keys'
+            if (Test-AICommitKeyName -Name $name) {
+                $scope = Get-AICommitKeyScope -Value $value
+                if ($scope.Warning) {
+                    Write-Host "Warning: $($scope.Warning)" -ForegroundColor Yellow
+                }
+                $label = if ($prompted) { "$(Read-Host "Label for this key, e.g. laptop or team-a (Enter to skip)")".Trim() } else { "" }
+                Save-AICommitKeyMetadata -Name $name -Value $value -Label $label
+            }
+        }
+        'unset' {
+            if ([string]::IsNullOrWhiteSpace($name)) {
+                Write-Host "Usage: aicommit config unset <NAME>" -ForegroundColor Yellow
+                return
+            }
+            if (Set-AICommitConfigValue -Name $name -Remove) {
+                Write-AICommitAuditLog -Action "unset" -Name $name
+                if (Test-AICommitKeyName -Name $name) {
+                    Save-AICommitKeyMetadata -Name $name -Remove
+                }
+                Import-AICommitUserSettings
+                Save-AICommitConfigState -Settings $script:AICommitUserSettings
+                Write-Host "$name removed" -ForegroundColor Green
+            } else {
+                Write-Host "$name is not set in the config file" -ForegroundColor Yellow
+            }
+        }
+        'list' {
+            Write-Host "`n--- CONFIG ($(Get-AICommitDataPath 'config.env')) ---" -ForegroundColor Cyan
+            foreach ($key in $script:AICommitUserSettings.Keys) {
+                # Never print anything that looks like a secret
+                $shown = if ($key -match $script:AICommitSecretNamePattern -or (Test-AICommitKeyName -Name $key)) { "********" } else { $script:AICommitUserSettings[$key] }
+                Write-Host "$key=$shown" -ForegroundColor White
+            }
+            Write-Host "--- END CONFIG ---`n" -ForegroundColor Cyan
+            if ($script:AICommitTeamSettings.Count -gt 0) {
+                Write-Host "--- TEAM CONFIG (.aicommit.yaml, overrides the above) ---" -ForegroundColor Cyan
+                foreach ($key in $script:AICommitTeamSettings.Keys) {
+                    Write-Host "$key=$($script:AICommitTeamSettings[$key])" -ForegroundColor White
+                }
+                Write-Host "--- END TEAM CONFIG ---`n" -ForegroundColor Cyan
+            }
+        }
+        'list-keys' {
+            Show-AICommitKeys
+        }
+        'rotate-key' {
+            if ([string]::IsNullOrWhiteSpace($name)) {
+                Write-Host "Usage: aicommit config rotate-key <provider> [check-old]" -ForegroundColor Yellow
+                return
+            }
+            Invoke-AICommitKeyRotation -Carrier $name -CheckOld:($Arguments -contains "check-old")
+        }
+        'log' {
+            $logFile = Get-AICommitDataPath "audit.log"
+            if (!(Test-Path $logFile)) {
+                Write-Host "No config changes recorded yet" -ForegroundColor Green
+                return
+            }
+            $count = if ($name -match '^\d+$') { [int]$name } else { 50 }
+            Write-Host "`n--- CONFIG CHANGES ---" -ForegroundColor Cyan
+            foreach ($entry in Get-Content -Path $logFile -Tail $count -Encoding UTF8) {
+                $fields = $entry -split "`t"
+                if ($fields.Count -lt 4) {
+                    continue
+                }
+                $color = if ($fields[3] -eq "external edit") { "Yellow" } else { "White" }
+                Write-Host ("{0}  {1,-8} {2}  ({3})" -f $fields[0], $fields[1], $fields[2], $fields[3]) -ForegroundColor $color
+            }
+            Write-Host "--- END CONFIG CHANGES ---`n" -ForegroundColor Cyan
+        }
+        default {
+            Write-Host "Error: Unknown config action: $action" -ForegroundColor Red
+            Write-Host "Available actions: set, unset, list, list-keys, rotate-key, log" -ForegroundColor Yellow
+        }
+    }
+}
+
+function Get-AICommitDataPath {
+    param([string]$ChildPath)
+
+    # Per-user state (caches, logs) lives outside any repository
+    $root = if ($env:AI_COMMIT_HOME) {
+        $env:AI_COMMIT_HOME
+    } else {
+        Join-Path $HOME ".aicommit"
+    }
+    if (!(Test-Path $root)) {
+        New-Item -ItemType Directory -Path $root -Force | Out-Null
+    }
+
+    if ([string]::IsNullOrWhiteSpace($ChildPath)) {
+        return $root
+    }
+    return Join-Path $root $ChildPath
+}
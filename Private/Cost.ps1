@@ -0,0 +1,199 @@
+# USD per million input/output tokens by model name. First match wins, so
+# more specific names come first. Prices in the cached model list ('aicommit
+# models') take precedence; models priced in neither are reported without
+# a cost, and local providers are free.
+$script:AICommitPrices = [ordered]@{
+    '*claude-opus-4-5*'      = @(5, 25)
+    '*claude-opus-4*'        = @(15, 75)
+    '*claude-sonnet-4*'      = @(3, 15)
+    '*claude-3-7-sonnet*'    = @(3, 15)
+    '*claude-haiku-4*'       = @(1, 5)
+    '*claude-3-5-haiku*'     = @(0.8, 4)
+    '*gemini-2.5-pro*'       = @(1.25, 10)
+    '*gemini-2.5-flash-lite*' = @(0.1, 0.4)
+    '*gemini-2.5-flash*'     = @(0.3, 2.5)
+    '*gemini-2.0-flash*'     = @(0.1, 0.4)
+    '*gpt-5-nano*'           = @(0.05, 0.4)
+    '*gpt-5-mini*'           = @(0.25, 2)
+    '*gpt-5*'                = @(1.25, 10)
+    '*gpt-4.1-nano*'         = @(0.1, 0.4)
+    '*gpt-4.1-mini*'         = @(0.4, 1.6)
+    '*gpt-4.1*'              = @(2, 8)
+    '*gpt-4o-mini*'          = @(0.15, 0.6)
+    '*gpt-4o*'               = @(2.5, 10)
+    'o4-mini*'               = @(1.1, 4.4)
+    'o3*'                    = @(2, 8)
+    'mistral-large*'         = @(2, 6)
+    'mistral-small*'         = @(0.1, 0.3)
+    'codestral*'             = @(0.3, 0.9)
+}
+
+function Get-AICommitUsage {
+    # Input and output tokens from a response (or a streamed event) in the
+    # provider's format; $null when it has none
+    param(
+        [string]$Carrier,
+        $Response
+    )
+
+    switch ($Carrier) {
+        'anthropic' {
+            # message_start carries it in .message, message_delta at the top
+            $usage = if ($Response.usage) { $Response.usage } else { $Response.message.usage }
+            if ($usage) {
+                return @{
+                    Input  = [int]$usage.input_tokens + [int]$usage.cache_creation_input_tokens + [int]$usage.cache_read_input_tokens
+                    Output = [int]$usage.output_tokens
+                }
+            }
+        }
+        'openai' {
+            # Streams send it with response.completed
+            $usage = if ($Response.usage) { $Response.usage } else { $Response.response.usage }
+            if ($usage) {
+                return @{ Input = [int]$usage.input_tokens; Output = [int]$usage.output_tokens }
+            }
+        }
+        'ollama' {
+            if ($Response.done) {
+                return @{ Input = [int]$Response.prompt_eval_count; Output = [int]$Response.eval_count }
+            }
+        }
+        'bedrock' {
+            if ($Response.usage) {
+                return @{ Input = [int]$Response.usage.inputTokens; Output = [int]$Response.usage.outputTokens }
+            }
+        }
+        { $_ -in @("google", "vertex") } {
+            if ($Response.usageMetadata) {
+                # Thinking is billed as output
+                return @{
+                    Input  = [int]$Response.usageMetadata.promptTokenCount
+                    Output = [int]$Response.usageMetadata.candidatesTokenCount + [int]$Response.usageMetadata.thoughtsTokenCount
+                }
+            }
+        }
+        default {
+            # Chat completions; Groq streams it in x_groq
+            $usage = if ($Response.usage) { $Response.usage } else { $Response.x_groq.usage }
+            if ($usage) {
+                return @{ Input = [int]$usage.prompt_tokens; Output = [int]$usage.completion_tokens }
+            }
+        }
+    }
+    return $null
+}
+
+function Get-AICommitCost {
+    # USD for the tokens, or $null when the model's price is unknown
+    param(
+        [string]$Carrier,
+        [string]$Model,
+        [hashtable]$Usage
+    )
+
+    if ($Carrier -in @('ollama', 'fake')) {
+        return 0.0
+    }
+    # The model list is never fetched just to price a call
+    $info = Get-AICommitModelInfo -Provider @{ Carrier = $Carrier; Model = $Model } -CacheOnly
+    if ($null -ne $info -and $null -ne $info.input_price -and $null -ne $info.output_price) {
+        return ($Usage.Input * [double]$info.input_price + $Usage.Output * [double]$info.output_price) / 1000000
+    }
+    foreach ($pattern in $script:AICommitPrices.Keys) {
+        if ($Model -like $pattern) {
+            $price = $script:AICommitPrices[$pattern]
+            return ($Usage.Input * $price[0] + $Usage.Output * $price[1]) / 1000000
+        }
+    }
+    return $null
+}
+
+function Add-AICommitUsage {
+    # Records a call for this run's summary and adds it to the running
+    # totals per provider and model in ~/.aicommit/usage.json
+    param(
+        [hashtable]$Provider,
+        [hashtable]$Usage
+    )
+
+    if ($null -eq $Usage) {
+        return
+    }
+    $cost = Get-AICommitCost -Carrier $Provider.Carrier -Model $Provider.Model -Usage $Usage
+    if ($null -eq $script:AICommitRunUsage) {
+        $script:AICommitRunUsage = New-Object System.Collections.Generic.List[object]
+    }
+    $script:AICommitRunUsage.Add([pscustomobject]@{
+        Carrier = $Provider.Carrier
+        Model   = $Provider.Model
+        Input   = $Usage.Input
+        Output  = $Usage.Output
+        Cost    = $cost
+    })
+
+    $usageFile = Get-AICommitDataPath "usage.json"
+    $totals = [ordered]@{}
+    if (Test-Path $usageFile) {
+        try {
+            $saved = Get-Content $usageFile -Raw -Encoding UTF8 | ConvertFrom-Json
+            foreach ($entry in $saved.PSObject.Properties) {
+                $totals[$entry.Name] = $entry.Value
+            }
+        }
+        catch {
+            # Corrupt file, start counting again
+        }
+    }
+    $key = "$($Provider.Carrier)/$($Provider.Model)"
+    $total = $totals[$key]
+    $totals[$key] = [ordered]@{
+        calls  = [int]$total.calls + 1
+        input  = [long]$total.input + $Usage.Input
+        output = [long]$total.output + $Usage.Output
+        cost   = [double]$total.cost + [double]$cost
+    }
+    try {
+        $totals | ConvertTo-Json -Depth 4 | Set-Content -Path $usageFile -Encoding UTF8
+    }
+    catch {
+        Write-Host "Warning: Could not save token usage - $($_.Exception.Message)" -ForegroundColor Yellow
+    }
+}
+
+function Show-AICommitCostSummary {
+    # One line at the end of a run; AI_COMMIT_COST_REPORT=false hides it
+    if ($null -eq $script:AICommitRunUsage -or $script:AICommitRunUsage.Count -eq 0) {
+        return
+    }
+    if ((Get-AICommitSetting -Name "AI_COMMIT_COST_REPORT" -Default "true").ToLower() -in @('false', 'no', 'off', '0')) {
+        return
+    }
+
+    $inputTokens = ($script:AICommitRunUsage | Measure-Object -Property Input -Sum).Sum
+    $outputTokens = ($script:AICommitRunUsage | Measure-Object -Property Output -Sum).Sum
+    $unpriced = @($script:AICommitRunUsage | Where-Object { $null -eq $_.Cost })
+    $cost = ($script:AICommitRunUsage | Where-Object { $null -ne $_.Cost } | Measure-Object -Property Cost -Sum).Sum
+
+    $line = "Usage: $inputTokens input / $outputTokens output tokens"
+    if ($unpriced.Count -eq $script:AICommitRunUsage.Count) {
+        $line += ", cost unknown for $($unpriced[0].Model)"
+    } else {
+        $line += ", about `$$('{0:N4}' -f $cost)"
+        if ($unpriced.Count -gt 0) {
+            $line += " (without $(@($unpriced.Model | Select-Object -Unique) -join ', '))"
+        }
+    }
+
+    $usageFile = Get-AICommitDataPath "usage.json"
+    if (Test-Path $usageFile) {
+        try {
+            $saved = Get-Content $usageFile -Raw -Encoding UTF8 | ConvertFrom-Json
+            $allTime = ($saved.PSObject.Properties.Value | Measure-Object -Property cost -Sum).Sum
+            $line += ", `$$('{0:N2}' -f $allTime) in total"
+        }
+        catch { }
+    }
+    Write-Host $line -ForegroundColor DarkGray
+    $script:AICommitRunUsage.Clear()
+}
@@ -0,0 +1,328 @@
+# Dependency-only commits: when nothing but go.mod/go.sum or package.json
+# and npm lock files changed, the added, removed and bumped packages are
+# read from the files themselves. With AI_COMMIT_LOCKFILE_SUMMARY=local
+# (the default) the message is written here, Dependabot-style ("Bump X from
+# a to b"); with "ai" the model gets the list instead of the lock file diff;
+# "off" treats these commits like any other.
+$script:AICommitDependencyFiles = @{
+    "go.mod"              = "go"
+    "go.sum"              = "go"
+    "package.json"        = "npm"
+    "package-lock.json"   = "npm"
+    "npm-shrinkwrap.json" = "npm"
+}
+
+function Get-AICommitDependencyMode {
+    $mode = "$(Get-AICommitSetting -Name "AI_COMMIT_LOCKFILE_SUMMARY" -Default "local")".Trim().ToLower()
+    if ($mode -in @('false', 'no', 'off', '0')) {
+        return "off"
+    }
+    if ($mode -eq "ai") {
+        return "ai"
+    }
+    return "local"
+}
+
+function Get-AICommitDependencyFileContent {
+    # The file before (HEAD) and after the change (index or working tree)
+    param(
+        [pscustomobject]$File,
+        [switch]$Before,
+        [switch]$Staged
+    )
+
+    if ($Before) {
+        if ($File.Status -eq 'added') {
+            return ""
+        }
+        return ((git show "HEAD:$($File.OldPath)" 2>$null) -join "`n")
+    }
+    if ($File.Status -eq 'deleted') {
+        return ""
+    }
+    if ($Staged) {
+        return ((git show ":$($File.Path)" 2>$null) -join "`n")
+    }
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    return "$(Get-Content -LiteralPath (Join-Path $root $File.Path) -Raw -Encoding UTF8 -ErrorAction SilentlyContinue)"
+}
+
+function Get-AICommitJsonMember {
+    # A property of parsed JSON, whether it came back as a hashtable
+    # (-AsHashtable, needed for package-lock's "" key) or an object
+    param(
+        $Object,
+        [string]$Name
+    )
+
+    if ($null -eq $Object) {
+        return $null
+    }
+    if ($Object -is [System.Collections.IDictionary]) {
+        return $Object[$Name]
+    }
+    $property = $Object.PSObject.Properties[$Name]
+    if ($property) {
+        return $property.Value
+    }
+    return $null
+}
+
+function Get-AICommitJsonNames {
+    param($Object)
+
+    if ($null -eq $Object) {
+        return ,@()
+    }
+    if ($Object -is [System.Collections.IDictionary]) {
+        return ,@($Object.Keys)
+    }
+    return ,@($Object.PSObject.Properties | ForEach-Object { $_.Name })
+}
+
+function Read-AICommitDependencies {
+    # One version of a dependency file as @{ Packages; Rest }: Packages maps
+    # names to @{ Version; Direct }, Rest is everything else in a manifest
+    # (a changed Rest means more than dependencies changed). $null when the
+    # file can't be read.
+    param(
+        [string]$Name,
+        [string]$Content
+    )
+
+    $packages = @{}
+    if ([string]::IsNullOrWhiteSpace($Content)) {
+        return @{ Packages = $packages; Rest = "" }
+    }
+
+    if ($Name -eq "go.mod") {
+        $rest = @()
+        $inRequire = $false
+        foreach ($line in $Content -split "`n") {
+            $line = $line.Trim()
+            if ($line -match '^require\s*\($') {
+                $inRequire = $true
+                continue
+            }
+            if ($inRequire -and $line -eq ')') {
+                $inRequire = $false
+                continue
+            }
+            $entry = if ($inRequire) { $line } elseif ($line -match '^require\s+(.+)$') { $Matches[1] } else { $null }
+            if ($null -eq $entry) {
+                if ($line) {
+                    $rest += $line
+                }
+                continue
+            }
+            if ($entry -match '^(?<module>\S+)\s+(?<version>v\S+)(?<comment>\s*//.*)?$') {
+                $packages[$Matches.module] = @{ Version = $Matches.version; Direct = "$($Matches.comment)" -notmatch 'indirect' }
+            }
+        }
+        return @{ Packages = $packages; Rest = $rest -join "`n" }
+    }
+
+    try {
+        $json = if ((Get-Command ConvertFrom-Json).Parameters.ContainsKey("AsHashtable")) {
+            $Content | ConvertFrom-Json -AsHashtable
+        } else {
+            $Content | ConvertFrom-Json
+        }
+    }
+    catch {
+        return $null
+    }
+
+    $sections = @("dependencies", "devDependencies", "optionalDependencies", "peerDependencies")
+    if ($Name -eq "package.json") {
+        foreach ($section in $sections) {
+            $dependencies = Get-AICommitJsonMember -Object $json -Name $section
+            foreach ($package in Get-AICommitJsonNames -Object $dependencies) {
+                $packages[$package] = @{ Version = "$(Get-AICommitJsonMember -Object $dependencies -Name $package)"; Direct = $true }
+            }
+            if ($json -is [System.Collections.IDictionary]) {
+                $json.Remove($section)
+            } else {
+                $json.PSObject.Properties.Remove($section)
+            }
+        }
+        return @{ Packages = $packages; Rest = ($json | ConvertTo-Json -Compress -Depth 32) }
+    }
+
+    # Lock files: v2 and v3 list every installed package under "packages"
+    # and the project's own dependencies under the "" entry; v1 only has
+    # the top-level "dependencies"
+    $installed = Get-AICommitJsonMember -Object $json -Name "packages"
+    if ($null -ne $installed) {
+        $root = Get-AICommitJsonMember -Object $installed -Name ""
+        $direct = @()
+        foreach ($section in $sections) {
+            $direct += Get-AICommitJsonNames -Object (Get-AICommitJsonMember -Object $root -Name $section)
+        }
+        foreach ($key in Get-AICommitJsonNames -Object $installed) {
+            # Nested copies (a/node_modules/b) are left to their parents
+            if ($key -notmatch '^node_modules/(?<package>(@[^/]+/)?[^/]+)$') {
+                continue
+            }
+            $package = $Matches.package
+            $version = Get-AICommitJsonMember -Object (Get-AICommitJsonMember -Object $installed -Name $key) -Name "version"
+            $packages[$package] = @{ Version = "$version"; Direct = $direct -contains $package }
+        }
+        return @{ Packages = $packages; Rest = "" }
+    }
+    $dependencies = Get-AICommitJsonMember -Object $json -Name "dependencies"
+    foreach ($package in Get-AICommitJsonNames -Object $dependencies) {
+        $version = Get-AICommitJsonMember -Object (Get-AICommitJsonMember -Object $dependencies -Name $package) -Name "version"
+        $packages[$package] = @{ Version = "$version"; Direct = $true }
+    }
+    return @{ Packages = $packages; Rest = "" }
+}
+
+function Compare-AICommitDependencyVersions {
+    # -1, 0 or 1 for the numeric part of two versions; 0 when either has
+    # none (ranges such as "latest", git URLs)
+    param(
+        [string]$From,
+        [string]$To
+    )
+
+    $parsed = foreach ($value in @($From, $To)) {
+        if ($value -notmatch '(\d+(?:\.\d+){0,3})') {
+            return 0
+        }
+        $parts = @($Matches[1] -split '\.')
+        while ($parts.Count -lt 2) {
+            $parts += "0"
+        }
+        [version]($parts -join '.')
+    }
+    return $parsed[0].CompareTo($parsed[1])
+}
+
+function Get-AICommitDependencyChanges {
+    # The package changes of a diff that touches only dependency files, as
+    # @{ Ecosystem; Name; From; To; Kind; Direct }; $null when other files
+    # changed, summaries are off or nothing could be read. Lock file versions
+    # win over the ranges in package.json.
+    param(
+        [string]$Diff,
+        [switch]$Staged
+    )
+
+    if ((Get-AICommitDependencyMode) -eq "off") {
+        return $null
+    }
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = @(ConvertFrom-AICommitDiff -Diff $text)
+    if ($files.Count -eq 0) {
+        return $null
+    }
+    foreach ($file in $files) {
+        if (!$script:AICommitDependencyFiles.ContainsKey((Split-Path $file.Path -Leaf)) -or $file.Status -in @('renamed', 'copied')) {
+            return $null
+        }
+    }
+
+    # Manifests first so the lock files' exact versions replace their ranges
+    $changes = [ordered]@{}
+    $ordered = @($files | Sort-Object { if ((Split-Path $_.Path -Leaf) -eq "package.json") { 0 } else { 1 } })
+    foreach ($file in $ordered) {
+        $name = Split-Path $file.Path -Leaf
+        # go.sum only records checksums of what go.mod requires
+        if ($name -eq "go.sum") {
+            continue
+        }
+        $beforeFile = Read-AICommitDependencies -Name $name -Content (Get-AICommitDependencyFileContent -File $file -Before)
+        $afterFile = Read-AICommitDependencies -Name $name -Content (Get-AICommitDependencyFileContent -File $file -Staged:$Staged)
+        # Scripts, the Go version or replace directives changed as well
+        if ($null -eq $beforeFile -or $null -eq $afterFile -or $beforeFile.Rest -ne $afterFile.Rest) {
+            return $null
+        }
+        $before = $beforeFile.Packages
+        $after = $afterFile.Packages
+        $ecosystem = $script:AICommitDependencyFiles[$name]
+        $directory = Split-Path $file.Path -Parent
+        foreach ($package in @($before.Keys) + @($after.Keys | Where-Object { !$before.ContainsKey($_) })) {
+            $from = if ($before.ContainsKey($package)) { $before[$package].Version } else { "" }
+            $to = if ($after.ContainsKey($package)) { $after[$package].Version } else { "" }
+            if ($from -eq $to) {
+                continue
+            }
+            $kind = if (!$from) { "added" } elseif (!$to) { "removed" } elseif ((Compare-AICommitDependencyVersions -From $from -To $to) -gt 0) { "downgraded" } else { "upgraded" }
+            $direct = if ($after.ContainsKey($package)) { $after[$package].Direct } else { $before[$package].Direct }
+            $changes["$ecosystem`n$directory`n$package"] = [pscustomobject]@{
+                Ecosystem = $ecosystem
+                Name      = $package
+                From      = $from
+                To        = $to
+                Kind      = $kind
+                Direct    = [bool]$direct
+            }
+        }
+    }
+    if ($changes.Count -eq 0) {
+        return $null
+    }
+    return ,@($changes.Values)
+}
+
+function Get-AICommitDependencyLine {
+    # "Bump x from 1.0.0 to 1.1.0", "Add x 2.0.0", "Remove x"
+    param([pscustomobject]$Change)
+
+    switch ($Change.Kind) {
+        'added' { return "Add $($Change.Name) $($Change.To)" }
+        'removed' { return "Remove $($Change.Name)" }
+        'downgraded' { return "Downgrade $($Change.Name) from $($Change.From) to $($Change.To)" }
+        default { return "Bump $($Change.Name) from $($Change.From) to $($Change.To)" }
+    }
+}
+
+function Format-AICommitDependencyChanges {
+    # The changes as the prompt's diff, in place of the lock file lines
+    param([object[]]$Changes)
+
+    $files = @($Changes | ForEach-Object { $_.Ecosystem } | Select-Object -Unique) -join ", "
+    $lines = $Changes | ForEach-Object {
+        "$($_.Ecosystem): $(Get-AICommitDependencyLine -Change $_)$(if (!$_.Direct) { ' (indirect)' })"
+    }
+    return "... (dependency changes read from the $files manifests and lock files, diff not shown)`n$($lines -join "`n")"
+}
+
+function New-AICommitDependencySuggestion {
+    # A suggestion in the shape ConvertFrom-AICommitSuggestion returns,
+    # written without the model
+    param([object[]]$Changes)
+
+    $direct = @($Changes | Where-Object { $_.Direct })
+    $indirect = @($Changes | Where-Object { !$_.Direct })
+    $listed = if ($direct.Count -gt 0) { $direct } else { $indirect }
+
+    if ($listed.Count -eq 1) {
+        $header = Get-AICommitDependencyLine -Change $listed[0]
+        $lines = @()
+    } else {
+        $ecosystems = @($listed | ForEach-Object { $_.Ecosystem } | Select-Object -Unique)
+        $label = if ($ecosystems.Count -eq 1) { "$(if ($ecosystems[0] -eq 'go') { 'Go' } else { 'npm' }) dependencies" } else { "dependencies" }
+        $verb = if (@($listed | Where-Object { $_.Kind -ne 'upgraded' }).Count -eq 0) { "Bump" } else { "Update" }
+        $header = "$verb $($listed.Count) $label"
+        $lines = @($listed | ForEach-Object { "- $(Get-AICommitDependencyLine -Change $_)" })
+    }
+    if ($direct.Count -gt 0 -and $indirect.Count -gt 0) {
+        $lines += "$(if ($lines.Count -gt 0) { "`n" })Also updates $($indirect.Count) indirect $(if ($indirect.Count -eq 1) { 'dependency' } else { 'dependencies' })."
+    }
+
+    # Conventional presets file dependency updates under build/chore
+    $preset = Get-AICommitStylePreset
+    if ($preset -and $preset.Name -in @('angular', 'karma')) {
+        $type = if ($preset.Name -eq 'angular') { "build" } else { "chore" }
+        $header = "$type(deps): $($header.Substring(0, 1).ToLower())$($header.Substring(1))"
+    }
+
+    return @{
+        Header      = $header
+        Description = ($lines -join "`n").Trim()
+        Risk        = ""
+        Breaking    = ""
+    }
+}
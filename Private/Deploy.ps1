@@ -0,0 +1,221 @@
+function Confirm-AICommitRemote {
+    # Called before committing when -push is set. Offers to add 'origin' if the
+    # repository has no remote yet; returns $false when the push should be skipped.
+    if (Test-AICommitRemote) {
+        return $true
+    }
+
+    Write-Host "Warning: No git remote is configured, so there is nothing to push to" -ForegroundColor Yellow
+    $url = Read-Host "Enter a URL to add as 'origin', or press Enter to skip the push"
+    if ([string]::IsNullOrWhiteSpace($url)) {
+        Write-Host "Skipping git push: no remote configured (add one with 'git remote add origin <url>')" -ForegroundColor Yellow
+        return $false
+    }
+
+    git remote add origin $url.Trim() 2>&1 | Out-Host
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Error: Could not add remote 'origin', skipping git push" -ForegroundColor Red
+        return $false
+    }
+    Write-Host "Added remote 'origin': $($url.Trim())" -ForegroundColor Green
+    return $true
+}
+
+function Get-AICommitPushArguments {
+    # Plain 'git push' needs an upstream; a branch without one (e.g. right after
+    # adding the remote) is pushed to the first remote and tracks it from then on
+    git rev-parse --abbrev-ref --symbolic-full-name '@{u}' 2>&1 | Out-Null
+    if ($LASTEXITCODE -eq 0) {
+        return @("push")
+    }
+    $remote = @(git remote 2>$null | Where-Object { $_ }) | Select-Object -First 1
+    return @("push", "--set-upstream", $remote, "HEAD")
+}
+
+# The push helpers return $true on success; command output goes straight to
+# the console so it doesn't end up in the return value
+function Invoke-AICommitGitPush {
+    Write-Host "Pushing to remote..." -ForegroundColor Yellow
+    $pushArgs = Get-AICommitPushArguments
+    git @pushArgs | Out-Host
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Push successful!" -ForegroundColor Green
+        return $true
+    }
+    Write-Host "Push failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+    return $false
+}
+
+function Invoke-AICommitClaspPush {
+    Write-Host "Pushing to clasp..." -ForegroundColor Yellow
+    clasp push | Out-Host
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Clasp push successful!" -ForegroundColor Green
+        return $true
+    }
+    Write-Host "Clasp push failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+    return $false
+}
+
+# Clasp environments (-claspEnv): each has its own project file next to
+# .clasp.json, e.g. .clasp.dev.json and .clasp.prod.json with their own
+# scriptId. The chosen one is put in place of .clasp.json for the run and
+# the original is restored afterwards. A "deploymentId" in the file (or
+# AI_COMMIT_CLASP_DEPLOYMENT_<ENV>) gets that deployment updated with a
+# description written for the environment.
+
+function Use-AICommitClaspEnvironment {
+    # Swaps in .clasp.<env>.json; $false when it doesn't exist
+    param([string]$Environment)
+
+    $variant = ".clasp.$Environment.json"
+    if (!(Test-Path $variant -PathType Leaf)) {
+        $known = @(Get-ChildItem -Path ".clasp.*.json" -File -ErrorAction SilentlyContinue | ForEach-Object { $_.Name -replace '^\.clasp\.(.+)\.json$', '$1' })
+        Write-AICommitError -Message "No clasp project file for '$Environment' ($variant not found)" -Kind "NotClasp"
+        if ($known.Count -gt 0) {
+            Write-Host "Environments found: $($known -join ', ')" -ForegroundColor Yellow
+        }
+        return $false
+    }
+
+    $original = if (Test-Path ".clasp.json") { [System.IO.File]::ReadAllBytes((Resolve-Path ".clasp.json").Path) } else { $null }
+    $claspFile = Join-Path (Get-Location).Path ".clasp.json"
+    Copy-Item -Path $variant -Destination $claspFile -Force
+    $null = Register-AICommitCleanup -Action {
+        if ($null -ne $original) {
+            [System.IO.File]::WriteAllBytes($claspFile, $original)
+        } else {
+            Remove-Item -Path $claspFile -Force -ErrorAction SilentlyContinue
+        }
+    }.GetNewClosure()
+    Write-Host "Using clasp environment $Environment ($variant)" -ForegroundColor Cyan
+    return $true
+}
+
+function Get-AICommitClaspDeploymentId {
+    param([string]$Environment)
+
+    $setting = Get-AICommitSetting -Name "AI_COMMIT_CLASP_DEPLOYMENT_$(($Environment -replace '[^A-Za-z0-9]', '_').ToUpper())"
+    if (![string]::IsNullOrWhiteSpace($setting)) {
+        return $setting.Trim()
+    }
+    try {
+        $project = Get-Content -Path ".clasp.$Environment.json" -Raw -Encoding UTF8 | ConvertFrom-Json
+        return $project.deploymentId
+    }
+    catch {
+        return $null
+    }
+}
+
+function Invoke-AICommitClaspDeploy {
+    # Updates the environment's deployment with a description of the commit
+    # written for that environment; without a deployment ID only the push
+    # happened
+    param(
+        [string]$Environment,
+        [string]$Message,
+        [hashtable]$Provider
+    )
+
+    $deploymentId = Get-AICommitClaspDeploymentId -Environment $Environment
+    if ([string]::IsNullOrWhiteSpace($deploymentId)) {
+        Write-Host "Note: No deploymentId for $Environment (in .clasp.$Environment.json or AI_COMMIT_CLASP_DEPLOYMENT_$($Environment.ToUpper())), pushed without updating a deployment" -ForegroundColor Yellow
+        return $true
+    }
+
+    $hash = "$(git rev-parse --short HEAD)".Trim()
+    $header = ($Message -split "`n")[0]
+    $description = "$Environment`: $header ($hash)"
+    if ($null -ne $Provider) {
+        $prompt = @"
+Write the description of an Apps Script deployment to the $Environment environment, based on the commit message below. One line, at most 100 characters, starting with "$Environment`: ". Say what users of $Environment get; for test or development environments mention what should be checked. Respond with only the description.
+
+Commit message:
+$Message
+"@
+        $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt $prompt
+        if (![string]::IsNullOrWhiteSpace($answer)) {
+            $description = "$((($answer.Trim() -split "`n")[0]).Trim('"', ' ')) ($hash)"
+        }
+    }
+    if ($description.Length -gt 120) {
+        $description = $description.Substring(0, 117) + "..."
+    }
+
+    Write-Host "Deploying to $Environment..." -ForegroundColor Yellow
+    clasp deploy --deploymentId $deploymentId --description $description | Out-Host
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Clasp deployment updated: $description" -ForegroundColor Green
+        return $true
+    }
+    Write-Host "Clasp deploy failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+    return $false
+}
+
+function Invoke-AICommitWranglerDeploy {
+    Write-Host "Deploying to wrangler..." -ForegroundColor Yellow
+    wrangler deploy | Out-Host
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Wrangler deployment successful!" -ForegroundColor Green
+        return $true
+    }
+    Write-Host "Wrangler deployment failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+    return $false
+}
+
+function Invoke-AICommitParallelPush {
+    # Runs git push and clasp push at the same time. Each job captures its
+    # own output so one failure can't scroll the other out of view.
+    Write-Host "Pushing to remote and clasp..." -ForegroundColor Yellow
+
+    $steps = @(
+        @{ Name = "git push"; Command = "git"; Arguments = @(Get-AICommitPushArguments) }
+        @{ Name = "clasp push"; Command = "clasp"; Arguments = @("push") }
+    )
+    $directory = (Get-Location).Path
+
+    $jobs = foreach ($step in $steps) {
+        Start-Job -Name $step.Name -ArgumentList $directory, $step.Command, $step.Arguments -ScriptBlock {
+            param($Directory, $Command, $Arguments)
+            Set-Location $Directory
+            $output = & $Command @Arguments 2>&1 | Out-String
+            [pscustomobject]@{
+                Output   = $output
+                ExitCode = $LASTEXITCODE
+            }
+        }
+    }
+
+    $null = $jobs | Wait-Job
+    $results = foreach ($job in $jobs) {
+        $result = Receive-Job -Job $job -ErrorAction SilentlyContinue | Select-Object -Last 1
+        Remove-Job -Job $job -Force
+        [pscustomobject]@{
+            Name     = $job.Name
+            Output   = if ($result) { $result.Output } else { "" }
+            ExitCode = if ($result) { $result.ExitCode } else { -1 }
+        }
+    }
+
+    # Full output of each step, then a summary that can't get lost
+    foreach ($result in $results) {
+        $color = if ($result.ExitCode -eq 0) { "Cyan" } else { "Red" }
+        Write-Host "`n--- $($result.Name) ---" -ForegroundColor $color
+        if (![string]::IsNullOrWhiteSpace($result.Output)) {
+            Write-Host $result.Output.TrimEnd()
+        }
+    }
+
+    Write-Host "`n--- PUSH SUMMARY ---" -ForegroundColor Cyan
+    foreach ($result in $results) {
+        if ($result.ExitCode -eq 0) {
+            Write-Host ("  {0,-12} successful" -f $result.Name) -ForegroundColor Green
+        } else {
+            Write-Host ("  {0,-12} failed with exit code: {1}" -f $result.Name, $result.ExitCode) -ForegroundColor Red
+        }
+    }
+    Write-Host "--- END PUSH SUMMARY ---`n" -ForegroundColor Cyan
+
+    return (@($results | Where-Object { $_.ExitCode -ne 0 }).Count -eq 0)
+}
@@ -0,0 +1,131 @@
+# Team digest of recent commits, grouped by author and area. The layout of
+# each output format lives in this table so a new format only needs an entry.
+$script:AICommitDigestTemplates = @{
+    markdown = "Format the digest as Markdown: a '# ' title with the period, one '## ' section per area with '- ' bullets naming the author in parentheses, and a short '## Highlights' section at the top."
+    slack    = "Format the digest for Slack mrkdwn: a *bold* title line with the period, a short highlights paragraph, then one *bold* line per area followed by lines starting with '• ' that name the author in parentheses. Do not use '#' headings or [text](url) links."
+}
+
+function ConvertTo-AICommitGitSince {
+    # 1w / 3d / 12h / 2m shorthands; anything else is passed to git as-is
+    param([string]$Since)
+
+    if ($Since -match '^(\d+)\s*([hdwmy])$') {
+        $unit = @{ h = "hours"; d = "days"; w = "weeks"; m = "months"; y = "years" }[$Matches[2]]
+        return "$($Matches[1]) $unit ago"
+    }
+    return $Since
+}
+
+function Get-AICommitDigestCommits {
+    # Commits since the given time with their author and area (the top-level
+    # folder most of their files are in)
+    param([string]$Since)
+
+    $separator = [char]0x1f
+    $records = git log --since="$Since" --no-merges --format="%x1e%h%x1f%an%x1f%s" --name-only
+    $commits = @()
+    foreach ($record in ($records -join "`n") -split [char]0x1e) {
+        $lines = @($record -split "`n" | Where-Object { $_.Trim() })
+        if ($lines.Count -eq 0) {
+            continue
+        }
+        $hash, $author, $subject = $lines[0] -split $separator, 3
+        $files = @($lines | Select-Object -Skip 1)
+        $areas = @($files | ForEach-Object { if ($_ -match '/') { ($_ -split '/')[0] } else { "(root)" } })
+        $area = if ($areas.Count -gt 0) { ($areas | Group-Object | Sort-Object Count -Descending | Select-Object -First 1).Name } else { "(root)" }
+        $commits += [pscustomobject]@{
+            Hash    = $hash
+            Author  = $author
+            Subject = $subject
+            Area    = $area
+        }
+    }
+    return ,$commits
+}
+
+function Invoke-AICommitDigest {
+    param(
+        [string]$Since,
+        [string]$Format,
+        [switch]$Send
+    )
+
+    if ([string]::IsNullOrWhiteSpace($Since)) {
+        $Since = "1w"
+    }
+    if ([string]::IsNullOrWhiteSpace($Format)) {
+        $Format = "markdown"
+    }
+    $Format = $Format.ToLower()
+    # json wraps the markdown digest (see Output.ps1)
+    if (!(Test-AICommitOutputFormat -Format $Format -Allowed (@($script:AICommitDigestTemplates.Keys | Sort-Object) + "json"))) {
+        return
+    }
+    $layout = if ($Format -eq "json") { "markdown" } else { $Format }
+
+    $gitSince = ConvertTo-AICommitGitSince -Since $Since
+    $commits = Get-AICommitDigestCommits -Since $gitSince
+    if ($commits.Count -eq 0) {
+        Write-Host "No commits since $gitSince" -ForegroundColor Green
+        return
+    }
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    # Grouped up front so the model only has to write, not sort
+    $grouped = foreach ($areaGroup in $commits | Group-Object Area | Sort-Object Count -Descending) {
+        "Area: $($areaGroup.Name)"
+        foreach ($commit in $areaGroup.Group) {
+            "  $($commit.Hash) $($commit.Author): $($commit.Subject)"
+        }
+    }
+    $authors = ($commits | Group-Object Author | Sort-Object Count -Descending | ForEach-Object { "$($_.Name) ($($_.Count))" }) -join ", "
+    $repoName = Split-Path -Leaf "$(git rev-parse --show-toplevel)".Trim()
+
+    Write-Host "Writing a digest of $($commits.Count) commit(s) since $gitSince..." -ForegroundColor Yellow
+    $prompt = @"
+Write a short team-facing digest of the work done in the repository '$repoName' since $gitSince, for people who did not follow every commit. Group related commits into a single bullet, describe what changed for users or the team rather than listing every commit, and mention who did the work.
+
+$($script:AICommitDigestTemplates[$layout])
+
+Respond with the digest only, without an introduction or closing remarks.
+
+Commits by author: $authors
+
+Commits by area:
+$($grouped -join "`n")
+"@
+
+    $digest = Invoke-AICommitCompletion -Provider $provider -Prompt $prompt
+    if ([string]::IsNullOrWhiteSpace($digest)) {
+        Write-Host "Error: The model did not return a digest" -ForegroundColor Red
+        return
+    }
+    $digest = $digest.Trim()
+
+    if ($Send) {
+        # Slack-style incoming webhook: {"text": "..."}
+        $webhook = Get-AICommitSetting -Name "AI_COMMIT_DIGEST_WEBHOOK"
+        if ([string]::IsNullOrWhiteSpace($webhook)) {
+            Write-Host "Error: Set AI_COMMIT_DIGEST_WEBHOOK to send the digest" -ForegroundColor Red
+        } else {
+            try {
+                $body = [System.Text.Encoding]::UTF8.GetBytes((@{ text = $digest } | ConvertTo-Json -Compress))
+                $null = Invoke-RestMethod -Uri $webhook -Method Post -Body $body -ContentType "application/json; charset=utf-8"
+                Write-Host "Digest sent" -ForegroundColor Green
+            }
+            catch {
+                Write-Host "Error: Sending the digest failed - $($_.Exception.Message)" -ForegroundColor Red
+            }
+        }
+    }
+
+    # To stdout, so it can be piped or redirected
+    if ($Format -eq "json") {
+        return Format-AICommitOutput -Content (New-AICommitOutput -Body $digest -Data @{ since = $gitSince; commits = $commits.Count; format = $layout }) -Format json
+    }
+    $digest
+}
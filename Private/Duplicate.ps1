@@ -0,0 +1,93 @@
+function Get-AICommitDiffSignature {
+    # Fuzzy fingerprint of a diff: its added and removed lines with
+    # whitespace collapsed. Trivial lines (braces, blank) are left out so
+    # they don't make unrelated changes look alike.
+    param([string]$Diff)
+
+    $signature = New-Object System.Collections.Generic.HashSet[string]
+    foreach ($file in ConvertFrom-AICommitDiff -Diff $Diff) {
+        foreach ($hunk in $file.Hunks) {
+            foreach ($line in $hunk.Lines) {
+                if ($line.Length -lt 1 -or $line[0] -notin @('+', '-')) {
+                    continue
+                }
+                $content = ($line.Substring(1) -replace '\s+', ' ').Trim()
+                if ($content.Length -ge 4) {
+                    [void]$signature.Add("$($line[0])$content")
+                }
+            }
+        }
+    }
+    return ,$signature
+}
+
+function Find-AICommitDuplicate {
+    # Compares the diff with the recent commits and returns the most similar
+    # one above AI_COMMIT_DUPLICATE_THRESHOLD (0-1), or $null
+    param([string]$Diff)
+
+    $current = Get-AICommitDiffSignature -Diff $Diff
+    if ($current.Count -eq 0) {
+        return $null
+    }
+
+    $lookback = [int](Get-AICommitSetting -Name "AI_COMMIT_DUPLICATE_LOOKBACK" -Default 50)
+    $threshold = [double](Get-AICommitSetting -Name "AI_COMMIT_DUPLICATE_THRESHOLD" -Default 0.8)
+
+    $separator = [char]0x1f
+    $log = (git log -n $lookback --no-merges -p --format="%x1e%H%x1f%h%x1f%s" 2>$null) -join "`n"
+    $best = $null
+    foreach ($record in $log -split [char]0x1e) {
+        if ([string]::IsNullOrWhiteSpace($record)) {
+            continue
+        }
+        $newline = $record.IndexOf("`n")
+        if ($newline -lt 0) {
+            continue
+        }
+        $fullHash, $shortHash, $subject = $record.Substring(0, $newline) -split $separator, 3
+        $previous = Get-AICommitDiffSignature -Diff $record.Substring($newline + 1)
+        if ($previous.Count -eq 0) {
+            continue
+        }
+
+        # Jaccard similarity of the two line sets
+        $common = New-Object System.Collections.Generic.HashSet[string] -ArgumentList (, $current)
+        $common.IntersectWith($previous)
+        $union = New-Object System.Collections.Generic.HashSet[string] -ArgumentList (, $current)
+        $union.UnionWith($previous)
+        $similarity = $common.Count / $union.Count
+
+        if ($similarity -ge $threshold -and ($null -eq $best -or $similarity -gt $best.Similarity)) {
+            $best = [pscustomobject]@{
+                Hash       = $fullHash
+                ShortHash  = $shortHash
+                Subject    = $subject
+                Similarity = $similarity
+            }
+        }
+    }
+    return $best
+}
+
+function Test-AICommitDuplicate {
+    # Warns when the change looks like a repeat of a recent commit, e.g. a
+    # reverted change being applied again by accident
+    param([string]$Diff)
+
+    if ((Get-AICommitSetting -Name "AI_COMMIT_DUPLICATE_CHECK" -Default "true").ToLower() -in @('false', 'no', 'off', '0')) {
+        return
+    }
+
+    $duplicate = Find-AICommitDuplicate -Diff $Diff
+    if ($null -eq $duplicate) {
+        return
+    }
+
+    $percent = [int]($duplicate.Similarity * 100)
+    Write-Host "Warning: This looks like a repeat of commit $($duplicate.ShortHash) ($percent% similar): $($duplicate.Subject)" -ForegroundColor Yellow
+    $revert = "$(git log -n 1 --format=%h --grep="This reverts commit $($duplicate.Hash)" 2>$null)".Trim()
+    if ($revert) {
+        Write-Host "Warning: $($duplicate.ShortHash) was reverted by $revert - make sure you mean to apply it again" -ForegroundColor Yellow
+    }
+}
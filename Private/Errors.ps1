@@ -0,0 +1,139 @@
+# What to run next after a fatal error, so no error is a dead end. Hints
+# are keyed by error kind and {0}, {1}... are filled from -Arguments.
+# Errors without a kind of their own get the General hint, so new error
+# paths get one too.
+$script:AICommitErrorHints = [ordered]@{
+    General         = "aicommit selftest (checks the setup) or aicommit help"
+    NotARepo        = "git init (or cd into your repository)"
+    MissingKey      = "aicommit config set {0} (prompts for the key)"
+    UnknownModel    = "aicommit -provider <name>, or aicommit config set AI_COMMIT_PROVIDER <name>"
+    UnknownProvider = "aicommit -provider {0}"
+    BadProvider     = "aicommit config set AI_COMMIT_PROVIDER <one of the providers below>"
+    NoModel         = "aicommit models select -provider {0}"
+    NoStagedChanges = "git add -p (stage the changes to commit)"
+    NoChanges       = "git status"
+    NotClasp        = "clasp clone <scriptId> (or cd into the Apps Script project)"
+    NotWrangler     = "wrangler init (or cd into the Worker project)"
+    TicketRequired  = "aicommit -ticket ABC-123"
+    OrgRequired     = "aicommit config set {0}"
+    Unauthorized    = "aicommit config set {0} (the key was rejected)"
+    ModelNotFound   = "aicommit models -refresh (the model is not available for your key)"
+    RateLimited     = "wait a minute, or aicommit config set AI_COMMIT_PROVIDER_FALLBACKS <provider>"
+    NoResponse      = "check your network, or aicommit -provider <another provider>"
+    ServerError     = "aicommit -provider <another provider>, or try again later"
+    ContextTooSmall = "aicommit -model <a model with a larger context window>, or commit fewer files at a time"
+    UnusableMessage = "aicommit -provider <another provider>, or aicommit config set AI_COMMIT_FORMAT_FALLBACK lenient"
+    CommitFailed    = "git status (a pre-commit hook or conflict may have stopped the commit)"
+    VerifyFailed    = "fix the failure, or aicommit -noVerify to commit anyway"
+    SigningFailed   = "git config user.signingkey (and gpg.format ssh for SSH keys); for GPG, export GPG_TTY=`$(tty)"
+}
+
+function Write-AICommitHint {
+    param(
+        [string]$Kind = "General",
+        [object[]]$Arguments = @()
+    )
+
+    $hint = if ($script:AICommitErrorHints.Contains($Kind)) { $script:AICommitErrorHints[$Kind] } else { $script:AICommitErrorHints.General }
+    Write-Host "Try: $($hint -f $Arguments)" -ForegroundColor Yellow
+}
+
+function Write-AICommitError {
+    # "Error: ..." in red followed by the hint for its kind
+    param(
+        [string]$Message,
+        [string]$Kind = "General",
+        [object[]]$Arguments = @()
+    )
+
+    Write-Host "Error: $Message" -ForegroundColor Red
+    Write-AICommitHint -Kind $Kind -Arguments $Arguments
+}
+
+function Get-AICommitFailureKind {
+    # Error kind for the last failed provider call; $null when the call
+    # itself worked (or never happened) and the caller reported the error
+    $failure = $script:AICommitLastFailure
+    if ($null -eq $failure) {
+        return $null
+    }
+    if ($failure -eq 0) {
+        return "NoResponse"
+    }
+    if ($failure -in @(401, 403)) {
+        return "Unauthorized"
+    }
+    if ($failure -eq 404) {
+        return "ModelNotFound"
+    }
+    if ($failure -eq 429) {
+        return "RateLimited"
+    }
+    if ($failure -ge 500) {
+        return "ServerError"
+    }
+    return "General"
+}
+
+# Response headers that carry the provider's ID for a request: Anthropic
+# (request-id), OpenAI, Mistral and Groq (x-request-id), Bedrock
+# (x-amzn-requestid) and Google (x-cloud-trace-context)
+$script:AICommitRequestIdHeaders = @("request-id", "x-request-id", "x-amzn-requestid", "x-cloud-trace-context")
+
+function Get-AICommitRequestId {
+    # The request ID from response headers: HttpResponseHeaders (streaming
+    # and PowerShell 7 errors), a dictionary (-ResponseHeadersVariable) or a
+    # WebHeaderCollection (Windows PowerShell 5.1). $null when there is none.
+    param($Headers)
+
+    if ($null -eq $Headers) {
+        return $null
+    }
+    foreach ($name in $script:AICommitRequestIdHeaders) {
+        $value = $null
+        if ($Headers.PSObject.Methods['TryGetValues']) {
+            $values = $null
+            if ($Headers.TryGetValues($name, [ref]$values)) {
+                $value = @($values)[0]
+            }
+        } else {
+            try {
+                $value = @($Headers[$name])[0]
+            } catch { }
+        }
+        if (![string]::IsNullOrWhiteSpace("$value")) {
+            return "$value".Trim()
+        }
+    }
+    return $null
+}
+
+function Write-AICommitRequestId {
+    # Shown with every failed call, so a support ticket can name the request
+    param([string]$Carrier)
+
+    if ($script:AICommitLastRequestId) {
+        Write-Host "Request ID: $($script:AICommitLastRequestId) (quote it when contacting $Carrier support)" -ForegroundColor Red
+    }
+}
+
+function Save-AICommitFailedRequest {
+    # Keeps the failed request in debug_failed_request.json and adds a line
+    # with the provider, status and request ID to the debug log in the data
+    # directory
+    param(
+        [string]$Request,
+        [hashtable]$Provider
+    )
+
+    $debugFile = "debug_failed_request.json"
+    $Request | Out-File -FilePath $debugFile -Encoding UTF8
+    Write-Host "Request saved to $debugFile for debugging" -ForegroundColor Yellow
+
+    $status = if ($null -eq $script:AICommitLastFailure) { "-" } else { $script:AICommitLastFailure }
+    $requestId = if ($script:AICommitLastRequestId) { $script:AICommitLastRequestId } else { "-" }
+    $entry = "{0}`t{1}`t{2}`tstatus={3}`trequest-id={4}" -f (Get-Date).ToString("o"), $Provider.Carrier, $Provider.Model, $status, $requestId
+    try {
+        Add-Content -Path (Get-AICommitDataPath "debug.log") -Value $entry -Encoding UTF8
+    } catch { }
+}
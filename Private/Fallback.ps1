@@ -0,0 +1,53 @@
+function Test-AICommitRetryableFailure {
+    # Rate limits (429), server errors (5xx) and timeouts are worth trying
+    # elsewhere; a bad key or request would fail on the next provider too
+    $failure = $script:AICommitLastFailure
+    if ($null -eq $failure) {
+        return $false
+    }
+    return ($failure -eq 0 -or $failure -eq 429 -or $failure -ge 500)
+}
+
+function Invoke-AICommitCompletion {
+    # Calls the provider and, when that fails with a retryable error, the
+    # providers in AI_COMMIT_PROVIDER_FALLBACKS in order, e.g.
+    # "google,openai:gpt-4.1". Every feature goes through here; -Schema asks
+    # for a JSON answer (see Invoke-AICommitProviderCompletion).
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt,
+        [hashtable]$Schema
+    )
+
+    $answer = Invoke-AICommitRetriedCompletion -Provider $Provider -Prompt $Prompt -Schema $Schema
+    if ($null -ne $answer -or !(Test-AICommitRetryableFailure)) {
+        return $answer
+    }
+
+    $chain = @("$(Get-AICommitSetting -Name "AI_COMMIT_PROVIDER_FALLBACKS")" -split ',' | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+    $failed = $Provider
+    foreach ($entry in $chain) {
+        $fallbackCarrier, $fallbackModel = $entry -split ":", 2
+        if ($fallbackCarrier -eq $Provider.Carrier -and (!$fallbackModel -or $fallbackModel -eq $Provider.Model)) {
+            continue
+        }
+
+        $reason = if ($script:AICommitLastFailure -eq 0) { "no response" } else { "HTTP $($script:AICommitLastFailure)" }
+        Write-Host "Warning: $($failed.Carrier) failed ($reason), trying $fallbackCarrier..." -ForegroundColor Yellow
+        $fallbackProvider = Get-AICommitProvider -Carrier $fallbackCarrier -Model $fallbackModel
+        if ($null -eq $fallbackProvider) {
+            continue
+        }
+
+        $answer = Invoke-AICommitRetriedCompletion -Provider $fallbackProvider -Prompt $Prompt -Schema $Schema
+        if ($null -ne $answer) {
+            Write-Host "Answer provided by $($fallbackProvider.Carrier) ($($fallbackProvider.Model))" -ForegroundColor Green
+            return $answer
+        }
+        if (!(Test-AICommitRetryableFailure)) {
+            return $null
+        }
+        $failed = $fallbackProvider
+    }
+    return $null
+}
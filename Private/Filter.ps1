@@ -0,0 +1,295 @@
+# Lock files and build output: thousands of lines that say nothing about
+# the change. A name matches the file anywhere, a trailing / a directory
+# anywhere, and a pattern with / the whole root-relative path.
+$script:AICommitGeneratedPatterns = @(
+    "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+    "composer.lock", "Gemfile.lock", "Cargo.lock", "poetry.lock", "Pipfile.lock", "uv.lock",
+    "go.sum", "packages.lock.json", "flake.lock",
+    "*.min.js", "*.min.css", "*.map",
+    "dist/", "vendor/", "node_modules/"
+)
+
+function Split-AICommitDiffNotes {
+    # The summaries below follow the diff after a "... (" line. They are
+    # split off before the diff is parsed again, or they would become part
+    # of the last file's hunk.
+    param([string]$Diff)
+
+    $match = [regex]::Match($Diff, '(?m)^\.\.\. \(')
+    if (!$match.Success) {
+        return $Diff, ""
+    }
+    return $Diff.Substring(0, $match.Index).TrimEnd(), "`n$($Diff.Substring($match.Index).TrimEnd())"
+}
+
+function Get-AICommitGeneratedPatterns {
+    # The built-in list (unless AI_COMMIT_GENERATED_DEFAULTS is off) plus
+    # AI_COMMIT_GENERATED_FILES, comma-separated
+    $patterns = @()
+    $defaults = "$(Get-AICommitSetting -Name "AI_COMMIT_GENERATED_DEFAULTS" -Default "true")".Trim().ToLower()
+    if ($defaults -notin @('false', 'no', 'off', '0')) {
+        $patterns += $script:AICommitGeneratedPatterns
+    }
+    $patterns += @("$(Get-AICommitSetting -Name "AI_COMMIT_GENERATED_FILES")" -split ',' | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+    return ,$patterns
+}
+
+function Test-AICommitGeneratedPath {
+    param(
+        [string]$Path,
+        [string[]]$Patterns
+    )
+
+    $leaf = Split-Path $Path -Leaf
+    foreach ($pattern in $Patterns) {
+        if ($pattern.EndsWith("/")) {
+            $directory = $pattern.TrimEnd("/")
+            if ("/$Path" -like "*/$directory/*") {
+                return $true
+            }
+        } elseif ($pattern.Contains("/")) {
+            if ($Path -like $pattern.TrimStart("/")) {
+                return $true
+            }
+        } elseif ($leaf -like $pattern) {
+            return $true
+        }
+    }
+    return $false
+}
+
+function Compress-AICommitGeneratedFiles {
+    # Lock files and generated artifacts become "N lines changed in <name>"
+    # instead of their diff. They are still committed.
+    param([string]$Diff)
+
+    $patterns = Get-AICommitGeneratedPatterns
+    if ($patterns.Count -eq 0) {
+        return $Diff
+    }
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $generated = @($files | Where-Object { Test-AICommitGeneratedPath -Path $_.Path -Patterns $patterns })
+    if ($generated.Count -eq 0) {
+        return $Diff
+    }
+    $kept = @($files | Where-Object { $generated -notcontains $_ })
+
+    Write-Host "Note: $($generated.Count) lock or generated file(s) summarized in the prompt: $(($generated | ForEach-Object { Split-Path $_.Path -Leaf } | Select-Object -Unique) -join ', ')" -ForegroundColor Yellow
+    $output = if ($kept.Count -gt 0) { Format-AICommitDiff -Files $kept } else { "" }
+    $summary = $generated | ForEach-Object { "$($_.Added + $_.Removed) lines changed in $($_.Path) ($($_.Status), generated)" }
+    return "$output`n... (lock files and generated files, diff not shown)`n$($summary -join "`n")$notes".TrimStart()
+}
+
+function Compress-AICommitRenamedFiles {
+    # Files moved or copied without changes become "renamed A -> B" instead
+    # of their (empty) diff; moved files with edits keep just the edits
+    param([string]$Diff)
+
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $moved = @($files | Where-Object { $_.Status -in @('renamed', 'copied') -and $_.Hunks.Count -eq 0 -and !$_.Binary })
+    if ($moved.Count -eq 0) {
+        return $Diff
+    }
+    $kept = @($files | Where-Object { $moved -notcontains $_ })
+
+    $output = if ($kept.Count -gt 0) { Format-AICommitDiff -Files $kept } else { "" }
+    $summary = $moved | ForEach-Object { "$($_.Status) $($_.OldPath) $([char]0x2192) $($_.Path)" }
+    return "$output`n... (moved or copied without changes)`n$($summary -join "`n")$notes".TrimStart()
+}
+
+# Extensions that are never worth showing as text, even when git's NUL
+# check lets them through (PDFs and SVG fonts often look like text)
+$script:AICommitBinaryExtensions = @(
+    ".png", ".jpg", ".jpeg", ".gif", ".bmp", ".ico", ".webp", ".tif", ".tiff", ".psd",
+    ".pdf", ".zip", ".gz", ".tgz", ".7z", ".rar", ".jar", ".war",
+    ".woff", ".woff2", ".ttf", ".otf", ".eot",
+    ".mp3", ".mp4", ".mov", ".wav", ".ogg", ".webm",
+    ".exe", ".dll", ".so", ".dylib", ".class", ".pyc", ".wasm", ".sqlite", ".db"
+)
+
+function Test-AICommitBinaryFile {
+    # Binary by git's own check, by extension, or by content that can't be
+    # text (NUL bytes or undecodable characters in the changed lines)
+    param($File)
+
+    if ($File.Binary -or [System.IO.Path]::GetExtension($File.Path).ToLower() -in $script:AICommitBinaryExtensions) {
+        return $true
+    }
+    foreach ($hunk in $File.Hunks) {
+        foreach ($line in $hunk.Lines) {
+            if ($line.IndexOf([char]0) -ge 0 -or $line.IndexOf([char]0xFFFD) -ge 0) {
+                return $true
+            }
+        }
+    }
+    return $false
+}
+
+function Get-AICommitFileSize {
+    # Size in bytes of the new version (the old one for deleted files), or
+    # $null when it can't be found
+    param($File)
+
+    if ($File.Status -ne 'deleted') {
+        $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+        $fullPath = if ($root) { Join-Path $root $File.Path } else { $File.Path }
+        if (Test-Path -LiteralPath $fullPath -PathType Leaf) {
+            return (Get-Item -LiteralPath $fullPath).Length
+        }
+        $size = "$(git cat-file -s ":$($File.Path)" 2>$null)".Trim()
+    } else {
+        $size = "$(git cat-file -s "HEAD:$($File.Path)" 2>$null)".Trim()
+    }
+    if ($size -match '^\d+$') {
+        return [long]$size
+    }
+    return $null
+}
+
+function Compress-AICommitBinaryFiles {
+    # Binary files become "new binary file X (N KB)" instead of their
+    # content, which means nothing to the model
+    param([string]$Diff)
+
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $binary = @($files | Where-Object { Test-AICommitBinaryFile -File $_ })
+    if ($binary.Count -eq 0) {
+        return $Diff
+    }
+    $kept = @($files | Where-Object { $binary -notcontains $_ })
+
+    $output = if ($kept.Count -gt 0) { Format-AICommitDiff -Files $kept } else { "" }
+    $summary = foreach ($file in $binary) {
+        $state = switch ($file.Status) { 'added' { "new" } 'deleted' { "deleted" } 'renamed' { "renamed" } default { "modified" } }
+        $bytes = Get-AICommitFileSize -File $file
+        $size = if ($null -ne $bytes) { " ($([math]::Max(1, [math]::Round($bytes / 1KB))) KB)" } else { "" }
+        "$state binary file $($file.Path)$size"
+    }
+    return "$output`n... (binary files, content not shown)`n$($summary -join "`n")$notes".TrimStart()
+}
+
+function Remove-AICommitPrivateFiles {
+    # .aicommit.env holds keys; diffs of commits made before it was ignored
+    # (series, note) may still contain it
+    param([string]$Diff)
+
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $private = @($files | Where-Object { (Split-Path $_.Path -Leaf) -eq ".aicommit.env" -or (Split-Path $_.OldPath -Leaf) -eq ".aicommit.env" })
+    if ($private.Count -eq 0) {
+        return $Diff
+    }
+    $kept = @($files | Where-Object { $private -notcontains $_ })
+    $output = if ($kept.Count -gt 0) { Format-AICommitDiff -Files $kept } else { "" }
+    return "$output$notes".Trim()
+}
+
+function Get-AICommitIgnoredPaths {
+    # Root-relative paths matching .aicommitignore at the repository root
+    # (gitignore syntax, negation included); git does the matching
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    $ignoreFile = Join-Path $root ".aicommitignore"
+    if (!$root -or !(Test-Path $ignoreFile -PathType Leaf)) {
+        return ,@()
+    }
+    Push-Location $root
+    try {
+        $paths = @(((git ls-files -z --cached --others --ignored "--exclude-from=$ignoreFile") -join "") -split "`0" | Where-Object { $_ })
+    }
+    finally {
+        Pop-Location
+    }
+    return ,$paths
+}
+
+function Remove-AICommitIgnoredFiles {
+    # Replaces the diff of files listed in .aicommitignore (fixtures,
+    # snapshots, generated code) with one summary line each, so their
+    # content never reaches the model. They are still committed.
+    param([string]$Diff)
+
+    $ignoredPaths = Get-AICommitIgnoredPaths
+    if ($ignoredPaths.Count -eq 0) {
+        return $Diff
+    }
+    $lookup = New-Object 'System.Collections.Generic.HashSet[string]' -ArgumentList (,[string[]]$ignoredPaths)
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $kept = @($files | Where-Object { !$lookup.Contains($_.Path) })
+    $ignored = @($files | Where-Object { $lookup.Contains($_.Path) })
+    if ($ignored.Count -eq 0) {
+        return $Diff
+    }
+
+    Write-Host "Note: $($ignored.Count) file(s) in .aicommitignore left out of the prompt (still committed)" -ForegroundColor Yellow
+    $output = if ($kept.Count -gt 0) { Format-AICommitDiff -Files $kept } else { "" }
+    $summary = $ignored | ForEach-Object { "$($_.Path): $($_.Status), +$($_.Added) -$($_.Removed) lines" }
+    return "$output`n... (contents not shown, listed in .aicommitignore)`n$($summary -join "`n")$notes".TrimStart()
+}
+
+function Remove-AICommitIgnoredHunks {
+    # Leaves out hunks whose changed lines all match AI_COMMIT_IGNORE_HUNKS
+    # (a regular expression, e.g. version bumps or copyright years) so they
+    # don't end up as the headline. They are still committed.
+    param([string]$Diff)
+
+    $pattern = Get-AICommitSetting -Name "AI_COMMIT_IGNORE_HUNKS"
+    if ([string]::IsNullOrWhiteSpace($pattern)) {
+        return $Diff
+    }
+    try {
+        $regex = New-Object System.Text.RegularExpressions.Regex($pattern)
+    }
+    catch {
+        Write-Host "Warning: AI_COMMIT_IGNORE_HUNKS is not a valid regular expression - $($_.Exception.Message)" -ForegroundColor Yellow
+        return $Diff
+    }
+
+    $text, $notes = Split-AICommitDiffNotes -Diff $Diff
+    $files = ConvertFrom-AICommitDiff -Diff $text
+    $kept = New-Object System.Collections.Generic.List[object]
+    $ignored = 0
+    foreach ($file in $files) {
+        foreach ($hunk in $file.Hunks) {
+            $changed = @($hunk.Lines | Where-Object { $_.Length -gt 1 -and $_[0] -in @('+', '-') } | ForEach-Object { $_.Substring(1) })
+            if ($changed.Count -gt 0 -and @($changed | Where-Object { !$regex.IsMatch($_) }).Count -eq 0) {
+                $ignored++
+            } else {
+                $kept.Add($hunk)
+            }
+        }
+    }
+    if ($ignored -eq 0) {
+        return $Diff
+    }
+    if ($kept.Count -eq 0) {
+        # Nothing else to describe, so the model gets to see them after all
+        return $Diff
+    }
+
+    Write-Host "Note: $ignored hunk(s) matching AI_COMMIT_IGNORE_HUNKS left out of the prompt (still committed)" -ForegroundColor Yellow
+    # Files without hunks (mode changes, empty new files) stay
+    return "$(Format-AICommitDiff -Files $files -Hunks $kept)$notes"
+}
+
+function Get-AICommitPromptDiff {
+    # The diff as the model gets to see it. Every diff that goes into a
+    # prompt passes through here: .aicommit.env is dropped, files in
+    # .aicommitignore are left out, plain renames, binary, lock and
+    # generated files summarized and AI_COMMIT_IGNORE_HUNKS hunks removed.
+    # None of this changes what is committed.
+    param([string]$Diff)
+
+    if ([string]::IsNullOrWhiteSpace($Diff)) {
+        return $Diff
+    }
+    $Diff = Remove-AICommitPrivateFiles -Diff $Diff
+    $Diff = Remove-AICommitIgnoredFiles -Diff $Diff
+    $Diff = Compress-AICommitRenamedFiles -Diff $Diff
+    $Diff = Compress-AICommitBinaryFiles -Diff $Diff
+    $Diff = Compress-AICommitGeneratedFiles -Diff $Diff
+    return Remove-AICommitIgnoredHunks -Diff $Diff
+}
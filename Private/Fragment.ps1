@@ -0,0 +1,118 @@
+# Changelog fragments for towncrier/reno-style workflows: with
+# AI_COMMIT_CHANGELOG_FRAGMENTS on, each commit also adds a news file such
+# as changes/ABC-123.feature.md, written from the commit's own header.
+$script:AICommitDefaultFragmentTypes = "feat=feature,fix=bugfix,perf=feature,docs=doc,revert=bugfix,breaking=removal"
+
+function Get-AICommitFragmentType {
+    # The fragment type for a message, from AI_COMMIT_FRAGMENT_TYPES
+    # (type=fragment pairs, "breaking" for breaking changes of any type);
+    # anything not listed is "misc"
+    param([pscustomobject]$Message)
+
+    $types = @{}
+    foreach ($pair in "$(Get-AICommitSetting -Name "AI_COMMIT_FRAGMENT_TYPES" -Default $script:AICommitDefaultFragmentTypes)" -split ',') {
+        $parts = $pair -split '=', 2
+        if ($parts.Count -eq 2 -and $parts[0].Trim()) {
+            $types[$parts[0].Trim().ToLower()] = $parts[1].Trim()
+        }
+    }
+
+    if ($Message.Breaking -and $types.ContainsKey("breaking")) {
+        return $types["breaking"]
+    }
+    if ($Message.Type -and $types.ContainsKey($Message.Type.ToLower())) {
+        return $types[$Message.Type.ToLower()]
+    }
+    return "misc"
+}
+
+function New-AICommitChangelogFragment {
+    # Writes the fragment for -Message and returns its root-relative path,
+    # or $null when fragments are off. The name comes from
+    # AI_COMMIT_FRAGMENT_NAME with {id} (the ticket, or +<slug> when there
+    # is none, towncrier's orphan fragments), {type} and {slug}.
+    param(
+        [pscustomobject]$Message,
+        [string]$Ticket
+    )
+
+    if (!(Test-AICommitSettingEnabled -Name "AI_COMMIT_CHANGELOG_FRAGMENTS")) {
+        return $null
+    }
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    if (!$root) {
+        return $null
+    }
+
+    $subject = "$($Message.Subject)".Trim()
+    $slug = ($subject.ToLower() -replace '[^a-z0-9]+', '-').Trim('-')
+    if ($slug.Length -gt 40) {
+        $slug = $slug.Substring(0, 40).TrimEnd('-')
+    }
+    if (!$Ticket -and $Message.Tickets.Count -gt 0) {
+        $Ticket = $Message.Tickets[0]
+    }
+    $id = if ($Ticket) { $Ticket.Trim().TrimStart('#') } else { "+$slug" }
+    $type = Get-AICommitFragmentType -Message $Message
+
+    $directory = "$(Get-AICommitSetting -Name "AI_COMMIT_FRAGMENT_DIR" -Default "changes")".Trim().Trim('/', '\')
+    $pattern = Get-AICommitSetting -Name "AI_COMMIT_FRAGMENT_NAME" -Default "{id}.{type}.md"
+    $name = $pattern.Replace("{id}", $id).Replace("{type}", $type).Replace("{slug}", $slug)
+
+    # Two commits for the same ticket and type get numbered files
+    $relativePath = if ($directory) { "$directory/$name" } else { $name }
+    $extension = [System.IO.Path]::GetExtension($name)
+    $stem = $relativePath.Substring(0, $relativePath.Length - $extension.Length)
+    $counter = 1
+    while (Test-Path -LiteralPath (Join-Path $root $relativePath)) {
+        $counter++
+        $relativePath = "$stem.$counter$extension"
+    }
+
+    # One sentence, like a hand-written entry
+    $text = if ($subject) { $subject.Substring(0, 1).ToUpper() + $subject.Substring(1) } else { "" }
+    if ($text -and $text -notmatch '[.!?]$') {
+        $text += "."
+    }
+
+    # It is committed with the change, so it has to be in the work tree
+    $fullPath = Join-Path $root $relativePath
+    if (!(Test-AICommitRepoPathAllowed -Path $fullPath)) {
+        return $null
+    }
+    New-Item -ItemType Directory -Path (Split-Path $fullPath -Parent) -Force | Out-Null
+    Set-Content -Path $fullPath -Value $text -Encoding UTF8
+    Write-Host "Wrote changelog fragment $relativePath" -ForegroundColor Cyan
+    return $relativePath
+}
+
+function Add-AICommitChangelogFragment {
+    # Writes the fragment and makes it part of the commit: staged for
+    # all/staged runs, added to the selection's paths when only chosen files
+    # are committed. Returns the fragment's path or $null.
+    param(
+        [string]$Message,
+        [string]$Ticket,
+        [hashtable]$Selection
+    )
+
+    $fragment = New-AICommitChangelogFragment -Message (ConvertFrom-AICommitMessageText -Text $Message) -Ticket $Ticket
+    if (!$fragment) {
+        return $null
+    }
+    if ($Selection.Mode -eq "files") {
+        $Selection.Paths = @($Selection.Paths) + $fragment
+    } else {
+        Add-AICommitChanges -Paths @($fragment)
+    }
+    return $fragment
+}
+
+function Remove-AICommitChangelogFragment {
+    # The commit didn't happen; the next run writes a new fragment
+    param([string]$Path)
+
+    git rm -q --cached --ignore-unmatch -- (ConvertTo-AICommitPathspec -Paths @($Path)) 2>&1 | Out-Null
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    Remove-Item -LiteralPath (Join-Path $root $Path) -Force -ErrorAction SilentlyContinue
+}
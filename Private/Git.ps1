@@ -0,0 +1,308 @@
+# Git helpers shared by every command. Features work on the parsed diff
+# and these functions instead of running their own git commands:
+#   ConvertFrom-AICommitDiff / Format-AICommitDiff - unified diff <-> files and hunks
+#   Get-AICommitFullDiff    - the diff to describe (all changes, -Staged, -Paths or -Amend)
+#   Add-AICommitChanges     - stages what Get-AICommitFullDiff showed
+#   Invoke-AICommitGuardedCommit - stages and commits, undoing the staging if interrupted
+# Paths are always taken from the repository root, never the current
+# directory. Tests/Git.Tests.ps1 covers them against temporary repositories.
+
+function ConvertFrom-AICommitDiff {
+    # Parses unified diff output (git diff) into one object per file, each
+    # holding its hunks with the function context git puts after the @@ line.
+    # Features that work on files or hunks should use this instead of running
+    # their own git commands.
+    param([string[]]$Diff)
+
+    $files = New-Object System.Collections.Generic.List[object]
+    $file = $null
+    $hunk = $null
+
+    foreach ($line in ($Diff -join "`n") -split "`n") {
+        $line = $line.TrimEnd("`r")
+
+        if ($line -match '^diff --git a/(.+) b/(.+)$') {
+            $file = [pscustomobject]@{
+                Path        = $Matches[2]
+                OldPath     = $Matches[1]
+                Status      = 'modified'
+                Binary      = $false
+                HeaderLines = New-Object System.Collections.Generic.List[string]
+                Hunks       = New-Object System.Collections.Generic.List[object]
+                Added       = 0
+                Removed     = 0
+            }
+            $file.HeaderLines.Add($line)
+            $files.Add($file)
+            $hunk = $null
+            continue
+        }
+
+        # Anything before the first "diff --git" line isn't part of a file
+        if ($null -eq $file) {
+            continue
+        }
+
+        if ($line -match '^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$') {
+            $hunk = [pscustomobject]@{
+                File     = $file.Path
+                Header   = $line
+                Context  = $Matches[5]
+                OldStart = [int]$Matches[1]
+                OldLines = if ($Matches[2]) { [int]$Matches[2] } else { 1 }
+                NewStart = [int]$Matches[3]
+                NewLines = if ($Matches[4]) { [int]$Matches[4] } else { 1 }
+                Lines    = New-Object System.Collections.Generic.List[string]
+                Added    = 0
+                Removed  = 0
+            }
+            $file.Hunks.Add($hunk)
+            continue
+        }
+
+        if ($null -ne $hunk) {
+            # Hunk context lines always carry a leading space, so an empty
+            # line can only be the trailing newline of the diff
+            if ($line -eq '') {
+                continue
+            }
+            $hunk.Lines.Add($line)
+            if ($line.StartsWith('+')) {
+                $hunk.Added++
+                $file.Added++
+            } elseif ($line.StartsWith('-')) {
+                $hunk.Removed++
+                $file.Removed++
+            }
+            continue
+        }
+
+        # File header lines (index, mode, rename and ---/+++ lines)
+        $file.HeaderLines.Add($line)
+        if ($line -match '^new file mode') {
+            $file.Status = 'added'
+        } elseif ($line -match '^deleted file mode') {
+            $file.Status = 'deleted'
+        } elseif ($line -match '^rename from (.+)$') {
+            $file.Status = 'renamed'
+            $file.OldPath = $Matches[1]
+        } elseif ($line -match '^rename to (.+)$') {
+            $file.Path = $Matches[1]
+        } elseif ($line -match '^copy from (.+)$') {
+            $file.Status = 'copied'
+            $file.OldPath = $Matches[1]
+        } elseif ($line -match '^copy to (.+)$') {
+            $file.Path = $Matches[1]
+        } elseif ($line -match '^Binary files ') {
+            $file.Binary = $true
+        }
+    }
+
+    return ,$files
+}
+
+function Format-AICommitDiff {
+    # Renders parsed files (optionally a subset of their hunks) back into a
+    # unified diff that git apply accepts.
+    param(
+        [object[]]$Files,
+        [object[]]$Hunks
+    )
+
+    $output = New-Object System.Text.StringBuilder
+    foreach ($file in $Files) {
+        $fileHunks = if ($PSBoundParameters.ContainsKey('Hunks')) {
+            @($file.Hunks | Where-Object { $Hunks -contains $_ })
+        } else {
+            @($file.Hunks)
+        }
+        if ($fileHunks.Count -eq 0 -and $file.Hunks.Count -gt 0) {
+            continue
+        }
+
+        foreach ($line in $file.HeaderLines) {
+            [void]$output.Append("$line`n")
+        }
+        foreach ($hunk in $fileHunks) {
+            [void]$output.Append("$($hunk.Header)`n")
+            foreach ($line in $hunk.Lines) {
+                [void]$output.Append("$line`n")
+            }
+        }
+    }
+
+    return $output.ToString()
+}
+
+function Get-AICommitFullDiff {
+    # -Staged: only what is in the index. -Paths: only these root-relative
+    # files (see Select-AICommitChanges). -Amend: the index against HEAD's
+    # parent, i.e. HEAD's changes plus the staged ones. Default: everything.
+    # Renames and copies are detected (-M -C), so a moved file is a few
+    # header lines instead of a whole delete and add.
+    param(
+        [switch]$Staged,
+        [string[]]$Paths,
+        [switch]$Amend
+    )
+
+    if ($Amend) {
+        return (git diff -M -C --cached (Get-AICommitAmendBase) -- ':(top)' ':(top,exclude).aicommit.env') -join "`n"
+    }
+    if ($Staged) {
+        return (git diff -M -C --cached -- ':(top)' ':(top,exclude).aicommit.env') -join "`n"
+    }
+    $pathspecs = if ($Paths.Count -gt 0) { ConvertTo-AICommitPathspec -Paths $Paths } else { @(':(top)') }
+
+    # Get untracked files (NUL-separated so unusual names aren't quoted).
+    # Never send the per-repo settings file (API keys) to the model.
+    $untrackedFiles = @(((git ls-files -z --others --exclude-standard -- @pathspecs) -join "") -split "`0" | Where-Object {
+        ![string]::IsNullOrWhiteSpace($_) -and (Split-Path $_ -Leaf) -ne ".aicommit.env"
+    })
+
+    try {
+        # Mark new files as intent-to-add so git diff shows them like any other
+        # change, with proper headers and binary detection
+        if ($untrackedFiles.Count -gt 0) {
+            git add -N -- $untrackedFiles 2>&1 | Out-Null
+        }
+        $fullDiff = (git diff -M -C HEAD -- @pathspecs ':(top,exclude).aicommit.env') -join "`n"
+    }
+    finally {
+        # Leave the index exactly as we found it
+        if ($untrackedFiles.Count -gt 0) {
+            git reset -q -- $untrackedFiles 2>&1 | Out-Null
+        }
+    }
+
+    return $fullDiff
+}
+
+function Get-AICommitDiffHash {
+    # Quick fingerprint to tell whether the changes moved since generation
+    param([string]$Diff)
+
+    $sha = [System.Security.Cryptography.SHA256]::Create()
+    try {
+        $bytes = $sha.ComputeHash([System.Text.Encoding]::UTF8.GetBytes("$Diff"))
+        return [System.BitConverter]::ToString($bytes) -replace '-', ''
+    }
+    finally {
+        $sha.Dispose()
+    }
+}
+
+function Add-AICommitChanges {
+    # Stage everything (or only -Paths) except the per-repo settings file (API keys).
+    # Like the diff it is taken from the repository root, also when run
+    # from a subdirectory.
+    param([string[]]$Paths)
+
+    $pathspecs = if ($Paths.Count -gt 0) { ConvertTo-AICommitPathspec -Paths $Paths } else { @(':(top)') }
+    git add -- @pathspecs ':(top,exclude).aicommit.env' 2>&1 | Out-Null
+}
+
+function Test-AICommitSigning {
+    # Whether commits are signed: -gpgSign, or commit.gpgsign in git config
+    # (GPG, SSH or X.509, as gpg.format says)
+    if ($script:AICommitSignCommits) {
+        return $true
+    }
+    return ("$(git config --bool commit.gpgsign 2>$null)".Trim() -eq "true")
+}
+
+function Get-AICommitSignArguments {
+    # -S for -gpgSign; commit.gpgsign needs no argument
+    if ($script:AICommitSignCommits) {
+        return ,@("-S")
+    }
+    return ,@()
+}
+
+function New-AICommitCommit {
+    # With -Paths only those files are committed; anything else that is
+    # staged stays staged for a later commit. -Amend replaces HEAD.
+    param(
+        [string]$Message,
+        [string[]]$Paths,
+        [switch]$Amend
+    )
+
+    # Write message to temp file to avoid command-line parsing issues
+    $tempMsgFile = New-AICommitTempFile -Prefix "commit"
+    try {
+        Set-Content -Path $tempMsgFile -Value $Message -Encoding UTF8 -NoNewline
+        $commitArgs = @(if ($Amend) { "--amend" }) + (Get-AICommitSignArguments)
+        # Shown as it comes (hooks can be slow) and kept to explain a failure
+        if ($Paths.Count -gt 0) {
+            git commit @commitArgs -F $tempMsgFile -- @(ConvertTo-AICommitPathspec -Paths $Paths) 2>&1 | ForEach-Object { "$_" } | Tee-Object -Variable commitOutput | Out-Host
+        } else {
+            git commit @commitArgs -F $tempMsgFile 2>&1 | ForEach-Object { "$_" } | Tee-Object -Variable commitOutput | Out-Host
+        }
+        $exitCode = $LASTEXITCODE
+    }
+    finally {
+        Remove-Item $tempMsgFile -Force -ErrorAction SilentlyContinue
+    }
+
+    if ($exitCode -ne 0) {
+        # Signing problems (no key, locked agent, missing pinentry) look like
+        # any other failure unless called out
+        if ((Test-AICommitSigning) -and ($commitOutput -join "`n") -match 'gpg failed to sign|failed to write commit object|error: (gpg|ssh|signing)|ssh-keygen|No secret key|Load key|signing failed') {
+            Write-AICommitError -Message "Git could not sign the commit (exit code $exitCode), nothing was committed" -Kind "SigningFailed"
+            return $false
+        }
+        Write-Host "Git commit failed with exit code: $exitCode" -ForegroundColor Red
+        Write-AICommitHint -Kind "CommitFailed"
+        return $false
+    }
+
+    Write-Host "`nCommit successful!" -ForegroundColor Green
+
+    # Show what was committed
+    $lastCommit = git log -1 --oneline
+    Write-Host "Created: $lastCommit" -ForegroundColor Cyan
+    return $true
+}
+
+function Invoke-AICommitGuardedCommit {
+    # Stages (with -Stage) and commits; if the run is interrupted before the
+    # commit exists (e.g. Ctrl+C in a slow pre-commit hook) the index is put
+    # back the way it was
+    param(
+        [string]$Message,
+        [string[]]$Paths,
+        [switch]$Stage,
+        [switch]$Amend
+    )
+
+    $indexTree = "$(git write-tree 2>$null)".Trim()
+    $restoreIndex = $null
+    if ($LASTEXITCODE -eq 0 -and $indexTree) {
+        $restoreIndex = Register-AICommitCleanup -Action {
+            git read-tree $indexTree 2>&1 | Out-Null
+            Write-Host "Restored the staging area after the interrupted commit" -ForegroundColor Yellow
+        }.GetNewClosure()
+    }
+
+    if ($Stage) {
+        Write-Host "Staging changes..." -ForegroundColor Yellow
+        Add-AICommitChanges -Paths $Paths
+    }
+
+    Write-Host "Committing..." -ForegroundColor Yellow
+    $committed = New-AICommitCommit -Message $Message -Paths $Paths -Amend:$Amend
+    if ($null -ne $restoreIndex) {
+        Unregister-AICommitCleanup -Entry $restoreIndex
+    }
+    if ($committed) {
+        Write-AICommitProgress -Name "committed" -Data @{ hash = "$(git rev-parse HEAD)".Trim() }
+    }
+    return $committed
+}
+
+function Test-AICommitRemote {
+    # True when the repository has at least one remote to push to
+    $remotes = @(git remote 2>$null | Where-Object { $_ })
+    return $remotes.Count -gt 0
+}
@@ -0,0 +1,312 @@
+# Help text for every command, shown by 'aicommit help [command]' and
+# 'aicommit examples'. New commands and flags only need an entry here.
+# Examples are wrapped with the unary comma so PowerShell doesn't flatten
+# the pairs into one list.
+$script:AICommitHelp = [ordered]@{
+    commit = @{
+        Usage    = "aicommit [-push] [-clasp] [-claspEnv <env>] [-noPushOnClaspFailure] [-wrangler] [-export] [-staged] [-interactive] [-patch] [-split [-plan [-output tree|json]]] [-amend] [-gpgSign] [-noVerify] [-fast] [-breaking] [-n <count>] [-header <header>] [-m <message>] [-provider <name>] [-model <name>] [-ticket <id>] [-coAuthor <"Name <email>">] [-repo <path>] [-progress json]"
+        Summary  = "Generate a message for all changes, review it and commit (default)"
+        Details  = @(
+            "Collects the diff of modified and new files, asks the AI for a header and description and lets you accept, edit or cancel it before everything is staged and committed."
+            "-push runs git push, -clasp runs clasp push and -wrangler runs wrangler deploy after a successful commit. With -push and -clasp both pushes run at the same time and a summary shows how each went; add -noPushOnClaspFailure to push to clasp first and only push to git if that worked."
+            "-claspEnv <env> pushes to one of several Apps Script projects: .clasp.<env>.json (e.g. .clasp.dev.json, .clasp.prod.json) stands in for .clasp.json during the run. The commit gets a 'Clasp-Environment: <env>' footer, and a deployment named by deploymentId in that file (or AI_COMMIT_CLASP_DEPLOYMENT_<ENV>) is updated with a description written for the environment."
+            "If the repository has no remote yet, -push asks for a URL to add as 'origin' (the first push then sets the upstream) or skips the push when none is given."
+            "When some changes are staged and others are not, you are asked whether to commit only the staged changes (default), stage everything or pick files; AI_COMMIT_MIXED_CHANGES=staged or all answers this in advance. -staged (or AI_COMMIT_STAGED=true) always describes and commits only what is staged and never touches the index."
+            "-interactive (or (i) at the staging question) shows staged and unstaged files side by side: 'f <n>' moves a whole file to the other side, 'h <n>' goes through its hunks like git add -p, 'd <n>' shows its diff, and Enter writes the message for what is staged."
+            "-patch goes through every unstaged hunk like git add -p (y, n, a for the rest of the file, d to skip it, q to stop); the message describes only the hunks you picked plus anything already staged."
+            "Changes that span several areas, such as frontend and backend files, can be split into one commit per area with its own message (AI_COMMIT_SPLIT, AI_COMMIT_SPLIT_GROUPS)."
+            "-split has the AI group the hunks of unrelated changes into logical commits, each with a proposed message. After you confirm the plan (or ask to (r)egroup), each group is reviewed, staged and committed in turn; the working tree is never touched, so skipped groups stay as uncommitted changes."
+            "-split -plan prints the proposed commits (groups, files, hunks and headers) as a tree, or as JSON with -output json, without staging or committing anything."
+            "-amend writes a new message for the last commit plus anything staged now and runs git commit --amend. A commit that is already on a remote is only amended after you confirm, since that rewrites published history."
+            "-export writes the diff to git-diff-export.txt and exits without calling the AI."
+            "-provider and -model override the configured provider and model for this run."
+            "-n 3 (or AI_COMMIT_SUGGESTIONS) asks for several alternative messages and lets you pick one by number, or review it first with e<number>."
+            "-header <header> keeps your own header and has the AI write only a description that fits it, for teams that write subjects by hand. Regenerating in the review then only replaces the description."
+            "-m <message> (-message) commits with your own message instead of asking the AI; staging choices, header checks, the ticket line and -push, -clasp and -wrangler work as usual."
+            "-gpgSign signs the commit (git commit -S) with your GPG, SSH or X.509 key as gpg.format says; commit.gpgsign in git config is honored without it. When signing fails you get that error and a hint instead of a generic commit failure."
+            "Commits that only change go.mod/go.sum, package.json or npm lock files get a 'Bump X from a to b' message built from the packages that changed, without asking the AI (AI_COMMIT_LOCKFILE_SUMMARY=ai has the AI write it from that list, off turns it off)."
+            "AI_COMMIT_VERIFY_COMMAND (e.g. go build ./...) runs before the message is generated; a failure warns, or stops the commit with AI_COMMIT_VERIFY_MODE=block. AI_COMMIT_VERIFY_TRAILER adds 'Verified: <command> passes' to the message. -noVerify skips it for one run."
+            "-fast is meant for tiny commits: it switches to the provider's cheapest quick model (or AI_COMMIT_FAST_MODEL), skips extended thinking, style examples, the model list and duplicate checks, caps the diff at AI_COMMIT_FAST_MAX_DIFF_LENGTH characters (default 8000) and doesn't retry or re-ask for the format."
+            "Scopes of earlier type(scope): headers are learned from history and given to the AI, so it reuses them; for conventional headers the review offers (s)cope to pick one of them (AI_COMMIT_SCOPE_LEARNING, AI_COMMIT_SCOPE_HISTORY)."
+            "AI_COMMIT_STYLE_PRESET (angular, karma, plain or custom) makes headers follow a convention; suggestions that don't are asked for again."
+            "Diffs that remove or change public API (exported Go and JavaScript/TypeScript symbols, functions in Public/) or bump a major version are checked by the AI, which adds a 'BREAKING CHANGE:' footer if callers break. -breaking marks the commit as breaking without asking (AI_COMMIT_BREAKING_DETECTION=false turns the check off)."
+            "With AI_COMMIT_RISK_SUMMARY on, diffs that touch sensitive paths (AI_COMMIT_SENSITIVE_PATHS), delete files or are very large get a 'Risk:' line for reviewers."
+            "-ticket adds a 'Refs: <id>' line to the message. When AI_COMMIT_REQUIRE_TICKET is on, the ticket is taken from -ticket or the branch name, or asked for, and nothing is committed without one. Otherwise a ticket in the branch name (feature/JIRA-1234-add-auth) is used when there is one, unless AI_COMMIT_BRANCH_TICKET is false; AI_COMMIT_TICKET_POSITION prefix or suffix puts it in the header instead."
+            "-coAuthor `"Name <email>`" adds a Co-authored-by trailer; give several separated by commas. AI_COMMIT_CO_AUTHORS (separated by ';') lists co-authors added to every commit."
+            "-repo <path> works on the repository at <path> instead of the current directory, like git -C. GIT_DIR and GIT_WORK_TREE are honored too; files such as .clasp.json and .aicommitignore are read from that work tree."
+            "-progress json writes one JSON object per line to stderr (events verifying, collecting_diff, calling_provider, tokens_streamed, awaiting_user, committed) for GUI wrappers and editor plugins."
+        )
+        Examples = @(
+            ,@("aicommit", "Review and commit all changes")
+            ,@("aicommit -push", "Commit, then push to the git remote")
+            ,@("aicommit -push -clasp", "Commit, push to git and push the Apps Script project")
+            ,@("aicommit -push -clasp -noPushOnClaspFailure", "Only push to git once clasp push succeeded")
+            ,@("aicommit -claspEnv prod -push", "Commit, push to git and deploy the prod Apps Script project")
+            ,@("aicommit -push -wrangler", "Commit, push and deploy the Cloudflare Worker")
+            ,@("aicommit -staged", "Commit only what you staged, e.g. with git add -p")
+            ,@("aicommit -interactive", "Pick files and hunks to commit, then write the message")
+            ,@("aicommit -patch", "Choose hunk by hunk what goes into the commit")
+            ,@("aicommit -split", "Let the AI sort unrelated changes into several commits")
+            ,@("aicommit -split -plan -output json", "Print the proposed split for review, change nothing")
+            ,@("aicommit -amend", "Fold staged changes into the last commit with a fresh message")
+            ,@("aicommit -gpgSign -push", "Sign the commit, then push")
+            ,@("aicommit -noVerify", "Commit without running AI_COMMIT_VERIFY_COMMAND first")
+            ,@("aicommit -fast", "Quick, cheap commit for a tiny change")
+            ,@("aicommit -n 3", "Pick from three alternative messages")
+            ,@("aicommit -header `"fix: handle nil pool`"", "Write the header yourself, let the AI describe the change")
+            ,@("aicommit -m `"Fix typo in README`" -push", "Commit with your own message and push")
+            ,@("aicommit -provider openai -model o4-mini", "Try a different model once")
+            ,@("aicommit -ticket ABC-123", "Reference a ticket in the message")
+            ,@("aicommit -coAuthor `"Ada Lovelace <ada@example.com>`"", "Credit a pairing partner with a Co-authored-by trailer")
+            ,@("aicommit -repo ../api -push", "Commit and push another repository without leaving this one")
+            ,@("aicommit -breaking", "Mark the commit as a breaking change")
+            ,@("aicommit -export", "Write the diff that would be analyzed to a file")
+            ,@("aicommit -progress json 2> progress.ndjson", "Log progress events for a wrapper")
+        )
+    }
+    revert   = @{
+        Usage    = "aicommit revert <ref>"
+        Summary  = "Revert a commit with a message that explains why"
+        Details  = @(
+            "Runs git revert --no-commit <ref>, asks for the reason and generates a message describing what is undone and why. Git's 'This reverts commit' line is kept."
+            "Cancelling the review aborts the revert."
+        )
+        Examples = @(
+            ,@("aicommit revert HEAD", "Revert the last commit")
+            ,@("aicommit revert a3f2d45", "Revert a specific commit")
+        )
+    }
+    note     = @{
+        Usage    = "aicommit note [ref] [-push]"
+        Summary  = "Attach an AI-written git note with the rationale behind a commit"
+        Details  = @(
+            "Generates a git notes annotation for <ref> (default HEAD) from its message and diff, for detail that is too long for the commit message. If the commit already has a note, it is refined instead. Review, edit or cancel it before it is attached with git notes add."
+            "-push also pushes the notes ref (refs/notes/commits) to the first remote, since git push doesn't include notes."
+        )
+        Examples = @(
+            ,@("aicommit note", "Add a note to the last commit")
+            ,@("aicommit note a3f2d45 -push", "Note a specific commit and push the notes")
+            ,@("git log --notes", "Show commits with their notes")
+        )
+    }
+    'squash-message' = @{
+        Usage    = "aicommit squash-message [-base <branch>] [-output github|text|markdown|json|trailer]"
+        Summary  = "Write the message for squash-merging the current branch"
+        Details  = @(
+            "Summarizes all commits since the branch left <branch> (default: the remote's default branch, or AI_COMMIT_SQUASH_BASE) into one title and description, followed by the list of commits and Co-authored-by lines for everyone else who committed on the branch."
+            "-output github (default) shows title and description for pasting into GitHub's squash merge box. text prints the whole message, markdown a '# ' title with the description, json an object with title, body and trailers, and trailer only the Co-authored-by lines, all to stdout for scripts."
+        )
+        Examples = @(
+            ,@("aicommit squash-message", "Message for merging into the default branch")
+            ,@("aicommit squash-message -base develop", "Compare against another branch")
+            ,@("aicommit squash-message -output json | ConvertFrom-Json", "Use title and body in a script")
+        )
+    }
+    series   = @{
+        Usage    = "aicommit series [-range <base>..HEAD]"
+        Summary  = "Propose better messages for every commit of a patch series"
+        Details  = @(
+            "Goes through the commits of the range (default: since the branch left the remote's default branch, or AI_COMMIT_SQUASH_BASE) and asks the AI for an improved message for each, from its own diff and the current message. Trailers such as Signed-off-by: are kept."
+            "A table shows the old and new subjects. Apply all, only some by number (1,3-4), review one with r <n> first, or cancel. The approved messages are applied with an interactive rebase that runs by itself; the code of the commits doesn't change."
+            "The working tree must be clean and the range must end at HEAD. Rewording commits that are already pushed needs a force push afterwards."
+        )
+        Examples = @(
+            ,@("aicommit series", "Improve the messages of the current branch's commits")
+            ,@("aicommit series -range HEAD~5..HEAD", "Only the last five commits")
+        )
+    }
+    hook     = @{
+        Usage    = "aicommit hook install|uninstall|status"
+        Summary  = "Pre-fill plain git commit with a suggested message"
+        Details  = @(
+            "install adds a prepare-commit-msg hook, so git commit opens the editor with a generated message for the staged changes above git's comments. Commits with -m, -F, merges, squashes and amends are left alone. Installing again updates the hook."
+            "If no suggestion can be made (provider down, no key) the commit goes ahead with a comment saying why, plus a header made from the file names with AI_COMMIT_HOOK_FALLBACK=heuristic. AI_COMMIT_HOOK_FAIL_MODE=hard stops the commit instead."
+            "The hook goes where git looks for hooks, including core.hooksPath. An existing prepare-commit-msg hook is kept as prepare-commit-msg.aicommit-chained and runs first; uninstall puts it back."
+            "Set AICOMMIT_HOOK_SKIP=1 to commit without a suggestion once."
+        )
+        Examples = @(
+            ,@("aicommit hook install", "Get suggestions in git commit's editor")
+            ,@("aicommit hook status", "Show whether the hook is installed and where")
+            ,@("aicommit hook uninstall", "Remove the hook and restore the previous one")
+        )
+    }
+    'pr-fill' = @{
+        Usage    = "aicommit pr-fill [-base <branch>] [-output markdown|text|json] [-send]"
+        Summary  = "Fill in the pull request template from the branch's changes"
+        Details  = @(
+            "Reads .github/PULL_REQUEST_TEMPLATE.md (or the other places GitHub looks for it; a simple Summary/Testing/Checklist template otherwise) and fills in its sections from the commits and diff since the branch left <branch> (default: the remote's default branch, or AI_COMMIT_SQUASH_BASE). Screenshot sections get a placeholder and only checklist items the diff clearly shows are ticked."
+            "The markdown is written to stdout; -output json wraps it in an object with the base branch and commit count for scripts. -send also puts it into the open pull request of the current branch on GitHub, using the token in GITHUB_TOKEN."
+        )
+        Examples = @(
+            ,@("aicommit pr-fill | Set-Clipboard", "Copy the description for pasting")
+            ,@("aicommit pr-fill > pr.md; gh pr create --body-file pr.md", "Open a pull request with it")
+            ,@("aicommit pr-fill -send", "Update the open pull request's description")
+        )
+    }
+    digest   = @{
+        Usage    = "aicommit digest [-since <time>] [-output markdown|slack|json] [-send]"
+        Summary  = "Write a team digest of recent commits, grouped by area and author"
+        Details  = @(
+            "Collects the commits since -since (default 1w; 3d, 12h, 2m or anything git log --since accepts) and has the AI write a digest for people who didn't follow every commit. The digest is written to stdout."
+            "-output picks the layout: markdown (default) or slack, or json for the markdown digest with the period and commit count. -send also posts it to the Slack-compatible incoming webhook in AI_COMMIT_DIGEST_WEBHOOK."
+        )
+        Examples = @(
+            ,@("aicommit digest", "Markdown digest of the last week")
+            ,@("aicommit digest -since 2w -output slack -send", "Post the last two weeks to Slack")
+            ,@("aicommit digest > digest.md", "Save the digest to a file")
+        )
+    }
+    style    = @{
+        Usage    = "aicommit style [show | learn [-from <range>]]"
+        Summary  = "Teach the AI your team's commit message style with examples"
+        Details  = @(
+            "Suggestions are written to match example messages from .aicommit-examples.txt in the repository root (or AI_COMMIT_STYLE_FILE) and the commits listed in AI_COMMIT_STYLE_EXAMPLES. At most AI_COMMIT_STYLE_COUNT (default 5) are used."
+            "learn picks good examples from -from (default HEAD~100..HEAD): short imperative headers with a body, no WIP, fixup or merge commits, and different kinds of changes. Edit the file freely and commit it to share the style."
+            "show prints the examples that will be used."
+        )
+        Examples = @(
+            ,@("aicommit style learn -from main~50..main", "Learn from the last 50 commits on main")
+            ,@("aicommit style show", "See the examples in use")
+            ,@("aicommit config set AI_COMMIT_STYLE_EXAMPLES a3f2d45,9c1e0b2", "Use specific commits as examples")
+        )
+    }
+    models   = @{
+        Usage    = "aicommit models [select] [-refresh] [-provider <name>]"
+        Summary  = "List the models your API key can use"
+        Details  = @(
+            "The list is cached in ~/.aicommit for AI_COMMIT_MODEL_CACHE_TTL_HOURS (default 24); -refresh fetches it again. The configured model is marked with *."
+            "select numbers the list and asks for a new default, which is saved as AI_COMMIT_PROVIDER and AI_COMMIT_MODEL in ~/.aicommit/config.env."
+        )
+        Examples = @(
+            ,@("aicommit models", "Models of the configured provider")
+            ,@("aicommit models -provider anthropic -refresh", "Fresh list of Claude models")
+            ,@("aicommit models select -provider google", "Pick a new default Gemini model")
+        )
+    }
+    config   = @{
+        Usage    = "aicommit config [list | list-keys | set <NAME> [value] | unset <NAME> | rotate-key <provider> [check-old] | log [count]]"
+        Summary  = "Manage settings in ~/.aicommit/config.env"
+        Details  = @(
+            "Settings use the same names as the environment variables. Environment variables override the config file and .aicommit.env in the repository root overrides both."
+            "set without a value prompts for it, so API keys stay out of shell history. Every change is recorded (names only) in the audit log shown by 'config log'."
+            "Storing a key warns about keys that reach further than needed (e.g. OpenAI user or admin keys) and asks for a label. list-keys shows each key's provider, kind, source, label and age, never the key."
+            "rotate-key asks for the provider's new key, checks it against the API, replaces the old one in the config file in one step and offers to check that the old key was revoked (check-old does so without asking). With input redirected the key is read from stdin and nothing is asked."
+        )
+        Examples = @(
+            ,@("aicommit config set AI_COMMIT_MODEL claude-3-5-haiku-20241022", "Change the default model")
+            ,@("aicommit config set ANTHROPIC_API_KEY_AICOMMIT", "Store a key (prompted)")
+            ,@("aicommit config list-keys", "See which keys are set and how old they are")
+            ,@("aicommit config rotate-key openai", "Replace the OpenAI key with a new one")
+            ,@("aicommit config log", "See when settings were changed")
+        )
+    }
+    repos    = @{
+        Usage    = "aicommit repos [list | set <name> <value> | unset <name> | forget [path] | clear]"
+        Summary  = "List the repositories you commit in and their remembered defaults"
+        Details  = @(
+            "Every commit records the repository in ~/.aicommit/repos.json (the 50 most recent are kept). -provider, -model and -push given with a commit are remembered for that repository and used next time without the flag; -push:`$false turns pushing off again."
+            "set and unset change the current repository's defaults: provider, model, style (an AI_COMMIT_STYLE_PRESET) or push (on or off). Flags, .aicommit.env, .aicommit.yaml and environment variables still take precedence; remembered defaults only win over the config file."
+            "forget drops one repository (default: the current one) and clear drops them all."
+        )
+        Examples = @(
+            ,@("aicommit repos", "Recently used repositories, the current one marked with *")
+            ,@("aicommit repos set style angular", "Always use the angular preset in this repository")
+            ,@("aicommit repos unset push", "Stop pushing after every commit here")
+        )
+    }
+    'encrypt-key' = @{
+        Usage    = "aicommit encrypt-key"
+        Summary  = "Encrypt an API key with age for use in your profile"
+        Details  = @(
+            "Needs the age CLI and AI_COMMIT_AGE_IDENTITY pointing to an identity file. Prints a value starting with 'age:' that can be used in place of the plain key."
+        )
+        Examples = @(
+            ,@("aicommit encrypt-key", "Encrypt a key for the configured identity")
+        )
+    }
+    selftest = @{
+        Usage    = "aicommit selftest"
+        Summary  = "Verify aicommit works on this machine"
+        Details  = @(
+            "Runs the full pipeline in a temporary repository with the offline fake provider, then runs aicommit itself there with auto-accept. No API key or network access is needed, and your settings and remembered repositories are left alone."
+        )
+        Examples = @(
+            ,@("aicommit selftest", "Run all checks")
+        )
+    }
+    help     = @{
+        Usage    = "aicommit help [command]"
+        Summary  = "Show all commands, or the details of one"
+        Details  = @()
+        Examples = @(
+            ,@("aicommit help", "Overview of all commands")
+            ,@("aicommit help revert", "Details and examples for revert")
+        )
+    }
+    examples = @{
+        Usage    = "aicommit examples [command]"
+        Summary  = "Show common command and flag combinations"
+        Details  = @()
+        Examples = @(
+            ,@("aicommit examples", "Examples for every command")
+            ,@("aicommit examples commit", "Examples for the default commit flow")
+        )
+    }
+}
+
+# Subcommands that don't need to run inside a git repository
+$script:AICommitRepositoryFreeCommands = @('help', 'examples', 'selftest', 'config', 'models', 'encrypt-key', 'repos')
+
+function Get-AICommitCommandNames {
+    return @($script:AICommitHelp.Keys | Where-Object { $_ -ne "commit" })
+}
+
+function Show-AICommitExamples {
+    param([string]$Command)
+
+    $names = if ([string]::IsNullOrWhiteSpace($Command)) { @($script:AICommitHelp.Keys) } else { @($Command.ToLower()) }
+    foreach ($name in $names) {
+        if (!$script:AICommitHelp.Contains($name)) {
+            Write-Host "Error: Unknown command: $name" -ForegroundColor Red
+            return
+        }
+        Write-Host "`n# $name" -ForegroundColor Cyan
+        foreach ($example in $script:AICommitHelp[$name].Examples) {
+            Write-Host ("  {0,-55} {1}" -f $example[0], $example[1]) -ForegroundColor White
+        }
+    }
+    Write-Host ""
+}
+
+function Show-AICommitHelp {
+    param([string]$Command)
+
+    # Short overview first; details only when asked for a command
+    if ([string]::IsNullOrWhiteSpace($Command)) {
+        Write-Host "`nUsage: aicommit [command] [flags]`n" -ForegroundColor Cyan
+        foreach ($name in $script:AICommitHelp.Keys) {
+            Write-Host ("  {0,-12} {1}" -f $name, $script:AICommitHelp[$name].Summary) -ForegroundColor White
+        }
+        Write-Host "`nRun 'aicommit help <command>' for details or 'aicommit examples' for common combinations.`n" -ForegroundColor Yellow
+        return
+    }
+
+    $name = $Command.ToLower()
+    if (!$script:AICommitHelp.Contains($name)) {
+        Write-Host "Error: Unknown command: $Command" -ForegroundColor Red
+        Write-Host "Available commands: $((Get-AICommitCommandNames) -join ', ')" -ForegroundColor Yellow
+        return
+    }
+
+    $entry = $script:AICommitHelp[$name]
+    Write-Host "`n$($entry.Summary)`n" -ForegroundColor Cyan
+    Write-Host "Usage: $($entry.Usage)`n" -ForegroundColor White
+    foreach ($paragraph in $entry.Details) {
+        Write-Host "$paragraph`n" -ForegroundColor White
+    }
+    Write-Host "Examples:" -ForegroundColor Cyan
+    foreach ($example in $entry.Examples) {
+        Write-Host ("  {0,-55} {1}" -f $example[0], $example[1]) -ForegroundColor White
+    }
+    Write-Host ""
+}
@@ -0,0 +1,235 @@
+# 'aicommit hook': a prepare-commit-msg hook so a plain 'git commit' opens
+# the editor with a generated message already filled in. The hook goes
+# where git looks for hooks (core.hooksPath included); a hook that was
+# there first is kept next to it and still runs, before ours.
+
+# First line after the shebang, how our hook is told apart from others
+$script:AICommitHookMarker = "# aicommit prepare-commit-msg hook"
+
+function Get-AICommitHookPaths {
+    # Where the hook lives and where a replaced hook is kept
+    $directory = "$(git rev-parse --git-path hooks 2>$null)".Trim()
+    if (![System.IO.Path]::IsPathRooted($directory)) {
+        $directory = Join-Path (Get-Location) $directory
+    }
+    return @{
+        Directory = $directory
+        Hook      = Join-Path $directory "prepare-commit-msg"
+        Chained   = Join-Path $directory "prepare-commit-msg.aicommit-chained"
+    }
+}
+
+function Test-AICommitHookOurs {
+    param([string]$Path)
+
+    return ((Test-Path $Path -PathType Leaf) -and (Get-Content -Path $Path -TotalCount 2 -Encoding UTF8) -contains $script:AICommitHookMarker)
+}
+
+function Install-AICommitHook {
+    $paths = Get-AICommitHookPaths
+    # Installing again rewrites aicommit's own hook, e.g. after an update
+    $update = Test-AICommitHookOurs -Path $paths.Hook
+    if (!(Test-Path $paths.Directory)) {
+        New-Item -ItemType Directory -Path $paths.Directory -Force | Out-Null
+    }
+    if (!$update -and (Test-Path $paths.Hook)) {
+        if (Test-Path $paths.Chained) {
+            Write-Host "Error: $($paths.Chained) already exists; remove it or merge it into the current hook first" -ForegroundColor Red
+            return
+        }
+        Move-Item -Path $paths.Hook -Destination $paths.Chained
+        Write-Host "Kept the existing prepare-commit-msg hook; it runs before aicommit's" -ForegroundColor Cyan
+    }
+
+    # The hook runs outside this session: it starts the same PowerShell and
+    # imports this copy of the module
+    $shell = Get-Command pwsh -ErrorAction SilentlyContinue
+    if ($null -eq $shell) {
+        $shell = Get-Command powershell -ErrorAction SilentlyContinue
+    }
+    if ($null -eq $shell) {
+        Write-Host "Error: Could not find pwsh or powershell to run the hook with" -ForegroundColor Red
+        return
+    }
+    $manifest = (Join-Path (Split-Path $PSScriptRoot -Parent) "AICommit.psd1") -replace '\\', '/'
+    $shellPath = $shell.Source -replace '\\', '/'
+
+    # Only plain commits get a message: -m, -F, merges, squashes and
+    # amends already have one. The module call only fails the commit when
+    # it exits with 3 (AI_COMMIT_HOOK_FAIL_MODE=hard).
+    $script = @"
+#!/bin/sh
+$script:AICommitHookMarker
+# Installed by 'aicommit hook install'; 'aicommit hook uninstall' removes it.
+chained="`$(dirname "`$0")/prepare-commit-msg.aicommit-chained"
+if [ -x "`$chained" ]; then
+    "`$chained" "`$@" || exit `$?
+fi
+case "`$2" in
+    ""|template) ;;
+    *) exit 0 ;;
+esac
+[ -n "`$AICOMMIT_HOOK_SKIP" ] && exit 0
+AICOMMIT_HOOK_MESSAGE_FILE="`$1" "$shellPath" -NoProfile -NonInteractive -Command "Import-Module '$manifest'; aicommit hook run" < /dev/null
+[ `$? -eq 3 ] && exit 1
+exit 0
+"@
+    [System.IO.File]::WriteAllText($paths.Hook, ($script -replace "`r`n", "`n"), (New-Object System.Text.UTF8Encoding $false))
+    # Windows PowerShell has no $IsWindows; git for Windows ignores the mode
+    if ($PSVersionTable.PSEdition -eq "Core" -and !$IsWindows) {
+        chmod +x $paths.Hook
+    }
+    $verb = if ($update) { "Updated" } else { "Installed" }
+    Write-Host "$verb the prepare-commit-msg hook in $($paths.Directory)" -ForegroundColor Green
+    Write-Host "git commit now opens the editor with a suggested message; set AICOMMIT_HOOK_SKIP=1 to skip it once" -ForegroundColor Cyan
+}
+
+function Uninstall-AICommitHook {
+    $paths = Get-AICommitHookPaths
+    if (!(Test-AICommitHookOurs -Path $paths.Hook)) {
+        Write-Host "The aicommit hook is not installed in $($paths.Directory)" -ForegroundColor Yellow
+        return
+    }
+    Remove-Item -Path $paths.Hook -Force
+    if (Test-Path $paths.Chained) {
+        Move-Item -Path $paths.Chained -Destination $paths.Hook
+        Write-Host "Restored the previous prepare-commit-msg hook" -ForegroundColor Cyan
+    }
+    Write-Host "Removed the aicommit hook" -ForegroundColor Green
+}
+
+function Get-AICommitHookFallback {
+    # AI_COMMIT_HOOK_FALLBACK=heuristic: a plain header from the staged
+    # file names, so the editor isn't empty when the provider is down
+    $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff -Staged)
+    if ($files.Count -eq 0) {
+        return $null
+    }
+    if ($files.Count -eq 1) {
+        $verb = switch ($files[0].Status) { 'added' { "Add" } 'deleted' { "Remove" } 'renamed' { "Rename" } default { "Update" } }
+        return "$verb $(Split-Path $files[0].Path -Leaf)"
+    }
+    $list = ($files | ForEach-Object { "- $($_.Path)" }) -join "`n"
+    return "Update $($files.Count) files`n`n$list"
+}
+
+function Write-AICommitHookFailure {
+    # AI_COMMIT_HOOK_FAIL_MODE soft (default): the commit goes ahead with a
+    # comment saying why there is no suggestion (and the heuristic message,
+    # if asked for). hard: the hook exits with 3, which stops the commit.
+    param(
+        [string]$File,
+        [string]$Reason
+    )
+
+    $mode = "$(Get-AICommitSetting -Name "AI_COMMIT_HOOK_FAIL_MODE" -Default "soft")".Trim().ToLower()
+    if ($mode -eq "hard") {
+        Write-Host "Error: aicommit could not suggest a message ($Reason); commit stopped (AI_COMMIT_HOOK_FAIL_MODE=hard, AICOMMIT_HOOK_SKIP=1 skips the hook)" -ForegroundColor Red
+        exit 3
+    }
+
+    Write-Host "Warning: aicommit could not suggest a message ($Reason)" -ForegroundColor Yellow
+    try {
+        $commentChar = "$(git config core.commentChar 2>$null)".Trim()
+        if (!$commentChar -or $commentChar -eq "auto") {
+            $commentChar = "#"
+        }
+        $fallback = $null
+        if ("$(Get-AICommitSetting -Name "AI_COMMIT_HOOK_FALLBACK")".Trim().ToLower() -eq "heuristic") {
+            $fallback = Get-AICommitHookFallback
+        }
+        $note = "$commentChar aicommit could not suggest a message: $Reason"
+        $text = if ($fallback) { "$fallback`n`n$note" } else { "`n$note" }
+        $existing = [System.IO.File]::ReadAllText($File)
+        [System.IO.File]::WriteAllText($File, "$text`n$existing", (New-Object System.Text.UTF8Encoding $false))
+    }
+    catch {
+        # The message file is left as git wrote it
+    }
+}
+
+function Invoke-AICommitHookRun {
+    # Called by the hook: writes the suggestion for the staged changes
+    # above what git put in the message file (its comments or a template).
+    # Nothing is asked; failures are handled by Write-AICommitHookFailure.
+    $file = $env:AICOMMIT_HOOK_MESSAGE_FILE
+    if ([string]::IsNullOrWhiteSpace($file) -or !(Test-Path $file)) {
+        Write-Host "Error: 'aicommit hook run' is meant to be called by the prepare-commit-msg hook" -ForegroundColor Red
+        return
+    }
+    try {
+        $stagedDiff = Get-AICommitFullDiff -Staged
+        $diff = Get-AICommitPromptDiff -Diff $stagedDiff
+        if ([string]::IsNullOrWhiteSpace($diff)) {
+            return
+        }
+        # Dependency bumps are written without the model by default
+        # (AI_COMMIT_LOCKFILE_SUMMARY)
+        $dependencyChanges = Get-AICommitDependencyChanges -Diff $stagedDiff -Staged
+        if ($null -ne $dependencyChanges -and (Get-AICommitDependencyMode) -eq "local") {
+            $parsed = New-AICommitDependencySuggestion -Changes $dependencyChanges
+        } else {
+            if ($null -ne $dependencyChanges) {
+                $diff = Format-AICommitDependencyChanges -Changes $dependencyChanges
+            }
+            $provider = Get-AICommitProvider
+            if ($null -eq $provider) {
+                Write-AICommitHookFailure -File $file -Reason "no provider or API key configured"
+                return
+            }
+            $prompt = New-AICommitPrompt -Task "Analyze this git diff and suggest a commit message. " -Diff $diff -Provider $provider -Template (Get-AICommitPromptTemplate)
+            $parsed = Get-AICommitSuggestion -Provider $provider -Prompt $prompt
+            if ($null -eq $parsed) {
+                $reason = switch ($script:AICommitLastFailure) { $null { "unusable answer from $($provider.Carrier)" } 0 { "no response from $($provider.Carrier)" } default { "HTTP $_ from $($provider.Carrier)" } }
+                if ($script:AICommitLastRequestId) {
+                    $reason += ", request ID $($script:AICommitLastRequestId)"
+                }
+                Write-AICommitHookFailure -File $file -Reason $reason
+                return
+            }
+        }
+        $message = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+        if (Test-AICommitBranchTicketEnabled) {
+            Add-AICommitTicketReference -Message $message -Ticket (Get-AICommitBranchTicket)
+        }
+        Add-AICommitCoAuthors -Message $message -CoAuthors (Get-AICommitCoAuthors)
+        Add-AICommitConfiguredTrailers -Message $message -Trailers (Get-AICommitConfiguredTrailers -Ticket (Get-AICommitBranchTicket))
+
+        $existing = [System.IO.File]::ReadAllText($file)
+        $text = "$(Format-AICommitMessage -Message $message)`n$existing"
+        [System.IO.File]::WriteAllText($file, $text, (New-Object System.Text.UTF8Encoding $false))
+    }
+    catch {
+        Write-AICommitHookFailure -File $file -Reason $_.Exception.Message
+    }
+}
+
+function Invoke-AICommitHook {
+    param([string[]]$Arguments)
+
+    $action = if ($Arguments.Count -gt 0) { $Arguments[0].ToLower() } else { "status" }
+    switch ($action) {
+        'install' {
+            Install-AICommitHook
+        }
+        'uninstall' {
+            Uninstall-AICommitHook
+        }
+        'run' {
+            Invoke-AICommitHookRun
+        }
+        'status' {
+            $paths = Get-AICommitHookPaths
+            if (Test-AICommitHookOurs -Path $paths.Hook) {
+                $chained = if (Test-Path $paths.Chained) { ", chained to the previous hook" } else { "" }
+                Write-Host "Installed in $($paths.Directory)$chained" -ForegroundColor Green
+            } else {
+                Write-Host "Not installed (hooks directory: $($paths.Directory))" -ForegroundColor Yellow
+            }
+        }
+        default {
+            Write-Host "Error: Unknown hook action: $action" -ForegroundColor Red
+            Write-Host "Available actions: install, uninstall, status" -ForegroundColor Yellow
+        }
+    }
+}
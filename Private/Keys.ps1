@@ -0,0 +1,380 @@
+function Get-AICommitApiKey {
+    param([string]$Name)
+
+    $value = Get-AICommitSetting -Name $Name
+    if ([string]::IsNullOrWhiteSpace($value)) {
+        Write-AICommitError -Message "$Name environment variable not set" -Kind "MissingKey" -Arguments @($Name)
+        Write-Host "Or set it with: `$env:$Name = 'your-api-key-here'" -ForegroundColor Yellow
+        Write-Host "Or add $Name=your-api-key-here to .aicommit.env in the repository root" -ForegroundColor Yellow
+        return $null
+    }
+
+    # Plain keys are used as-is
+    if (!$value.StartsWith("age:")) {
+        return $value
+    }
+
+    # Keys encrypted with 'aicommit encrypt-key' need the age identity to decrypt
+    $identity = Get-AICommitSetting -Name "AI_COMMIT_AGE_IDENTITY"
+    if ([string]::IsNullOrWhiteSpace($identity) -or !(Test-Path $identity)) {
+        Write-Host "Error: $Name is age-encrypted but AI_COMMIT_AGE_IDENTITY does not point to an identity file" -ForegroundColor Red
+        return $null
+    }
+    # age doesn't expand ~ or relative paths the way PowerShell does
+    $identity = (Resolve-Path $identity).ProviderPath
+    if (!(Get-Command age -ErrorAction SilentlyContinue)) {
+        Write-Host "Error: $Name is age-encrypted but the age CLI was not found (https://age-encryption.org)" -ForegroundColor Red
+        return $null
+    }
+
+    $cipherFile = [System.IO.Path]::GetTempFileName()
+    try {
+        [System.IO.File]::WriteAllBytes($cipherFile, [Convert]::FromBase64String($value.Substring(4)))
+        $plain = age --decrypt -i $identity $cipherFile 2>$null
+        if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($plain)) {
+            Write-Host "Error: Could not decrypt $Name with identity $identity" -ForegroundColor Red
+            return $null
+        }
+        return "$plain".Trim()
+    }
+    catch {
+        Write-Host "Error: $Name does not contain a valid encrypted key - $($_.Exception.Message)" -ForegroundColor Red
+        return $null
+    }
+    finally {
+        Remove-Item $cipherFile -Force -ErrorAction SilentlyContinue
+    }
+}
+
+function Protect-AICommitApiKey {
+    $identity = Get-AICommitSetting -Name "AI_COMMIT_AGE_IDENTITY"
+    if ([string]::IsNullOrWhiteSpace($identity) -or !(Test-Path $identity)) {
+        Write-Host "Error: AI_COMMIT_AGE_IDENTITY must point to an age identity file" -ForegroundColor Red
+        Write-Host "Create one with: age-keygen -o ~/.aicommit/identity.txt" -ForegroundColor Yellow
+        return
+    }
+    $identity = (Resolve-Path $identity).ProviderPath
+    if (!(Get-Command age -ErrorAction SilentlyContinue) -or !(Get-Command age-keygen -ErrorAction SilentlyContinue)) {
+        Write-Host "Error: age and age-keygen must be installed (https://age-encryption.org)" -ForegroundColor Red
+        return
+    }
+
+    $recipient = "$(age-keygen -y $identity)".Trim()
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($recipient)) {
+        Write-Host "Error: Could not read the public key from $identity" -ForegroundColor Red
+        return
+    }
+
+    $secureKey = Read-Host "API key to encrypt" -AsSecureString
+    $bstr = [Runtime.InteropServices.Marshal]::SecureStringToBSTR($secureKey)
+    $cipherFile = [System.IO.Path]::GetTempFileName()
+    try {
+        # Pipe the key through stdin so the plain text never touches disk
+        [Runtime.InteropServices.Marshal]::PtrToStringBSTR($bstr) | age -r $recipient -o $cipherFile
+        if ($LASTEXITCODE -ne 0) {
+            Write-Host "Error: age encryption failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+            return
+        }
+        $encoded = [Convert]::ToBase64String([System.IO.File]::ReadAllBytes($cipherFile))
+    }
+    finally {
+        [Runtime.InteropServices.Marshal]::ZeroFreeBSTR($bstr)
+        Remove-Item $cipherFile -Force -ErrorAction SilentlyContinue
+    }
+
+    Write-Host "`nEncrypted key (use it as the value of your API key variable):" -ForegroundColor Green
+    Write-Host "age:$encoded" -ForegroundColor White
+}
+
+function Test-AICommitKeyName {
+    # Settings that hold an API key: the providers' key variables and
+    # anything named like one, including workspace keys
+    param([string]$Name)
+
+    if ($Name -match '_API_KEY(_AICOMMIT(_\w+)?)?$') {
+        return $true
+    }
+    return (@($script:AICommitProviders.Values | Where-Object { $_.KeyName -eq $Name }).Count -gt 0)
+}
+
+function Get-AICommitKeyScope {
+    # What a key's prefix tells about its reach, where the provider makes
+    # that visible: @{ Kind; Warning }. Encrypted keys can't be inspected.
+    param([string]$Value)
+
+    switch -regex ($Value) {
+        '^age:' {
+            return @{ Kind = "encrypted"; Warning = $null }
+        }
+        '^sk-admin-' {
+            return @{ Kind = "admin"; Warning = "This is an OpenAI admin key, which can manage your whole organization. Use a project key with model access only" }
+        }
+        '^sk-(proj|svcacct)-' {
+            return @{ Kind = "project"; Warning = $null }
+        }
+        '^sk-ant-admin' {
+            return @{ Kind = "admin"; Warning = "This is an Anthropic admin key, which can manage your organization. Use a regular API key from a dedicated workspace" }
+        }
+        '^sk-ant-' {
+            return @{ Kind = "workspace"; Warning = $null }
+        }
+        '^sk-or-' {
+            return @{ Kind = "api"; Warning = $null }
+        }
+        '^sk-[A-Za-z0-9]{20,}$' {
+            return @{ Kind = "user"; Warning = "This looks like a legacy OpenAI user key, which can use every project of your account. A project key (sk-proj-) limited to model access does less harm if it leaks" }
+        }
+        '^AIza' {
+            return @{ Kind = "google"; Warning = $null }
+        }
+    }
+    return @{ Kind = "unknown"; Warning = $null }
+}
+
+function Get-AICommitKeyMetadata {
+    # Label, provider and creation date of keys stored with 'aicommit config
+    # set', by setting name. Only a hash of the value is kept, to notice
+    # keys replaced outside aicommit.
+    $path = Get-AICommitDataPath "keys.json"
+    $metadata = @{}
+    if (Test-Path $path) {
+        try {
+            $json = Get-Content -Path $path -Raw -Encoding UTF8 | ConvertFrom-Json
+            foreach ($property in $json.PSObject.Properties) {
+                $metadata[$property.Name] = $property.Value
+            }
+        }
+        catch {
+            Write-Host "Warning: Could not read $path - $($_.Exception.Message)" -ForegroundColor Yellow
+        }
+    }
+    return $metadata
+}
+
+function Save-AICommitKeyMetadata {
+    # Records a stored key; -Remove forgets it
+    param(
+        [string]$Name,
+        [string]$Value,
+        [string]$Label,
+        [switch]$Remove
+    )
+
+    $metadata = Get-AICommitKeyMetadata
+    if ($Remove) {
+        $metadata.Remove($Name)
+    } else {
+        $carrier = @($script:AICommitProviders.Keys | Where-Object { $script:AICommitProviders[$_].KeyName -eq $Name }) | Select-Object -First 1
+        $metadata[$Name] = [ordered]@{
+            provider = $carrier
+            label    = $Label
+            created  = (Get-Date).ToString("o")
+            kind     = (Get-AICommitKeyScope -Value $Value).Kind
+            hash     = Get-AICommitValueHash -Value $Value
+        }
+    }
+    $metadata | ConvertTo-Json | Out-File -FilePath (Get-AICommitDataPath "keys.json") -Encoding UTF8
+}
+
+function Show-AICommitKeys {
+    # 'aicommit config list-keys': every key aicommit can see, where it comes
+    # from and what is known about it - never the key itself
+    $metadata = Get-AICommitKeyMetadata
+    $names = @($script:AICommitProviders.Values | ForEach-Object { $_.KeyName } | Where-Object { $_ })
+    $names += @($script:AICommitUserSettings.Keys) + @($script:AICommitRepoSettings.Keys) + @($metadata.Keys)
+    $names = @($names | Where-Object { Test-AICommitKeyName -Name $_ } | Select-Object -Unique)
+
+    Write-Host "`n--- API KEYS ---" -ForegroundColor Cyan
+    $shown = 0
+    foreach ($name in $names) {
+        $value = Get-AICommitSetting -Name $name
+        if ([string]::IsNullOrWhiteSpace($value)) {
+            continue
+        }
+        $shown++
+        $source = if ($script:AICommitRepoSettings.Contains($name)) {
+            ".aicommit.env"
+        } elseif (![string]::IsNullOrWhiteSpace([Environment]::GetEnvironmentVariable($name))) {
+            "environment"
+        } else {
+            "config file"
+        }
+        $scope = Get-AICommitKeyScope -Value $value
+        $carrier = @($script:AICommitProviders.Keys | Where-Object { $script:AICommitProviders[$_].KeyName -eq $name }) | Select-Object -First 1
+
+        $details = @()
+        if ($carrier) {
+            $details += $carrier
+        }
+        $details += "$($scope.Kind) key"
+        $details += "from $source"
+        $info = $metadata[$name]
+        if ($null -ne $info -and $info.hash -eq (Get-AICommitValueHash -Value $value)) {
+            if ($info.label) {
+                $details += "label '$($info.label)'"
+            }
+            $created = [DateTime]::Parse($info.created, [System.Globalization.CultureInfo]::InvariantCulture, [System.Globalization.DateTimeStyles]::RoundtripKind)
+            $details += "stored $($created.ToString('yyyy-MM-dd')) ($([int]((Get-Date) - $created).TotalDays) days ago)"
+        } elseif ($null -ne $info) {
+            $details += "changed outside aicommit, date unknown"
+        } else {
+            $details += "date unknown"
+        }
+        $color = if ($scope.Warning) { "Yellow" } else { "White" }
+        Write-Host "$name  ($($details -join ', '))" -ForegroundColor $color
+        if ($scope.Warning) {
+            Write-Host "  Warning: $($scope.Warning)" -ForegroundColor Yellow
+        }
+    }
+    if ($shown -eq 0) {
+        Write-Host "No API keys set" -ForegroundColor Yellow
+    }
+    Write-Host "--- END API KEYS ---`n" -ForegroundColor Cyan
+}
+
+function Test-AICommitApiKey {
+    # True when the provider accepts the key, checked by listing its models
+    param(
+        [string]$Carrier,
+        [string]$Key
+    )
+
+    if ($Carrier -eq "openrouter") {
+        # Its model list is public, so ask about the key itself
+        try {
+            $null = Invoke-RestMethod -Uri "$(Get-AICommitOpenRouterUrl)/key" -Method Get -Headers @{ "Authorization" = "Bearer $Key" }
+            return $true
+        }
+        catch {
+            return $false
+        }
+    }
+    $provider = @{ Carrier = $Carrier; Model = $script:AICommitProviders[$Carrier].DefaultModel; ApiKey = $Key }
+    # The catalog's own warning would only repeat what is reported here;
+    # $null means the request failed, an empty list that it went through
+    $catalog = Get-AICommitModelCatalog -Provider $provider -Refresh 6>$null
+    if ($null -eq $catalog) {
+        return $false
+    }
+    if ($catalog.Count -eq 0) {
+        Write-Host "Note: $Carrier accepted the key but lists no models for it" -ForegroundColor Yellow
+    }
+    return $true
+}
+
+function Invoke-AICommitKeyRotation {
+    # 'aicommit config rotate-key <provider>': checks the new key against the
+    # API, replaces the old one in the config file in one step and can check
+    # that the old key no longer works. With input redirected the key is
+    # read from stdin and nothing else is asked, for scripted rotation.
+    param(
+        [string]$Carrier,
+        [switch]$CheckOld
+    )
+
+    $Carrier = $Carrier.ToLower()
+    if (!$script:AICommitProviders.ContainsKey($Carrier)) {
+        Write-AICommitError -Message "Unknown provider: $Carrier" -Kind "BadProvider"
+        return
+    }
+    $name = $script:AICommitProviders[$Carrier].KeyName
+    if (!$name -and $script:AICommitProviders[$Carrier].KeyNameSetting) {
+        $name = Get-AICommitSetting -Name $script:AICommitProviders[$Carrier].KeyNameSetting
+    }
+    $workspaceKeyName = Get-AICommitWorkspaceKeyName -Carrier $Carrier
+    if ($workspaceKeyName) {
+        $name = $workspaceKeyName
+    }
+    if (!$name) {
+        Write-Host "Error: $Carrier doesn't use an API key" -ForegroundColor Red
+        return
+    }
+    $scripted = [Console]::IsInputRedirected
+
+    # The old key, decrypted if needed, for the revocation check
+    $oldValue = Get-AICommitSetting -Name $name
+    $oldKey = if (![string]::IsNullOrWhiteSpace($oldValue)) { Get-AICommitApiKey -Name $name 6>$null } else { $null }
+
+    if ($scripted) {
+        $newKey = "$([Console]::In.ReadLine())".Trim()
+    } else {
+        $secureKey = Read-Host "New key for $name" -AsSecureString
+        $bstr = [Runtime.InteropServices.Marshal]::SecureStringToBSTR($secureKey)
+        try {
+            $newKey = "$([Runtime.InteropServices.Marshal]::PtrToStringBSTR($bstr))".Trim()
+        }
+        finally {
+            [Runtime.InteropServices.Marshal]::ZeroFreeBSTR($bstr)
+        }
+    }
+    if ([string]::IsNullOrWhiteSpace($newKey)) {
+        Write-Host "No key given, nothing changed" -ForegroundColor Yellow
+        return
+    }
+    if ($newKey -eq $oldKey -or $newKey -eq $oldValue) {
+        Write-Host "Error: That is the current key, nothing changed" -ForegroundColor Red
+        return
+    }
+
+    $scope = Get-AICommitKeyScope -Value $newKey
+    if ($scope.Warning) {
+        Write-Host "Warning: $($scope.Warning)" -ForegroundColor Yellow
+    }
+    if (Test-AICommitCapability -Provider @{ Carrier = $Carrier } -Name "ModelList") {
+        Write-Host "Checking the new key with $Carrier..." -ForegroundColor Yellow
+        if (!(Test-AICommitApiKey -Carrier $Carrier -Key $newKey)) {
+            Write-Host "Error: $Carrier did not accept the new key, nothing changed" -ForegroundColor Red
+            return
+        }
+        Write-Host "The new key works" -ForegroundColor Green
+    } else {
+        Write-Host "Note: $Carrier can't be asked about keys, storing the new key unchecked" -ForegroundColor Yellow
+    }
+
+    $metadata = Get-AICommitKeyMetadata
+    $oldLabel = if ($null -ne $metadata[$name]) { "$($metadata[$name].label)" } else { "" }
+    $label = $oldLabel
+    if (!$scripted) {
+        $hint = if ($oldLabel) { " (Enter keeps '$oldLabel')" } else { " (Enter to skip)" }
+        $answer = "$(Read-Host "Label for this key$hint")".Trim()
+        if ($answer) {
+            $label = $answer
+        }
+    }
+
+    $null = Set-AICommitConfigValue -Name $name -Value $newKey
+    Write-AICommitAuditLog -Action "rotated" -Name $name
+    Import-AICommitUserSettings
+    Save-AICommitConfigState -Settings $script:AICommitUserSettings
+    Save-AICommitKeyMetadata -Name $name -Value $newKey -Label $label
+    Write-Host "$name replaced in $(Get-AICommitDataPath 'config.env')" -ForegroundColor Green
+    if ("$oldValue".StartsWith("age:")) {
+        Write-Host "Note: The old key was encrypted; the new one is stored as is - use 'aicommit encrypt-key' to encrypt it" -ForegroundColor Yellow
+    }
+
+    # Settings with higher precedence would keep using the old key
+    if ($script:AICommitRepoSettings.Contains($name)) {
+        Write-Host "Warning: $name is also set in .aicommit.env, which takes precedence - update it there too" -ForegroundColor Yellow
+    } elseif (![string]::IsNullOrWhiteSpace([Environment]::GetEnvironmentVariable($name))) {
+        Write-Host "Warning: $name is also set in the environment, which takes precedence - update your profile too" -ForegroundColor Yellow
+    }
+
+    $keysUrl = $script:AICommitProviders[$Carrier].KeysUrl
+    if (!$CheckOld -and !$scripted -and $null -ne $oldValue) {
+        $where = if ($keysUrl) { " at $keysUrl" } else { "" }
+        $CheckOld = (Read-Host "Revoke the old key$where, then check that it no longer works? (y/n)").ToLower() -in @('y', 'yes')
+    }
+    if (!$CheckOld) {
+        return
+    }
+    if ([string]::IsNullOrWhiteSpace($oldKey)) {
+        Write-Host "Note: The old key could not be read, so it can't be checked" -ForegroundColor Yellow
+        return
+    }
+    if (Test-AICommitApiKey -Carrier $Carrier -Key $oldKey) {
+        $where = if ($keysUrl) { " at $keysUrl" } else { " in the $Carrier console" }
+        Write-Host "Warning: The old key still works - revoke it$where" -ForegroundColor Yellow
+    } else {
+        Write-Host "The old key is no longer accepted" -ForegroundColor Green
+    }
+}
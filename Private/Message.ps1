@@ -0,0 +1,155 @@
+# Canonical commit message. Review, the editor, tickets and the risk line all
+# work on this structure; it is only turned into text for git at the end.
+#   Type, Scope, Breaking, Subject - header parts (type(scope)!: subject)
+#   Body                           - free text between header and footers
+#   Footers                        - trailers such as Risk: or BREAKING CHANGE:
+#   Tickets                        - referenced tickets, written as Refs: lines
+
+function New-AICommitMessage {
+    param(
+        [string]$Header,
+        [string]$Description
+    )
+
+    if ([string]::IsNullOrWhiteSpace($Description)) {
+        return ConvertFrom-AICommitMessageText -Text $Header
+    }
+    return ConvertFrom-AICommitMessageText -Text "$Header`n`n$Description"
+}
+
+function Set-AICommitMessageHeader {
+    # Conventional headers are split into their parts; anything else is
+    # kept whole as the subject
+    param(
+        [pscustomobject]$Message,
+        [string]$Header
+    )
+
+    $Header = "$Header".Trim()
+    if ($Header -match '^(?<type>[a-z]+)(?:\((?<scope>[^)]*)\))?(?<bang>!)?:\s*(?<subject>.+)$') {
+        $Message.Type = $Matches.type
+        $Message.Scope = $Matches.scope
+        $Message.Breaking = [bool]$Matches.bang -or $Message.Breaking
+        $Message.Subject = $Matches.subject.Trim()
+    } else {
+        $Message.Type = $null
+        $Message.Scope = $null
+        $Message.Subject = $Header
+    }
+}
+
+function Get-AICommitMessageHeader {
+    param([pscustomobject]$Message)
+
+    if ([string]::IsNullOrWhiteSpace($Message.Type)) {
+        return $Message.Subject
+    }
+    $scope = if ($Message.Scope) { "($($Message.Scope))" } else { "" }
+    $bang = if ($Message.Breaking) { "!" } else { "" }
+    return "$($Message.Type)$scope$($bang): $($Message.Subject)"
+}
+
+function ConvertFrom-AICommitMessageText {
+    # Parses a git commit message: first line is the header, a final
+    # paragraph made only of "Token: value" lines holds the footers
+    param([string]$Text)
+
+    $message = [pscustomobject]@{
+        Type     = $null
+        Scope    = $null
+        Breaking = $false
+        Subject  = ""
+        Body     = ""
+        Footers  = New-Object System.Collections.Generic.List[object]
+        Tickets  = New-Object System.Collections.Generic.List[string]
+    }
+
+    $lines = @("$Text".Trim() -split "`n" | ForEach-Object { $_.TrimEnd("`r") })
+    if ($lines.Count -eq 0 -or [string]::IsNullOrWhiteSpace($lines[0])) {
+        return $message
+    }
+    Set-AICommitMessageHeader -Message $message -Header $lines[0]
+
+    $rest = if ($lines.Count -gt 1) { ($lines[1..($lines.Count - 1)] -join "`n").Trim() } else { "" }
+    $paragraphs = @($rest -split "`n\s*`n" | Where-Object { ![string]::IsNullOrWhiteSpace($_) })
+
+    # Same trailer shape git interpret-trailers uses, plus BREAKING CHANGE.
+    # A body of one paragraph is only footers when every token is a known
+    # trailer; "Note: keeps the old API working" is prose.
+    $trailer = '^(?<token>BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*):\s+(?<value>.+)$'
+    if ($paragraphs.Count -gt 0) {
+        $lastLines = @($paragraphs[-1] -split "`n")
+        $isFooter = @($lastLines | Where-Object { $_ -notmatch $trailer }).Count -eq 0
+        if ($isFooter -and $paragraphs.Count -eq 1) {
+            $knownTokens = Get-AICommitTrailerTokens
+            $isFooter = @($lastLines | Where-Object { $null = $_ -match $trailer; $knownTokens -notcontains $Matches.token }).Count -eq 0
+        }
+        if ($isFooter) {
+            foreach ($line in $lastLines) {
+                $null = $line -match $trailer
+                if ($Matches.token -eq "Refs") {
+                    $message.Tickets.Add($Matches.value.Trim())
+                } else {
+                    $message.Footers.Add([pscustomobject]@{ Token = $Matches.token; Value = $Matches.value.Trim() })
+                    if ($Matches.token -in @("BREAKING CHANGE", "BREAKING-CHANGE")) {
+                        $message.Breaking = $true
+                    }
+                }
+            }
+            $paragraphs = if ($paragraphs.Count -gt 1) { $paragraphs[0..($paragraphs.Count - 2)] } else { @() }
+        }
+    }
+    $message.Body = ($paragraphs -join "`n`n").Trim()
+
+    return $message
+}
+
+function Get-AICommitTrailerTokens {
+    # Trailer tokens aicommit writes itself, plus the team's AI_COMMIT_TRAILERS
+    $tokens = @("BREAKING CHANGE", "BREAKING-CHANGE", "Refs", "Co-authored-by", "Signed-off-by", "Risk", "Verified", "Clasp-Environment")
+    foreach ($entry in "$(Get-AICommitSetting -Name "AI_COMMIT_TRAILERS")" -split ';') {
+        if ($entry.Trim() -match '^([A-Za-z][A-Za-z0-9-]*)\s*:') {
+            $tokens += $Matches[1]
+        }
+    }
+    return ,$tokens
+}
+
+function Format-AICommitMessage {
+    # The exact text handed to git commit
+    param([pscustomobject]$Message)
+
+    $parts = @(Get-AICommitMessageHeader -Message $Message)
+    if (![string]::IsNullOrWhiteSpace($Message.Body)) {
+        $parts += $Message.Body.Trim()
+    }
+
+    $footerLines = @($Message.Footers | ForEach-Object { "$($_.Token): $($_.Value)" })
+    $footerLines += @($Message.Tickets | ForEach-Object { "Refs: $_" })
+    if ($footerLines.Count -gt 0) {
+        $parts += ($footerLines -join "`n")
+    }
+    return ($parts -join "`n`n")
+}
+
+function Get-AICommitMessageDescription {
+    # Everything after the header, as shown during review
+    param([pscustomobject]$Message)
+
+    $text = Format-AICommitMessage -Message $Message
+    $newline = $text.IndexOf("`n")
+    if ($newline -lt 0) {
+        return ""
+    }
+    return $text.Substring($newline).Trim()
+}
+
+function Add-AICommitMessageFooter {
+    param(
+        [pscustomobject]$Message,
+        [string]$Token,
+        [string]$Value
+    )
+
+    $Message.Footers.Add([pscustomobject]@{ Token = $Token; Value = $Value.Trim() })
+}
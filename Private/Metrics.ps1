@@ -0,0 +1,136 @@
+# Opt-in run metrics for a team's own collector, so platform teams can see
+# adoption and cost. Nothing is sent unless AI_COMMIT_METRICS_COMMAND or
+# AI_COMMIT_OTLP_ENDPOINT is set. Records are anonymous: no repository,
+# paths, messages or user names, only what happened and what it cost.
+$script:AICommitRunMetrics = @{}
+
+function Start-AICommitMetrics {
+    param([string]$Command)
+
+    $script:AICommitRunMetrics = @{
+        command = if ($Command) { $Command.ToLower() } else { "commit" }
+        started = Get-Date
+        fast    = [bool]$script:AICommitFastMode
+    }
+}
+
+function Set-AICommitMetric {
+    # review: accepted, edited, regenerated, picked, auto_accepted, manual,
+    # cancelled
+    # outcome: committed, failed, no_changes
+    param(
+        [string]$Name,
+        $Value
+    )
+
+    $script:AICommitRunMetrics[$Name] = $Value
+}
+
+function Get-AICommitMetricsRecord {
+    $metrics = $script:AICommitRunMetrics
+    $usage = @($script:AICommitRunUsage)
+    $priced = @($usage | Where-Object { $null -ne $_.Cost })
+    $last = $usage | Select-Object -Last 1
+
+    $outcome = $metrics.outcome
+    if (!$outcome) {
+        $outcome = if ($metrics.review -eq "cancelled") { "cancelled" } else { "stopped" }
+    }
+    $module = Get-Module AICommit | Select-Object -First 1
+    return [ordered]@{
+        schema        = 1
+        time          = (Get-Date).ToUniversalTime().ToString("o")
+        version       = if ($module) { "$($module.Version)" } else { $null }
+        command       = $metrics.command
+        duration_ms   = [int]((Get-Date) - $metrics.started).TotalMilliseconds
+        provider      = if ($last) { $last.Carrier } else { $null }
+        model         = if ($last) { $last.Model } else { $null }
+        fast          = $metrics.fast
+        review        = $metrics.review
+        outcome       = $outcome
+        calls         = $usage.Count
+        input_tokens  = [long]($usage | Measure-Object -Property Input -Sum).Sum
+        output_tokens = [long]($usage | Measure-Object -Property Output -Sum).Sum
+        cost_usd      = if ($priced.Count -gt 0) { [Math]::Round([double]($priced | Measure-Object -Property Cost -Sum).Sum, 6) } else { $null }
+    }
+}
+
+function ConvertTo-AICommitOtlpMetrics {
+    # The record as an OTLP/HTTP JSON metrics export: a run counter, the
+    # duration, token and cost sums, all with the record's labels
+    param([System.Collections.IDictionary]$Record)
+
+    $now = "$([long](([DateTimeOffset]::UtcNow).ToUnixTimeMilliseconds()) * 1000000)"
+    $attributes = @()
+    foreach ($name in @("command", "provider", "model", "review", "outcome", "version")) {
+        if ($Record[$name]) {
+            $attributes += @{ key = "aicommit.$name"; value = @{ stringValue = "$($Record[$name])" } }
+        }
+    }
+    $attributes += @{ key = "aicommit.fast"; value = @{ boolValue = [bool]$Record.fast } }
+
+    $point = { param($value, $extra) @{ timeUnixNano = $now; attributes = @($attributes + $extra) } + $value }
+    $metrics = @(
+        @{ name = "aicommit.runs"; unit = "1"; sum = @{ aggregationTemporality = 1; isMonotonic = $true; dataPoints = @(& $point @{ asInt = "1" } @()) } }
+        @{ name = "aicommit.duration"; unit = "ms"; gauge = @{ dataPoints = @(& $point @{ asInt = "$($Record.duration_ms)" } @()) } }
+        @{ name = "aicommit.tokens"; unit = "1"; sum = @{ aggregationTemporality = 1; isMonotonic = $true; dataPoints = @(
+            (& $point @{ asInt = "$($Record.input_tokens)" } @(@{ key = "aicommit.direction"; value = @{ stringValue = "input" } }))
+            (& $point @{ asInt = "$($Record.output_tokens)" } @(@{ key = "aicommit.direction"; value = @{ stringValue = "output" } }))
+        ) } }
+    )
+    if ($null -ne $Record.cost_usd) {
+        $metrics += @{ name = "aicommit.cost"; unit = "USD"; sum = @{ aggregationTemporality = 1; isMonotonic = $true; dataPoints = @(& $point @{ asDouble = [double]$Record.cost_usd } @()) } }
+    }
+
+    return @{
+        resourceMetrics = @(@{
+            resource     = @{ attributes = @(@{ key = "service.name"; value = @{ stringValue = "aicommit" } }) }
+            scopeMetrics = @(@{ scope = @{ name = "aicommit" }; metrics = $metrics })
+        })
+    } | ConvertTo-Json -Depth 12 -Compress
+}
+
+function Send-AICommitMetrics {
+    # Called once at the end of a run. Failures only warn; metrics never
+    # get in the way of a commit.
+    $command = Get-AICommitSetting -Name "AI_COMMIT_METRICS_COMMAND"
+    $endpoint = Get-AICommitSetting -Name "AI_COMMIT_OTLP_ENDPOINT"
+    if (([string]::IsNullOrWhiteSpace($command) -and [string]::IsNullOrWhiteSpace($endpoint)) -or $null -eq $script:AICommitRunMetrics.started) {
+        return
+    }
+    $record = Get-AICommitMetricsRecord
+
+    # A command set by the repository's own settings has to be allowed first
+    if (![string]::IsNullOrWhiteSpace($command) -and (Test-AICommitCommandAllowed -Name "AI_COMMIT_METRICS_COMMAND" -Command $command)) {
+        # The record is piped in as one line of JSON, available as $input
+        try {
+            ($record | ConvertTo-Json -Compress) | & ([scriptblock]::Create($command)) | Out-Null
+        }
+        catch {
+            Write-Host "Warning: AI_COMMIT_METRICS_COMMAND failed - $($_.Exception.Message)" -ForegroundColor Yellow
+        }
+    }
+
+    if (![string]::IsNullOrWhiteSpace($endpoint)) {
+        $url = $endpoint.TrimEnd('/')
+        if ($url -notmatch '/v1/metrics$') {
+            $url = "$url/v1/metrics"
+        }
+        # Same "key=value,key=value" format as OTEL_EXPORTER_OTLP_HEADERS
+        $headers = @{}
+        foreach ($pair in "$(Get-AICommitSetting -Name "AI_COMMIT_OTLP_HEADERS")" -split ',') {
+            $key, $value = $pair -split '=', 2
+            if ($key.Trim() -and $value) {
+                $headers[$key.Trim()] = [uri]::UnescapeDataString($value.Trim())
+            }
+        }
+        try {
+            $body = ConvertTo-AICommitOtlpMetrics -Record $record
+            $null = Invoke-RestMethod -Uri $url -Method Post -Headers $headers -Body ([System.Text.Encoding]::UTF8.GetBytes($body)) -ContentType "application/json" -TimeoutSec 5
+        }
+        catch {
+            Write-Host "Warning: Could not send metrics to $url - $($_.Exception.Message)" -ForegroundColor Yellow
+        }
+    }
+    $script:AICommitRunMetrics = @{}
+}
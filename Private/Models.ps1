@@ -0,0 +1,305 @@
+function Get-AICommitModelCatalog {
+    # The provider's models as @{ id; name; context_window; output_limit }
+    # plus input_price/output_price (USD per million tokens) where the
+    # provider publishes them. -CacheOnly never goes to the network and
+    # returns whatever an earlier fetch left, however old.
+    param(
+        [hashtable]$Provider,
+        [switch]$Refresh,
+        [switch]$CacheOnly
+    )
+
+    if (!(Test-AICommitCapability -Provider $Provider -Name "ModelList")) {
+        return $null
+    }
+
+    $cacheFile = Get-AICommitDataPath "models-$($Provider.Carrier).json"
+    # Default: refresh the model list once a day
+    $ttlHours = [double](Get-AICommitSetting -Name "AI_COMMIT_MODEL_CACHE_TTL_HOURS" -Default 24)
+
+    # Serve from cache while it is fresh
+    if (!$Refresh -and (Test-Path $cacheFile)) {
+        try {
+            $cache = Get-Content $cacheFile -Raw -Encoding UTF8 | ConvertFrom-Json
+            # PowerShell 7 already turns ISO strings into dates, 5.1 doesn't
+            $age = (Get-Date) - [datetime]$cache.fetchedAt
+            if ($age.TotalHours -lt $ttlHours) {
+                return ,@($cache.models)
+            }
+            if ($CacheOnly) {
+                return ,@($cache.models)
+            }
+        }
+        catch {
+            # Corrupt cache, fall through and fetch again
+        }
+    }
+    if ($CacheOnly) {
+        return $null
+    }
+
+    try {
+        $listModels = $script:AICommitProviders[$Provider.Carrier].ListModels
+        if ($null -ne $listModels) {
+            # Registered provider
+            $models = @(& $listModels $Provider)
+        } elseif ($Provider.Carrier -eq "anthropic") {
+            $response = Invoke-RestMethod -Uri "https://api.anthropic.com/v1/models?limit=1000" -Method Get -Headers @{
+                "x-api-key"         = $Provider.ApiKey
+                "anthropic-version" = "2023-06-01"
+            }
+            $models = @($response.data | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.display_name
+                    context_window = $_.max_input_tokens
+                    output_limit   = $_.max_tokens
+                }
+            })
+        } elseif ($Provider.Carrier -eq "openai") {
+            $headers = @{ "Authorization" = "Bearer $($Provider.ApiKey)" }
+            foreach ($name in $Provider.Headers.Keys) {
+                $headers[$name] = $Provider.Headers[$name]
+            }
+            $response = Invoke-RestMethod -Uri "https://api.openai.com/v1/models" -Method Get -Headers $headers
+            # The list also has embedding, audio and image models
+            $patterns = $script:AICommitProviders.openai.ModelPatterns
+            $models = @($response.data | Where-Object { $id = $_.id; @($patterns | Where-Object { $id -like $_ }).Count -gt 0 } | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.id
+                    context_window = $null
+                    output_limit   = $null
+                }
+            })
+        } elseif ($Provider.Carrier -eq "google") {
+            $response = Invoke-RestMethod -Uri "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1000" -Method Get -Headers @{
+                "x-goog-api-key" = $Provider.ApiKey
+            }
+            # Only models that can actually generate a commit message
+            $models = @($response.models | Where-Object { $_.supportedGenerationMethods -contains "generateContent" } | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.name -replace "^models/", ""
+                    name           = $_.displayName
+                    context_window = $_.inputTokenLimit
+                    output_limit   = $_.outputTokenLimit
+                }
+            })
+        } elseif ($Provider.Carrier -eq "mistral") {
+            $response = Invoke-RestMethod -Uri "https://api.mistral.ai/v1/models" -Method Get -Headers @{
+                "Authorization" = "Bearer $($Provider.ApiKey)"
+            }
+            # Embedding and moderation models can't write commit messages
+            $models = @($response.data | Where-Object { $_.capabilities.completion_chat -ne $false } | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.id
+                    context_window = $_.max_context_length
+                    output_limit   = $null
+                }
+            })
+        } elseif ($Provider.Carrier -eq "groq") {
+            $response = Invoke-RestMethod -Uri "https://api.groq.com/openai/v1/models" -Method Get -Headers @{
+                "Authorization" = "Bearer $($Provider.ApiKey)"
+            }
+            # Speech and moderation models share the list
+            $models = @($response.data | Where-Object { $_.active -ne $false -and $_.id -notmatch 'whisper|tts|guard' } | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.id
+                    context_window = $_.context_window
+                    output_limit   = $_.max_completion_tokens
+                }
+            })
+        } elseif ($Provider.Carrier -eq "custom") {
+            $baseUrl = Get-AICommitCustomUrl
+            if ($null -eq $baseUrl) {
+                return $null
+            }
+            $headers = @{}
+            if ($Provider.ApiKey) {
+                $headers["Authorization"] = "Bearer $($Provider.ApiKey)"
+            }
+            $response = Invoke-RestMethod -Uri "$baseUrl/models" -Method Get -Headers $headers
+            $models = @($response.data | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.id
+                    context_window = $null
+                    output_limit   = $null
+                }
+            })
+        } elseif ($Provider.Carrier -eq "openrouter") {
+            $response = Invoke-RestMethod -Uri "$(Get-AICommitOpenRouterUrl)/models" -Method Get -Headers @{
+                "Authorization" = "Bearer $($Provider.ApiKey)"
+            }
+            # Prices come per token; routers with a variable price say -1
+            $models = @($response.data | ForEach-Object {
+                $inputPrice = if ($_.pricing -and [double]$_.pricing.prompt -ge 0) { [double]$_.pricing.prompt * 1000000 } else { $null }
+                $outputPrice = if ($_.pricing -and [double]$_.pricing.completion -ge 0) { [double]$_.pricing.completion * 1000000 } else { $null }
+                [pscustomobject]@{
+                    id             = $_.id
+                    name           = $_.name
+                    context_window = $_.context_length
+                    output_limit   = $_.top_provider.max_completion_tokens
+                    input_price    = $inputPrice
+                    output_price   = $outputPrice
+                }
+            })
+        } elseif ($Provider.Carrier -eq "ollama") {
+            # Models pulled into the local Ollama server
+            $response = Invoke-RestMethod -Uri "$(Get-AICommitOllamaUrl)/api/tags" -Method Get
+            $models = @($response.models | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.name
+                    name           = $_.name
+                    context_window = $null
+                    output_limit   = $null
+                }
+            })
+        } elseif ($Provider.Carrier -eq "bedrock") {
+            if (!(Get-Command aws -ErrorAction SilentlyContinue)) {
+                return $null
+            }
+            $awsArgs = Get-AICommitAwsArguments
+            $output = aws bedrock list-foundation-models --by-output-modality TEXT --output json @awsArgs 2>&1
+            if ($LASTEXITCODE -ne 0) {
+                throw ($output | Out-String).Trim()
+            }
+            # Bedrock also hosts other vendors; aicommit supports Claude and Titan
+            $patterns = $script:AICommitProviders.bedrock.ModelPatterns
+            $models = @((($output | Out-String) | ConvertFrom-Json).modelSummaries | Where-Object { $id = $_.modelId; @($patterns | Where-Object { $id -like $_ }).Count -gt 0 } | ForEach-Object {
+                [pscustomobject]@{
+                    id             = $_.modelId
+                    name           = $_.modelName
+                    context_window = $null
+                    output_limit   = $null
+                }
+            })
+        } else {
+            # Declares ModelList but has no listing here yet
+            return $null
+        }
+    }
+    catch {
+        Write-Host "Warning: Could not fetch $($Provider.Carrier) model list - $($_.Exception.Message)" -ForegroundColor Yellow
+        return $null
+    }
+
+    $cache = @{
+        fetchedAt = (Get-Date).ToString("o")
+        models    = $models
+    }
+    $cache | ConvertTo-Json -Depth 5 | Out-File -FilePath $cacheFile -Encoding UTF8
+
+    # An empty list stays a list, so it isn't mistaken for a failed fetch
+    return ,$models
+}
+
+function Get-AICommitModelInfo {
+    param(
+        [hashtable]$Provider,
+        [switch]$CacheOnly
+    )
+
+    $catalog = Get-AICommitModelCatalog -Provider $Provider -CacheOnly:$CacheOnly
+    if ($null -eq $catalog) {
+        return $null
+    }
+
+    $modelId = $Provider.Model -replace "^models/", ""
+    # Ollama lists untagged models as name:latest
+    return $catalog | Where-Object { $_.id -eq $modelId -or $_.id -eq "$($modelId):latest" } | Select-Object -First 1
+}
+
+function Test-AICommitModel {
+    param([hashtable]$Provider)
+
+    # Providers that route model names themselves are not second-guessed
+    if (!(Test-AICommitCapability -Provider $Provider -Name "ModelCheck")) {
+        return
+    }
+
+    $catalog = Get-AICommitModelCatalog -Provider $Provider
+    if ($null -eq $catalog) {
+        # No model list available (offline, API change) - don't block the commit
+        return
+    }
+
+    if ($null -eq (Get-AICommitModelInfo -Provider $Provider)) {
+        Write-Host "Warning: Model '$($Provider.Model)' is not in the $($Provider.Carrier) model list for your key" -ForegroundColor Yellow
+        Write-Host "Run 'aicommit models' to see the available models" -ForegroundColor Yellow
+    }
+}
+
+function Show-AICommitModels {
+    # Lists the models the key can use; -Select then asks for a new default
+    # and saves it to the config file
+    param(
+        [switch]$Refresh,
+        [switch]$Select
+    )
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    if (!(Test-AICommitCapability -Provider $provider -Name "ModelList")) {
+        Write-Host "The $($provider.Carrier) provider can't list its models; see its documentation for model names" -ForegroundColor Yellow
+        return
+    }
+
+    $catalog = Get-AICommitModelCatalog -Provider $provider -Refresh:$Refresh
+    if ($null -eq $catalog) {
+        return
+    }
+    if ($catalog.Count -eq 0) {
+        Write-Host "$($provider.Carrier) lists no models for your key" -ForegroundColor Yellow
+        return
+    }
+
+    $currentId = $provider.Model -replace "^models/", ""
+    $sorted = @($catalog | Sort-Object id)
+    Write-Host "`n--- $($provider.Carrier.ToUpper()) MODELS ---" -ForegroundColor Cyan
+    for ($i = 0; $i -lt $sorted.Count; $i++) {
+        $model = $sorted[$i]
+        $marker = if ($model.id -eq $currentId) { "*" } else { " " }
+        $context = if ($model.context_window) { " ($($model.context_window) tokens)" } else { "" }
+        if ($null -ne $model.input_price -and $null -ne $model.output_price) {
+            $context += " `${0:0.###}/`${1:0.###} per 1M tokens" -f [double]$model.input_price, [double]$model.output_price
+        }
+        $color = if ($model.id -eq $currentId) { "Green" } else { "White" }
+        $number = if ($Select) { "{0,3}. " -f ($i + 1) } else { "" }
+        Write-Host "$marker $number$($model.id)$context" -ForegroundColor $color
+    }
+    Write-Host "--- END MODELS ---`n" -ForegroundColor Cyan
+
+    if (!$Select) {
+        return
+    }
+    $answer = Read-Host "Number or name of the new default model (Enter to keep $currentId)"
+    if ([string]::IsNullOrWhiteSpace($answer)) {
+        return
+    }
+    $answer = $answer.Trim()
+    $chosen = if ($answer -match '^\d+$' -and [int]$answer -ge 1 -and [int]$answer -le $sorted.Count) {
+        $sorted[[int]$answer - 1].id
+    } else {
+        ($sorted | Where-Object { $_.id -eq $answer } | Select-Object -First 1).id
+    }
+    if ([string]::IsNullOrWhiteSpace($chosen)) {
+        Write-Host "Error: '$answer' is not in the list" -ForegroundColor Red
+        return
+    }
+
+    Save-AICommitConfigSetting -Name "AI_COMMIT_PROVIDER" -Value $provider.Carrier
+    Save-AICommitConfigSetting -Name "AI_COMMIT_MODEL" -Value $chosen
+    Write-Host "Default model set to $chosen ($($provider.Carrier)) in $(Get-AICommitDataPath 'config.env')" -ForegroundColor Green
+    # The config file has the lowest precedence
+    foreach ($name in @("AI_COMMIT_PROVIDER", "AI_COMMIT_MODEL")) {
+        if ([Environment]::GetEnvironmentVariable($name) -or $script:AICommitRepoSettings.Contains($name) -or $script:AICommitTeamSettings.Contains($name)) {
+            Write-Host "Warning: $name is also set in your environment, .aicommit.env or .aicommit.yaml, which takes precedence" -ForegroundColor Yellow
+        }
+    }
+}
@@ -0,0 +1,145 @@
+function Read-AICommitNote {
+    # Shows a note and lets the user accept, edit or cancel it; returns the
+    # final text or $null
+    param([string]$Note)
+
+    $current = $Note
+    while ($true) {
+        Write-Host "`n--- SUGGESTED NOTE ---" -ForegroundColor Cyan
+        Write-Host $current -ForegroundColor White
+        Write-Host "--- END NOTE ---`n" -ForegroundColor Cyan
+
+        do {
+            $choice = (Read-Host "Attach this note? (y)es / (e)dit / (c)ancel").ToLower()
+        } while ($choice -notin @('y', 'yes', 'e', 'edit', 'c', 'cancel', ''))
+
+        switch ($choice) {
+            {$_ -in @('c', 'cancel')} {
+                Write-Host "Note cancelled" -ForegroundColor Yellow
+                return $null
+            }
+            {$_ -in @('e', 'edit')} {
+                $tempFile = New-AICommitTempFile -Prefix "aicommit-note"
+                $editContent = @"
+$current
+
+# Edit the note above. Lines starting with '#' are ignored.
+# Save and close the editor to continue.
+"@
+                Set-Content -Path $tempFile -Value $editContent -Encoding UTF8
+                Invoke-AICommitEditor -Path $tempFile
+                $edited = (@(Get-Content -Path $tempFile -Encoding UTF8) | Where-Object { !$_.StartsWith("#") }) -join "`n"
+                Remove-Item $tempFile -Force -ErrorAction SilentlyContinue
+                if (![string]::IsNullOrWhiteSpace($edited)) {
+                    $current = $edited.Trim()
+                }
+            }
+            default {
+                return $current
+            }
+        }
+    }
+}
+
+function Invoke-AICommitNote {
+    # Writes (or refines) a git notes annotation for a commit: rationale and
+    # detail that would be too long for the commit message itself
+    param(
+        [string]$Ref,
+        [switch]$Push
+    )
+
+    if ([string]::IsNullOrWhiteSpace($Ref)) {
+        $Ref = "HEAD"
+    }
+    $fullHash = git rev-parse --verify --quiet "$Ref^{commit}"
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($fullHash)) {
+        Write-Host "Error: '$Ref' is not a valid commit" -ForegroundColor Red
+        return
+    }
+    $fullHash = "$fullHash".Trim()
+    $shortHash = "$(git rev-parse --short $fullHash)".Trim()
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    $message = (git log -1 --format=%B $fullHash) -join "`n"
+    $existingNote = (git notes show $fullHash 2>$null) -join "`n"
+    $hasNote = ($LASTEXITCODE -eq 0 -and ![string]::IsNullOrWhiteSpace($existingNote))
+
+    # The commit's diff gives the details the message left out
+    $diff = Get-AICommitPromptDiff -Diff ((git show -M -C --format= --patch $fullHash -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")
+    $maxLength = [int](Get-AICommitSetting -Name "AI_COMMIT_MAX_DIFF_LENGTH" -Default 30000)
+    if ($diff.Length -gt $maxLength) {
+        $diff = $diff.Substring(0, $maxLength) + "`n... (diff truncated)"
+        Write-Host "Note: Diff was truncated due to length" -ForegroundColor Yellow
+    }
+
+    $focus = Read-Host "Anything the note should cover? (optional)"
+
+    if ($hasNote) {
+        Write-Host "Refining the existing note on $shortHash..." -ForegroundColor Yellow
+        $task = "Improve this existing git note for the commit below. Keep everything that is still accurate, correct what the diff contradicts and add what is missing.`n`nExisting note:`n$existingNote"
+    } else {
+        Write-Host "Writing a note for $shortHash..." -ForegroundColor Yellow
+        $task = "Write a git note for the commit below. The note complements the commit message: explain the rationale, alternatives that were considered, side effects and anything a future reader of this commit should know. Do not repeat the commit message."
+    }
+    if (![string]::IsNullOrWhiteSpace($focus)) {
+        $task += "`n`nThe developer wants the note to cover: $focus"
+    }
+
+    $prompt = @"
+$task
+
+Respond with the note text only: plain text, no markdown headings, lines wrapped at 72 characters, no introduction or closing remarks.
+
+Commit message:
+$message
+
+Diff:
+$diff
+"@
+
+    $note = Invoke-AICommitCompletion -Provider $provider -Prompt $prompt
+    if ([string]::IsNullOrWhiteSpace($note)) {
+        Write-Host "Error: The model did not return a note" -ForegroundColor Red
+        return
+    }
+
+    $note = Read-AICommitNote -Note $note.Trim()
+    if ($null -eq $note) {
+        return
+    }
+
+    # -f replaces the note being refined
+    $noteFile = New-AICommitTempFile -Prefix "note"
+    Set-Content -Path $noteFile -Value $note -Encoding UTF8 -NoNewline
+    git notes add -f -F $noteFile $fullHash | Out-Host
+    $exitCode = $LASTEXITCODE
+    Remove-Item $noteFile -Force -ErrorAction SilentlyContinue
+    if ($exitCode -ne 0) {
+        Write-Host "Git notes failed with exit code: $exitCode" -ForegroundColor Red
+        return
+    }
+    Write-Host "Note attached to $shortHash (show it with 'git log --notes' or 'git notes show $shortHash')" -ForegroundColor Green
+
+    if ($Push) {
+        # Notes live in their own ref, which plain 'git push' leaves out
+        $remote = @(git remote 2>$null | Where-Object { $_ }) | Select-Object -First 1
+        if ($null -eq $remote) {
+            Write-Host "Skipping notes push: no remote configured" -ForegroundColor Yellow
+            return
+        }
+        $notesRef = "$(git notes get-ref)".Trim()
+        Write-Host "Pushing $notesRef to $remote..." -ForegroundColor Yellow
+        git push $remote $notesRef | Out-Host
+        if ($LASTEXITCODE -eq 0) {
+            Write-Host "Notes push successful!" -ForegroundColor Green
+        } else {
+            Write-Host "Notes push failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+            Write-Host "If the remote has notes you don't, fetch them first: git fetch $remote $($notesRef):$notesRef" -ForegroundColor Yellow
+        }
+    }
+}
@@ -0,0 +1,112 @@
+# Output formats shared by the commands that print generated content
+# (squash-message, pr-fill, digest, -split -plan). A command builds one
+# content object with New-AICommitOutput and hands it to
+# Format-AICommitOutput; a new format only needs an entry in this table.
+#   text     - plain commit-style text: title, body, sections, trailers
+#   markdown - '# ' title and '## ' sections, trailers as a bold list
+#   json     - all fields, for scripts
+#   trailer  - only the git trailers ("Token: value" lines)
+$script:AICommitOutputFormatters = [ordered]@{
+    text     = {
+        param($Content)
+        $parts = @()
+        if ($Content.Title) { $parts += $Content.Title }
+        if ($Content.Body) { $parts += $Content.Body }
+        foreach ($name in $Content.Sections.Keys) {
+            $parts += "$($name):`n$($Content.Sections[$name])"
+        }
+        if ($Content.Trailers.Count -gt 0) {
+            $parts += (@($Content.Trailers | ForEach-Object { "$($_.Token): $($_.Value)" }) -join "`n")
+        }
+        $parts -join "`n`n"
+    }
+    markdown = {
+        param($Content)
+        $parts = @()
+        if ($Content.Title) { $parts += "# $($Content.Title)" }
+        if ($Content.Body) { $parts += $Content.Body }
+        foreach ($name in $Content.Sections.Keys) {
+            $parts += "## $name`n`n$($Content.Sections[$name])"
+        }
+        if ($Content.Trailers.Count -gt 0) {
+            $parts += (@($Content.Trailers | ForEach-Object { "- **$($_.Token):** $($_.Value)" }) -join "`n")
+        }
+        $parts -join "`n`n"
+    }
+    json     = {
+        param($Content)
+        # body is everything after the title, as git sees a message
+        $bodyParts = @($Content.Body) + @(if ($Content.Trailers.Count -gt 0) { @($Content.Trailers | ForEach-Object { "$($_.Token): $($_.Value)" }) -join "`n" })
+        $object = [ordered]@{
+            title    = $Content.Title
+            body     = (@($bodyParts | Where-Object { $_ }) -join "`n`n")
+            sections = $Content.Sections
+            trailers = @($Content.Trailers | ForEach-Object { [ordered]@{ token = $_.Token; value = $_.Value } })
+        }
+        foreach ($name in $Content.Data.Keys) {
+            $object[$name] = $Content.Data[$name]
+        }
+        [pscustomobject]$object | ConvertTo-Json -Depth 8
+    }
+    trailer  = {
+        param($Content)
+        @($Content.Trailers | ForEach-Object { "$($_.Token): $($_.Value)" }) -join "`n"
+    }
+}
+
+function New-AICommitOutput {
+    # The content of one command's output, independent of its format.
+    # Sections are named blocks of text; Trailers are @{ Token; Value };
+    # Data holds extra fields that only the json format shows.
+    param(
+        [string]$Title,
+        [string]$Body,
+        [System.Collections.IDictionary]$Sections = [ordered]@{},
+        [object[]]$Trailers = @(),
+        [hashtable]$Data = @{}
+    )
+
+    return [pscustomobject]@{
+        Title    = $Title
+        Body     = "$Body".Trim()
+        Sections = $Sections
+        Trailers = @($Trailers | Where-Object { $null -ne $_ })
+        Data     = $Data
+    }
+}
+
+function ConvertTo-AICommitOutput {
+    # A commit message structure as output content: header as the title,
+    # footers and Refs: lines as trailers
+    param(
+        [pscustomobject]$Message,
+        [hashtable]$Data = @{}
+    )
+
+    $trailers = @($Message.Footers | ForEach-Object { @{ Token = $_.Token; Value = $_.Value } })
+    $trailers += @($Message.Tickets | ForEach-Object { @{ Token = "Refs"; Value = $_ } })
+    return New-AICommitOutput -Title (Get-AICommitMessageHeader -Message $Message) -Body $Message.Body -Trailers $trailers -Data $Data
+}
+
+function Test-AICommitOutputFormat {
+    # $true when -Format is one of -Allowed; otherwise prints the choices
+    param(
+        [string]$Format,
+        [string[]]$Allowed
+    )
+
+    if ($Format -in $Allowed) {
+        return $true
+    }
+    Write-Host "Error: Unknown output '$Format' - use $(($Allowed | Select-Object -SkipLast 1) -join ', ') or $($Allowed[-1])" -ForegroundColor Red
+    return $false
+}
+
+function Format-AICommitOutput {
+    param(
+        [pscustomobject]$Content,
+        [string]$Format = "text"
+    )
+
+    return (& $script:AICommitOutputFormatters[$Format.ToLower()] $Content)
+}
@@ -0,0 +1,87 @@
+# Commit style presets (AI_COMMIT_STYLE_PRESET). Each one tells the model
+# how to write the header and checks the header it gets back, so a message
+# that breaks the convention is asked for again instead of committed.
+#   Instructions - requirement line added to the prompt
+#   Pattern      - regular expression the header must match
+#   Rule         - what the pattern means, for warnings and the re-ask
+$script:AICommitStylePresets = [ordered]@{
+    angular = @{
+        Instructions = "Write the header as 'type(scope): subject' (Angular convention). The type is one of build, ci, docs, feat, fix, perf, refactor, style, test; the scope is optional; the subject starts in lower case. This replaces the header style of the example"
+        Pattern      = '^(build|ci|docs|feat|fix|perf|refactor|style|test)(\([\w\-./ ]+\))?!?: [a-z]'
+        Rule         = "type(scope): subject, with type build, ci, docs, feat, fix, perf, refactor, style or test and a lower-case subject"
+    }
+    karma = @{
+        Instructions = "Write the header as 'type(scope): subject' (Karma convention). The type is one of feat, fix, docs, style, refactor, perf, test, chore; the scope is optional. This replaces the header style of the example"
+        Pattern      = '^(feat|fix|docs|style|refactor|perf|test|chore)(\([^)]+\))?: \S'
+        Rule         = "type(scope): subject, with type feat, fix, docs, style, refactor, perf, test or chore"
+    }
+    plain = @{
+        Instructions = "Write the header as a plain sentence without a type or scope prefix, starting with a capitalized verb"
+        Pattern      = '^(?![a-z]+(\([^)]*\))?!?:)[A-Z]'
+        Rule         = "a plain sentence starting with a capital letter and no type prefix"
+    }
+}
+
+# Unknown preset names are reported once per session
+$script:AICommitPresetWarned = @{}
+
+function Get-AICommitStylePreset {
+    # The configured preset, or $null when none is set. "custom" takes its
+    # pattern from AI_COMMIT_STYLE_PATTERN and its prompt line from
+    # AI_COMMIT_STYLE_INSTRUCTIONS.
+    $name = "$(Get-AICommitSetting -Name "AI_COMMIT_STYLE_PRESET")".Trim().ToLower()
+    if (!$name -or $name -in @('none', 'off')) {
+        return $null
+    }
+    if ($name -eq "custom") {
+        $pattern = "$(Get-AICommitSetting -Name "AI_COMMIT_STYLE_PATTERN")".Trim()
+        if (!$pattern) {
+            if (!$script:AICommitPresetWarned[$name]) {
+                Write-Host "Warning: AI_COMMIT_STYLE_PRESET=custom needs AI_COMMIT_STYLE_PATTERN, the header regular expression" -ForegroundColor Yellow
+                $script:AICommitPresetWarned[$name] = $true
+            }
+            return $null
+        }
+        $instructions = Get-AICommitSetting -Name "AI_COMMIT_STYLE_INSTRUCTIONS" -Default "The header must match the regular expression $pattern"
+        return @{ Name = $name; Instructions = $instructions; Pattern = $pattern; Rule = "a header matching $pattern" }
+    }
+    if (!$script:AICommitStylePresets.Contains($name)) {
+        if (!$script:AICommitPresetWarned[$name]) {
+            Write-Host "Warning: Unknown AI_COMMIT_STYLE_PRESET '$name' - use $(($script:AICommitStylePresets.Keys + @('custom')) -join ', ')" -ForegroundColor Yellow
+            $script:AICommitPresetWarned[$name] = $true
+        }
+        return $null
+    }
+    $preset = $script:AICommitStylePresets[$name]
+    return @{ Name = $name; Instructions = $preset.Instructions; Pattern = $preset.Pattern; Rule = $preset.Rule }
+}
+
+function Get-AICommitPresetRules {
+    # The preset's requirement line for a prompt; empty without a preset
+    $preset = Get-AICommitStylePreset
+    if ($null -eq $preset) {
+        return ""
+    }
+    return "`n- $($preset.Instructions)"
+}
+
+function Test-AICommitStylePreset {
+    # Returns the preset problems of a header; empty when it follows the
+    # preset or none is set
+    param([string]$Header)
+
+    $preset = Get-AICommitStylePreset
+    if ($null -eq $preset -or [string]::IsNullOrWhiteSpace($Header)) {
+        return ,@()
+    }
+    try {
+        if ($Header -cmatch $preset.Pattern) {
+            return ,@()
+        }
+    }
+    catch {
+        Write-Host "Warning: AI_COMMIT_STYLE_PATTERN is not a valid regular expression - $($_.Exception.Message)" -ForegroundColor Yellow
+        return ,@()
+    }
+    return ,@("Header doesn't follow the $($preset.Name) style ($($preset.Rule))")
+}
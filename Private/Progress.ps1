@@ -0,0 +1,22 @@
+function Write-AICommitProgress {
+    # With -progress json, one JSON object per line on stderr so GUI
+    # wrappers and editor plugins can show their own progress. Events:
+    # verifying, collecting_diff, calling_provider, tokens_streamed,
+    # awaiting_user, committed. Nothing is written otherwise.
+    param(
+        [string]$Name,
+        [hashtable]$Data = @{}
+    )
+
+    if ($script:AICommitProgressFormat -ne "json") {
+        return
+    }
+    $record = [ordered]@{
+        event = $Name
+        time  = (Get-Date).ToUniversalTime().ToString("o")
+    }
+    foreach ($key in $Data.Keys) {
+        $record[$key] = $Data[$key]
+    }
+    [Console]::Error.WriteLine(($record | ConvertTo-Json -Compress -Depth 4))
+}
@@ -0,0 +1,371 @@
+# Description tone (AI_COMMIT_TONE), as told to the model
+$script:AICommitTones = @{
+    neutral = "Write the description in a neutral, factual tone"
+    casual  = "Write the description in a relaxed, conversational tone, as if explaining the change to a teammate"
+    formal  = "Write the description in a formal, precise tone without contractions"
+}
+
+function Get-AICommitDescriptionRules {
+    # Team preferences for the description (AI_COMMIT_BODY_MAX_WORDS,
+    # AI_COMMIT_TONE) as extra requirement lines for a prompt
+    $rules = ""
+    $maxWords = [int](Get-AICommitSetting -Name "AI_COMMIT_BODY_MAX_WORDS" -Default 0)
+    if ($maxWords -gt 0) {
+        $rules += "`n- Keep the description to at most $maxWords words"
+    }
+    $tone = "$(Get-AICommitSetting -Name "AI_COMMIT_TONE")".Trim().ToLower()
+    if ($tone) {
+        if ($script:AICommitTones.ContainsKey($tone)) {
+            $rules += "`n- $($script:AICommitTones[$tone])"
+        } else {
+            Write-Host "Warning: Unknown AI_COMMIT_TONE '$tone' - use $(($script:AICommitTones.Keys | Sort-Object) -join ', ')" -ForegroundColor Yellow
+        }
+    }
+    return $rules
+}
+
+function Limit-AICommitDescription {
+    # Models overshoot word limits, so AI_COMMIT_BODY_MAX_WORDS is enforced
+    # afterwards: whole sentences are kept while they fit, and a first
+    # sentence that is too long on its own is cut at the limit
+    param([string]$Description)
+
+    $maxWords = [int](Get-AICommitSetting -Name "AI_COMMIT_BODY_MAX_WORDS" -Default 0)
+    if ($maxWords -le 0 -or [string]::IsNullOrWhiteSpace($Description)) {
+        return $Description
+    }
+    $words = @($Description.Trim() -split '\s+')
+    if ($words.Count -le $maxWords) {
+        return $Description
+    }
+
+    $kept = @()
+    $count = 0
+    foreach ($sentence in [regex]::Split($Description.Trim(), '(?<=[.!?])\s+')) {
+        $sentenceWords = @($sentence -split '\s+').Count
+        if ($count + $sentenceWords -gt $maxWords) {
+            break
+        }
+        $kept += $sentence
+        $count += $sentenceWords
+    }
+    if ($kept.Count -eq 0) {
+        return (($words | Select-Object -First $maxWords) -join " ").TrimEnd(",;:") + "..."
+    }
+    return ($kept -join " ")
+}
+
+function Get-AICommitRepoContext {
+    # What the project looks like, so messages use its style and terms:
+    # recent commit subjects (AI_COMMIT_CONTEXT_COMMITS), the branch name
+    # (AI_COMMIT_CONTEXT_BRANCH) and a README excerpt (AI_COMMIT_CONTEXT_README)
+    $sections = @()
+
+    $branchSetting = "$(Get-AICommitSetting -Name "AI_COMMIT_CONTEXT_BRANCH" -Default "true")".Trim().ToLower()
+    if ($branchSetting -notin @('false', 'no', 'off', '0')) {
+        $branch = "$(git rev-parse --abbrev-ref HEAD 2>$null)".Trim()
+        if ($branch -and $branch -ne "HEAD") {
+            $sections += "Current branch: $branch"
+        }
+    }
+
+    $commitCount = [int](Get-AICommitSetting -Name "AI_COMMIT_CONTEXT_COMMITS" -Default 10)
+    if ($commitCount -gt 0) {
+        $subjects = @(git log -n $commitCount --no-merges --format=%s 2>$null | Where-Object { $_ })
+        if ($subjects.Count -gt 0) {
+            $sections += "Recent commit subjects:`n$(($subjects | ForEach-Object { "- $_" }) -join "`n")"
+        }
+    }
+
+    # Off by default; true for the first 1500 characters, or a length
+    $readmeSetting = "$(Get-AICommitSetting -Name "AI_COMMIT_CONTEXT_README")".Trim().ToLower()
+    $readmeLength = if ($readmeSetting -in @('true', 'yes', 'on')) { 1500 } elseif ($readmeSetting -match '^\d+$') { [int]$readmeSetting } else { 0 }
+    if ($readmeLength -gt 0) {
+        $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+        $readme = @("README.md", "README.rst", "README.txt", "README") | ForEach-Object { Join-Path $root $_ } | Where-Object { Test-Path $_ -PathType Leaf } | Select-Object -First 1
+        if ($readme) {
+            $text = ((Get-Content -Path $readme -Raw -Encoding UTF8) -replace "(\r?\n){3,}", "`n`n").Trim()
+            if ($text.Length -gt $readmeLength) {
+                $text = $text.Substring(0, $readmeLength) + "..."
+            }
+            $sections += "README excerpt:`n$text"
+        }
+    }
+
+    if ($sections.Count -eq 0) {
+        return ""
+    }
+    return "About the project (match its style and terminology, but describe only the diff):`n$($sections -join "`n`n")"
+}
+
+function New-AICommitPrompt {
+    param(
+        [string]$Task,
+        [string]$Context,
+        [string]$Diff,
+        [string[]]$RiskReasons,
+        [string[]]$BreakingReasons,
+        [hashtable]$Provider,
+        [string]$Template
+    )
+
+    # Tiny and huge diffs get their own strategy (see Tiers.ps1); a huge
+    # one is replaced by its per-file summary
+    $tier = Get-AICommitDiffTier -Diff $Diff
+    $diffLabel = "diff"
+    if ($tier -eq "huge") {
+        $Diff = ConvertTo-AICommitDiffStat -Diff $Diff
+        $diffLabel = "summary of the changes"
+        Write-Host "Note: Large diff, sending a per-file summary instead of the full diff" -ForegroundColor Yellow
+    }
+
+    # With a known context window the diff is checked in tokens below; the
+    # character limit applies when the window is unknown or it is set
+    $window = if ($null -ne $Provider) { Get-AICommitContextWindow -Provider $Provider } else { $null }
+    $maxLengthSetting = Get-AICommitSetting -Name "AI_COMMIT_MAX_DIFF_LENGTH"
+    if ($script:AICommitFastMode) {
+        # -fast trades detail for speed: a much smaller diff, always
+        $maxLengthSetting = Get-AICommitSetting -Name "AI_COMMIT_FAST_MAX_DIFF_LENGTH" -Default 8000
+    }
+    if ($null -eq $window -or $maxLengthSetting) {
+        # Default: 30,000 characters
+        $maxLength = if ($maxLengthSetting) { [int]$maxLengthSetting } else { 30000 }
+        if ($Diff.Length -gt $maxLength) {
+            $Diff = $Diff.Substring(0, $maxLength) + "`n... (diff truncated)"
+            Write-Host "Note: Diff was truncated due to length" -ForegroundColor Yellow
+        }
+    }
+
+    # The team's own messages as few-shot examples, if configured (not in -fast)
+    $style = if ($script:AICommitFastMode) { "" } else { Get-AICommitStylePrompt }
+    $project = if ($script:AICommitFastMode) { "" } else { Get-AICommitRepoContext }
+
+    if ($null -ne $window) {
+        # The diff matters most, then the context; examples are a nice-to-have.
+        # The fixed instructions below are about 400 tokens.
+        $fitted = Resolve-AICommitPromptBudget -Provider $Provider -Window $window -Reserved 400 -FixedText $Task -Sections @(
+            @{ Name = "diff"; Text = $Diff; Priority = 1; Share = 0.6 }
+            @{ Name = "context"; Text = $Context; Priority = 2; Share = 0.2 }
+            @{ Name = "project context"; Text = $project; Priority = 3; Share = 0.05 }
+            @{ Name = "style examples"; Text = $style; Priority = 4; Share = 0.05 }
+        )
+        if ($null -eq $fitted) {
+            return $null
+        }
+        $Diff = $fitted["diff"]
+        $Context = $fitted["context"]
+        $project = $fitted["project context"]
+        $style = $fitted["style examples"]
+    }
+    if ($style) {
+        $style = "$style`n`n"
+    }
+    if ($project) {
+        $project = "$project`n`n"
+    }
+
+    # Extra context (e.g. the commit being reverted) goes right before the diff
+    if (![string]::IsNullOrWhiteSpace($Context)) {
+        $Context = "$Context`n`n"
+    }
+
+    # High-impact diffs get an extra line for reviewers, possibly breaking
+    # ones a line for what breaks
+    $lineCount = 2
+    $riskFormat = ""
+    $riskRules = ""
+    if ($RiskReasons.Count -gt 0) {
+        $lineCount++
+        $riskFormat = "`nRISK: [one short sentence for reviewers]"
+        $riskRules = @"
+
+- Then start with exactly "RISK: " and name what a reviewer should check, in one short sentence (e.g. touches auth middleware; behavior change behind flag X)
+- This change looks high-impact because it $($RiskReasons -join '; ')
+"@
+    }
+    $breakingFormat = ""
+    $breakingRules = ""
+    if ($BreakingReasons.Count -gt 0) {
+        $lineCount++
+        $breakingFormat = "`nBREAKING: [what breaks for callers and how to migrate, or none]"
+        $breakingRules = @"
+
+- Then start with exactly "BREAKING: " and say in one sentence what breaks for users of the code and how to migrate, or write "none" if nothing actually breaks
+- This change may be breaking because it $($BreakingReasons -join '; ')
+"@
+    }
+
+    # Moved code should read as a move, not as new code
+    $renameRules = ""
+    if ($Diff -match '(?m)^(rename|copy) from |^(renamed|copied) .+ \u2192 ') {
+        $renameRules = "`n- Renamed, moved or copied files are not new code: describe them as a move or refactor and name what changed beyond the move, if anything"
+    }
+
+    # Binary files are listed by name and size (see Filter.ps1)
+    $binaryRules = ""
+    if ($Diff -match '(?m)^(new|modified|deleted|renamed) binary file ') {
+        $binaryRules = "`n- Mention binary files the way they are listed, e.g. `"new binary file docs/logo.png (12 KB)`", without guessing their content"
+    }
+
+    $format = @"
+CRITICAL: You must respond in EXACTLY this format. Do not add any other text, explanations, or formatting:
+
+HEADER: [your header text here]
+DESCRIPTION: [your description text here]$riskFormat$breakingFormat
+
+STRICT REQUIREMENTS:
+- Start with exactly "HEADER: " (including the space after colon)
+- Header must be 50 characters or less
+- Use imperative mood (Add, Fix, Update - NOT Added, Fixed, Updated)$(Get-AICommitPresetRules)$(Get-AICommitScopeRules)
+- Then a blank line
+- Then start with exactly "DESCRIPTION: " (including the space after colon)
+- Description should explain what changed and why$(Get-AICommitDescriptionRules)$(Get-AICommitTierRules -Tier $tier)$renameRules$binaryRules$riskRules$breakingRules
+- Do not use markdown, bullets, or special formatting
+- Do not add introductory text like "Here's a suggested commit message"
+- Do not add closing text or explanations
+- Your response should contain ONLY these $(@('two', 'three', 'four')[$lineCount - 2]) lines
+
+EXAMPLE FORMAT:
+HEADER: Add user authentication system
+DESCRIPTION: Implements login/logout functionality with JWT tokens and password hashing for secure user management
+"@
+
+    # A team's own prompt (AI_COMMIT_PROMPT_TEMPLATE) replaces the layout below
+    if (![string]::IsNullOrWhiteSpace($Template)) {
+        return Expand-AICommitPromptTemplate -Template $Template -Values @{
+            task     = $Task
+            format   = $format
+            examples = "$style".Trim()
+            project  = "$project".Trim()
+            context  = "$Context".Trim()
+            diff     = $Diff
+        }
+    }
+
+    return @"
+$Task
+
+$format
+
+$($style)$($project)$($Context)Now analyze this $($diffLabel):
+
+$Diff
+"@
+}
+
+# Structured output (JsonMode providers): the same three fields as the text
+# format, as a JSON object. Strict schemas need every field required.
+$script:AICommitSuggestionSchema = @{
+    type                 = "object"
+    properties           = [ordered]@{
+        header      = @{ type = "string"; description = "Commit header, 50 characters or less, imperative mood" }
+        description = @{ type = "string"; description = "What changed and why, without markdown" }
+        risk        = @{ type = "string"; description = "One short sentence for reviewers if a RISK line was asked for, otherwise empty" }
+        breaking    = @{ type = "string"; description = "What breaks and how to migrate if a BREAKING line was asked for, otherwise empty" }
+    }
+    required             = @("header", "description", "risk", "breaking")
+    additionalProperties = $false
+}
+$script:AICommitJsonInstruction = "Instead of the HEADER:/DESCRIPTION:/RISK:/BREAKING: lines, return them as a JSON object with the string fields header, description, risk and breaking (empty if no such line is asked for)."
+
+# Several alternatives in one answer (AI_COMMIT_SUGGESTIONS / -n)
+$script:AICommitCandidatesSchema = @{
+    type                 = "object"
+    properties           = [ordered]@{
+        candidates = @{ type = "array"; items = $script:AICommitSuggestionSchema }
+    }
+    required             = @("candidates")
+    additionalProperties = $false
+}
+
+function ConvertTo-AICommitGeminiSchema {
+    # Gemini's responseSchema is an OpenAPI subset: upper-case types and no
+    # additionalProperties
+    param([hashtable]$Schema)
+
+    $converted = @{}
+    foreach ($key in $Schema.Keys) {
+        $value = $Schema[$key]
+        if ($key -eq "additionalProperties") {
+            continue
+        } elseif ($key -eq "type") {
+            $converted.type = $value.ToUpper()
+        } elseif ($key -eq "items") {
+            $converted.items = ConvertTo-AICommitGeminiSchema -Schema $value
+        } elseif ($key -eq "properties") {
+            $properties = [ordered]@{}
+            foreach ($name in $value.Keys) {
+                $properties[$name] = ConvertTo-AICommitGeminiSchema -Schema $value[$name]
+            }
+            $converted.properties = $properties
+        } else {
+            $converted[$key] = $value
+        }
+    }
+    return $converted
+}
+
+function ConvertFrom-AICommitJsonSuggestion {
+    # Reads a structured answer; $null when it isn't a JSON object with a header
+    param([string]$Suggestion)
+
+    # Tolerates code fences or text around the object
+    $start = $Suggestion.IndexOf("{")
+    $end = $Suggestion.LastIndexOf("}")
+    if ($start -lt 0 -or $end -le $start) {
+        return $null
+    }
+    try {
+        $object = $Suggestion.Substring($start, $end - $start + 1) | ConvertFrom-Json
+    }
+    catch {
+        return $null
+    }
+    if ([string]::IsNullOrWhiteSpace($object.header)) {
+        return $null
+    }
+    return @{
+        Header      = "$($object.header)".Trim()
+        Description = "$($object.description)".Trim()
+        Risk        = "$($object.risk)".Trim()
+        Breaking    = "$($object.breaking)".Trim()
+    }
+}
+
+function ConvertFrom-AICommitSuggestion {
+    param(
+        [string]$Suggestion,
+        [switch]$Lenient
+    )
+
+    $lines = $Suggestion -split "`n"
+    $header = ($lines | Where-Object { $_ -match "^HEADER:" }) -replace "^HEADER:\s*", ""
+    $description = ($lines | Where-Object { $_ -match "^DESCRIPTION:" }) -replace "^DESCRIPTION:\s*", ""
+    $risk = ($lines | Where-Object { $_ -match "^RISK:" } | Select-Object -First 1) -replace "^RISK:\s*", ""
+    $breaking = ($lines | Where-Object { $_ -match "^BREAKING:" } | Select-Object -First 1) -replace "^BREAKING:\s*", ""
+
+    if ($Lenient -and [string]::IsNullOrWhiteSpace($header)) {
+        # Small models like to wrap the answer in markdown or chat around it:
+        # drop fences and emphasis, then look for the labels anywhere
+        $cleaned = @($lines | ForEach-Object { ($_ -replace '[*`#>]', '').Trim() } | Where-Object {
+            $_ -ne "" -and $_ -notmatch "^(here'?s|here is|sure|certainly|suggested commit)" -and $_ -notmatch "^(text|plaintext)$"
+        })
+        $header = ($cleaned | Where-Object { $_ -match "^\s*header\s*:" } | Select-Object -First 1) -replace "^\s*header\s*:\s*", ""
+        $description = ($cleaned | Where-Object { $_ -match "^\s*description\s*:" } | Select-Object -First 1) -replace "^\s*description\s*:\s*", ""
+        $risk = ($cleaned | Where-Object { $_ -match "^\s*risk\s*:" } | Select-Object -First 1) -replace "^\s*risk\s*:\s*", ""
+        $breaking = ($cleaned | Where-Object { $_ -match "^\s*breaking\s*:" } | Select-Object -First 1) -replace "^\s*breaking\s*:\s*", ""
+
+        # No labels at all: treat it like a plain commit message
+        if ([string]::IsNullOrWhiteSpace($header) -and $cleaned.Count -gt 0) {
+            $header = $cleaned[0]
+            $description = ($cleaned | Select-Object -Skip 1) -join " "
+        }
+    }
+
+    return @{
+        Header      = "$header".Trim()
+        Description = "$description".Trim()
+        Risk        = "$risk".Trim()
+        Breaking    = "$breaking".Trim()
+    }
+}
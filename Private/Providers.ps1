@@ -0,0 +1,357 @@
+# Known providers: the variable holding their API key, the model used when
+# only the provider is chosen, the model name patterns used to guess the
+# provider when AI_COMMIT_PROVIDER isn't set, and what the provider can do.
+#
+# Capabilities are checked with Test-AICommitCapability before a feature uses
+# them, so a feature is skipped or simplified rather than failing elsewhere:
+#   Streaming      - partial answers can be shown while they arrive
+#   SystemMessages - instructions can be sent separately from the diff
+#   JsonMode       - the answer can be constrained to JSON
+#   Candidates     - several answers from one request
+#   PromptCaching  - repeated prompt prefixes are cached by the provider
+#   Thinking       - extended thinking / reasoning effort settings
+#   ModelList      - the available models can be listed
+#   ModelCheck     - unknown model names are worth a warning
+#
+# FastModel is the cheap, quick model -fast switches to (AI_COMMIT_FAST_MODEL
+# overrides it); providers without one keep their model. KeysUrl is where
+# keys are created and revoked, shown by 'aicommit config rotate-key'.
+$script:AICommitProviders = @{
+    anthropic = @{
+        KeyName       = "ANTHROPIC_API_KEY_AICOMMIT"
+        DefaultModel  = "claude-3-5-haiku-20241022"
+        FastModel     = "claude-3-5-haiku-20241022"
+        KeysUrl       = "https://console.anthropic.com/settings/keys"
+        ModelPatterns = @("claude-*")
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "PromptCaching", "Thinking", "ModelList", "ModelCheck")
+    }
+    google    = @{
+        KeyName       = "GEMINI_API_KEY_AICOMMIT"
+        DefaultModel  = "gemini-2.5-flash"
+        FastModel     = "gemini-2.5-flash-lite"
+        KeysUrl       = "https://aistudio.google.com/apikey"
+        ModelPatterns = @("gemini-*", "models/gemini-*")
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "Candidates", "PromptCaching", "Thinking", "ModelList", "ModelCheck")
+    }
+    # Gemini on Vertex AI with Application Default Credentials instead of a
+    # key. Same model names as google, so it is only used when chosen
+    # explicitly (AI_COMMIT_PROVIDER=vertex or -provider vertex).
+    vertex    = @{
+        KeyName       = $null
+        DefaultModel  = "gemini-2.5-flash"
+        FastModel     = "gemini-2.5-flash-lite"
+        ModelPatterns = @()
+        ModelsOf      = "google"
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "Candidates", "PromptCaching", "Thinking")
+    }
+    openai    = @{
+        KeyName       = "OPENAI_API_KEY_AICOMMIT"
+        DefaultModel  = "gpt-4.1-mini"
+        FastModel     = "gpt-4.1-nano"
+        KeysUrl       = "https://platform.openai.com/api-keys"
+        ModelPatterns = @("gpt-*", "chatgpt-*", "o1*", "o3*", "o4*")
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "PromptCaching", "Thinking", "ModelList", "ModelCheck")
+    }
+    mistral   = @{
+        KeyName       = "MISTRAL_API_KEY_AICOMMIT"
+        DefaultModel  = "mistral-small-latest"
+        FastModel     = "ministral-3b-latest"
+        KeysUrl       = "https://console.mistral.ai/api-keys"
+        ModelPatterns = @("mistral-*", "open-mistral-*", "ministral-*", "magistral-*", "codestral-*")
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "Candidates", "ModelList", "ModelCheck")
+    }
+    # Fast inference for open models through an OpenAI-compatible API
+    groq      = @{
+        KeyName       = "GROQ_API_KEY_AICOMMIT"
+        DefaultModel  = "llama-3.1-8b-instant"
+        FastModel     = "llama-3.1-8b-instant"
+        KeysUrl       = "https://console.groq.com/keys"
+        ModelPatterns = @("llama-3*", "mixtral-*")
+        Capabilities  = @("Streaming", "SystemMessages", "JsonMode", "ModelList", "ModelCheck")
+    }
+    # One key for models of many vendors, named vendor/model. OpenRouter
+    # changes its catalog often, so model names are passed through unchecked
+    # (no ModelCheck).
+    openrouter = @{
+        KeyName       = "OPENROUTER_API_KEY_AICOMMIT"
+        DefaultModel  = "openai/gpt-4.1-mini"
+        FastModel     = "openai/gpt-4.1-nano"
+        KeysUrl       = "https://openrouter.ai/settings/keys"
+        ModelPatterns = @("openai/*", "anthropic/*", "google/*", "meta-llama/*", "mistralai/*", "deepseek/*", "qwen/*", "x-ai/*", "openrouter/*")
+        Capabilities  = @("Streaming", "SystemMessages", "ModelList")
+    }
+    # Any OpenAI-compatible server (vLLM, LM Studio, LiteLLM, gateways) at
+    # AI_COMMIT_CUSTOM_BASE_URL. The key is optional; AI_COMMIT_CUSTOM_API_KEY_ENV
+    # names the variable holding it. Only used when chosen explicitly.
+    custom    = @{
+        KeyName        = $null
+        KeyNameSetting = "AI_COMMIT_CUSTOM_API_KEY_ENV"
+        DefaultModel   = $null
+        ModelPatterns  = @()
+        Capabilities   = @("SystemMessages", "ModelList")
+    }
+    # Claude and Titan through AWS Bedrock; the aws CLI signs the requests
+    # with the standard credential chain (environment, shared config, SSO),
+    # so there is no key. Cross-region inference profiles add a prefix (us.).
+    bedrock   = @{
+        KeyName       = $null
+        DefaultModel  = "anthropic.claude-3-haiku-20240307-v1:0"
+        ModelPatterns = @("anthropic.claude-*", "*.anthropic.claude-*", "amazon.titan-text-*")
+        Capabilities  = @("SystemMessages", "ModelList", "ModelCheck")
+    }
+    # Local models served by Ollama; needs no key. Ollama model names are
+    # usually tagged (llama3.2:3b), untagged ones need AI_COMMIT_PROVIDER.
+    # Bedrock model IDs (-v1:0) and OpenRouter's free variants (:free) are
+    # tagged too and are excluded, as are Groq's hyphenated llama-3 names.
+    ollama    = @{
+        KeyName         = $null
+        DefaultModel    = "llama3.2"
+        ModelPatterns   = @("*:*", "llama*", "qwen*", "gemma*", "phi*", "codellama*", "deepseek-r1*", "deepseek-coder*")
+        ExcludePatterns = @("anthropic.*", "*.anthropic.*", "amazon.*", "*:free", "llama-3*")
+        Capabilities    = @("Streaming", "SystemMessages", "JsonMode", "ModelList", "ModelCheck")
+    }
+    # Offline stand-in used by 'aicommit selftest'; needs no key
+    fake      = @{
+        KeyName       = $null
+        DefaultModel  = "fake"
+        ModelPatterns = @("fake")
+        Capabilities  = @()
+    }
+}
+
+function Test-AICommitCapability {
+    param(
+        [hashtable]$Provider,
+        [string]$Name
+    )
+
+    $entry = $script:AICommitProviders[$Provider.Carrier]
+    return ($null -ne $entry -and $entry.Capabilities -contains $Name)
+}
+
+# -provider / -model overrides and -fast for the current invocation
+$script:AICommitProviderOverride = $null
+$script:AICommitModelOverride = $null
+$script:AICommitFastMode = $false
+
+function Get-AICommitFastProvider {
+    # The provider/model for -fast: AI_COMMIT_FAST_MODEL ("model" or
+    # "provider:model"), else the provider's FastModel. An explicit -model
+    # always wins.
+    param([hashtable]$Provider)
+
+    if ($script:AICommitModelOverride) {
+        return $Provider
+    }
+    $carrier = $Provider.Carrier
+    $fastModel = $script:AICommitProviders[$carrier].FastModel
+    $setting = Get-AICommitSetting -Name "AI_COMMIT_FAST_MODEL"
+    if (![string]::IsNullOrWhiteSpace($setting)) {
+        # Ollama tags contain colons too, so only a known provider name counts
+        $prefix, $rest = $setting.Trim() -split ":", 2
+        if ($rest -and $script:AICommitProviders.ContainsKey($prefix.ToLower())) {
+            $carrier = $prefix.ToLower()
+            $fastModel = $rest
+        } else {
+            $fastModel = $setting.Trim()
+        }
+    }
+    if ([string]::IsNullOrWhiteSpace($fastModel) -or ($carrier -eq $Provider.Carrier -and $fastModel -eq $Provider.Model)) {
+        return $Provider
+    }
+
+    $fastProvider = Get-AICommitProvider -Carrier $carrier -Model $fastModel
+    if ($null -eq $fastProvider) {
+        Write-Host "Warning: Fast model unavailable, keeping $($Provider.Model)" -ForegroundColor Yellow
+        return $Provider
+    }
+    Write-Host "Fast mode: using $($fastProvider.Model) ($($fastProvider.Carrier))" -ForegroundColor Cyan
+    return $fastProvider
+}
+
+function Get-AICommitCarrierFromModel {
+    # Returns every provider whose model patterns match; callers decide what
+    # to do when that is none or more than one
+    param([string]$Model)
+
+    $matching = @()
+    foreach ($name in $script:AICommitProviders.Keys | Sort-Object) {
+        $excluded = @($script:AICommitProviders[$name].ExcludePatterns | Where-Object { $_ -and $Model -like $_ })
+        if ($excluded.Count -gt 0) {
+            continue
+        }
+        foreach ($pattern in $script:AICommitProviders[$name].ModelPatterns) {
+            if ($Model -like $pattern) {
+                $matching += $name
+                break
+            }
+        }
+    }
+    return ,$matching
+}
+
+function Get-AICommitWorkspaceKeyName {
+    # Anthropic bills the workspace a key was created in, so a workspace
+    # (AI_COMMIT_ANTHROPIC_WORKSPACE) is chosen by its own key:
+    # ANTHROPIC_API_KEY_AICOMMIT_<WORKSPACE>, e.g. _WRKSPC_01ABC for
+    # wrkspc_01abc. $null when no workspace is set.
+    param([string]$Carrier)
+
+    if ($Carrier -ne "anthropic") {
+        return $null
+    }
+    $workspace = "$(Get-AICommitSetting -Name "AI_COMMIT_ANTHROPIC_WORKSPACE")".Trim()
+    if (!$workspace) {
+        return $null
+    }
+    return "$($script:AICommitProviders.anthropic.KeyName)_$(($workspace -replace '[^A-Za-z0-9]', '_').ToUpper())"
+}
+
+function Get-AICommitOrgHeaders {
+    # Organization and project headers that bill OpenAI usage to the right
+    # place (AI_COMMIT_OPENAI_ORGANIZATION, AI_COMMIT_OPENAI_PROJECT). With
+    # AI_COMMIT_REQUIRE_ORG on, a missing organization, project or Anthropic
+    # workspace is an error instead of usage landing on a default account.
+    # Returns the headers, or $null after reporting the error.
+    param(
+        [string]$Carrier,
+        [string]$ApiKey
+    )
+
+    $headers = @{}
+    $required = Test-AICommitSettingEnabled -Name "AI_COMMIT_REQUIRE_ORG"
+    if ($Carrier -eq "openai") {
+        $organization = "$(Get-AICommitSetting -Name "AI_COMMIT_OPENAI_ORGANIZATION")".Trim()
+        $project = "$(Get-AICommitSetting -Name "AI_COMMIT_OPENAI_PROJECT")".Trim()
+        if ($organization) {
+            if ($organization -notmatch '^org-') {
+                Write-Host "Warning: AI_COMMIT_OPENAI_ORGANIZATION '$organization' doesn't look like an organization ID (org-...)" -ForegroundColor Yellow
+            }
+            $headers["OpenAI-Organization"] = $organization
+        }
+        if ($project) {
+            if ($project -notmatch '^proj_') {
+                Write-Host "Warning: AI_COMMIT_OPENAI_PROJECT '$project' doesn't look like a project ID (proj_...)" -ForegroundColor Yellow
+            }
+            $headers["OpenAI-Project"] = $project
+        }
+
+        # Project and service account keys carry their project; user keys
+        # use the account's default organization and project
+        $keyKind = (Get-AICommitKeyScope -Value $ApiKey).Kind
+        if ($required -and !$organization) {
+            Write-AICommitError -Message "This repository requires an OpenAI organization (AI_COMMIT_REQUIRE_ORG), set AI_COMMIT_OPENAI_ORGANIZATION" -Kind "OrgRequired" -Arguments @("AI_COMMIT_OPENAI_ORGANIZATION org-...")
+            return $null
+        }
+        if ($required -and !$project -and $keyKind -ne "project") {
+            Write-AICommitError -Message "This repository requires an OpenAI project (AI_COMMIT_REQUIRE_ORG); set AI_COMMIT_OPENAI_PROJECT or use a project key (sk-proj-)" -Kind "OrgRequired" -Arguments @("AI_COMMIT_OPENAI_PROJECT proj_...")
+            return $null
+        }
+    } elseif ($Carrier -eq "anthropic" -and $required -and $null -eq (Get-AICommitWorkspaceKeyName -Carrier $Carrier)) {
+        Write-AICommitError -Message "This repository requires an Anthropic workspace (AI_COMMIT_REQUIRE_ORG), set AI_COMMIT_ANTHROPIC_WORKSPACE" -Kind "OrgRequired" -Arguments @("AI_COMMIT_ANTHROPIC_WORKSPACE wrkspc_...")
+        return $null
+    }
+    return $headers
+}
+
+function Get-AICommitProvider {
+    # -Carrier/-Model pick a specific provider (e.g. a fallback); otherwise
+    # the -provider/-model flags and then the settings apply
+    param(
+        [string]$Carrier,
+        [string]$Model
+    )
+
+    # Model configuration - Check for user preference, if none use default
+    $configuredModel = Get-AICommitSetting -Name "AI_COMMIT_MODEL"
+    $configuredCarrier = Get-AICommitSetting -Name "AI_COMMIT_PROVIDER"
+    $AI_MODEL = if ($Model) { $Model } else { $script:AICommitModelOverride }
+    $carrier = if ($Carrier) { $Carrier } else { $script:AICommitProviderOverride }
+
+    # A one-off -model without -provider is routed by its name when that is
+    # unambiguous, so it doesn't inherit a configured provider it can't use
+    if ([string]::IsNullOrWhiteSpace($carrier) -and ![string]::IsNullOrWhiteSpace($AI_MODEL)) {
+        $candidates = Get-AICommitCarrierFromModel -Model $AI_MODEL
+        if ($candidates.Count -eq 1) {
+            $carrier = $candidates[0]
+        }
+    }
+
+    # Otherwise the configured provider decides
+    if ([string]::IsNullOrWhiteSpace($carrier)) {
+        $carrier = $configuredCarrier
+    }
+
+    if ([string]::IsNullOrWhiteSpace($carrier)) {
+        # No provider configured - fall back to guessing from the model name
+        if ([string]::IsNullOrWhiteSpace($AI_MODEL)) {
+            $AI_MODEL = if ($configuredModel) { $configuredModel } else { "gemini-2.5-flash" }  # Default model
+        }
+        $candidates = Get-AICommitCarrierFromModel -Model $AI_MODEL
+        if ($candidates.Count -eq 0) {
+            Write-AICommitError -Message "Unknown model carrier for model: $AI_MODEL" -Kind "UnknownModel"
+            return $null
+        }
+        if ($candidates.Count -gt 1) {
+            Write-AICommitError -Message "Model '$AI_MODEL' could belong to more than one provider: $($candidates -join ', ')" -Kind "UnknownProvider" -Arguments @($candidates[0])
+            return $null
+        }
+        $carrier = $candidates[0]
+    } else {
+        $carrier = $carrier.ToLower()
+        if (!$script:AICommitProviders.ContainsKey($carrier)) {
+            Write-AICommitError -Message "Unknown provider: $carrier" -Kind "BadProvider"
+            Write-Host "Available providers: $(($script:AICommitProviders.Keys | Sort-Object) -join ', ')" -ForegroundColor Yellow
+            return $null
+        }
+        # The model name is taken as-is; only pick one if none was given.
+        # A configured model is kept unless it clearly belongs elsewhere.
+        if ([string]::IsNullOrWhiteSpace($AI_MODEL)) {
+            $candidates = Get-AICommitCarrierFromModel -Model $configuredModel
+            $sameModels = $script:AICommitProviders[$carrier].ModelsOf
+            # Providers without a default (custom) always take the configured model
+            $noDefault = !$script:AICommitProviders[$carrier].DefaultModel
+            $AI_MODEL = if ($configuredModel -and ($noDefault -or $candidates.Count -eq 0 -or $candidates -contains $carrier -or ($sameModels -and $candidates -contains $sameModels))) {
+                $configuredModel
+            } else {
+                $script:AICommitProviders[$carrier].DefaultModel
+            }
+        }
+    }
+
+    # Check for appropriate API key; some providers let a setting name the
+    # variable that holds it
+    $apiKey = $null
+    $keyName = $script:AICommitProviders[$carrier].KeyName
+    if (!$keyName -and $script:AICommitProviders[$carrier].KeyNameSetting) {
+        $keyName = Get-AICommitSetting -Name $script:AICommitProviders[$carrier].KeyNameSetting
+    }
+    $workspaceKeyName = Get-AICommitWorkspaceKeyName -Carrier $carrier
+    if ($workspaceKeyName) {
+        $keyName = $workspaceKeyName
+    }
+    if ($keyName) {
+        $apiKey = Get-AICommitApiKey -Name $keyName
+        if ($null -eq $apiKey) {
+            return $null
+        }
+    }
+
+    if ([string]::IsNullOrWhiteSpace($AI_MODEL)) {
+        Write-AICommitError -Message "No model set for provider $carrier" -Kind "NoModel" -Arguments @($carrier)
+        Write-Host "Or set AI_COMMIT_MODEL (or pass -model)" -ForegroundColor Yellow
+        return $null
+    }
+
+    $orgHeaders = Get-AICommitOrgHeaders -Carrier $carrier -ApiKey $apiKey
+    if ($null -eq $orgHeaders) {
+        return $null
+    }
+
+    return @{
+        Model   = $AI_MODEL
+        Carrier = $carrier
+        ApiKey  = $apiKey
+        Headers = $orgHeaders
+    }
+}
@@ -0,0 +1,162 @@
+# 'aicommit pr-fill': the repository's pull request template, filled in from
+# the branch's commits and diff
+$script:AICommitPullRequestTemplates = @(
+    ".github/PULL_REQUEST_TEMPLATE.md"
+    ".github/pull_request_template.md"
+    "PULL_REQUEST_TEMPLATE.md"
+    "pull_request_template.md"
+    "docs/PULL_REQUEST_TEMPLATE.md"
+    "docs/pull_request_template.md"
+)
+
+# Used when the repository has no template of its own
+$script:AICommitDefaultPullRequestTemplate = @"
+## Summary
+
+## Testing
+
+## Checklist
+- [ ] Tests added or updated
+- [ ] Documentation updated
+"@
+
+function Get-AICommitPullRequestTemplate {
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    foreach ($relative in $script:AICommitPullRequestTemplates) {
+        $path = Join-Path $root $relative
+        if (Test-Path $path -PathType Leaf) {
+            return @{ Path = $relative; Text = (Get-Content -Path $path -Raw -Encoding UTF8) }
+        }
+    }
+    return @{ Path = $null; Text = $script:AICommitDefaultPullRequestTemplate }
+}
+
+function Get-AICommitGitHubRepository {
+    # owner/name from the origin URL (https or ssh), $null if it isn't GitHub
+    $url = "$(git remote get-url origin 2>$null)".Trim()
+    if ($url -match 'github\.com[:/]([^/]+)/([^/]+?)(\.git)?/?$') {
+        return "$($Matches[1])/$($Matches[2])"
+    }
+    return $null
+}
+
+function Send-AICommitPullRequestBody {
+    # Puts the body into the open pull request of the current branch
+    param([string]$Body)
+
+    $token = Get-AICommitSetting -Name "GITHUB_TOKEN"
+    if ([string]::IsNullOrWhiteSpace($token)) {
+        Write-AICommitError -Message "Set GITHUB_TOKEN (a token that can write pull requests) to post the description" -Kind "MissingKey" -Arguments @("GITHUB_TOKEN")
+        return
+    }
+    $repository = Get-AICommitGitHubRepository
+    if ($null -eq $repository) {
+        Write-Host "Error: The origin remote is not a GitHub repository" -ForegroundColor Red
+        return
+    }
+    $branch = "$(git rev-parse --abbrev-ref HEAD)".Trim()
+    $owner = $repository.Split('/')[0]
+    $headers = @{
+        "Authorization"        = "Bearer $token"
+        "Accept"               = "application/vnd.github+json"
+        "X-GitHub-Api-Version" = "2022-11-28"
+    }
+
+    try {
+        $pulls = @(Invoke-RestMethod -Uri "https://api.github.com/repos/$repository/pulls?state=open&head=$([uri]::EscapeDataString("$($owner):$branch"))" -Method Get -Headers $headers)
+        if ($pulls.Count -eq 0) {
+            Write-Host "No open pull request for $branch yet" -ForegroundColor Yellow
+            Write-Host "Create one with: aicommit pr-fill > pr.md; gh pr create --body-file pr.md" -ForegroundColor Yellow
+            return
+        }
+        $payload = @{ body = $Body } | ConvertTo-Json
+        $null = Invoke-RestMethod -Uri "https://api.github.com/repos/$repository/pulls/$($pulls[0].number)" -Method Patch -Headers $headers -Body ([System.Text.Encoding]::UTF8.GetBytes($payload)) -ContentType "application/json; charset=utf-8"
+        Write-Host "Description of pull request #$($pulls[0].number) updated: $($pulls[0].html_url)" -ForegroundColor Green
+    }
+    catch {
+        Write-Host "Error: Could not update the pull request - $($_.Exception.Message)" -ForegroundColor Red
+    }
+}
+
+function Invoke-AICommitPullRequestFill {
+    # Writes the filled-in template to stdout (markdown, or another format
+    # from Output.ps1 with -Output); -Send also puts it into the open pull
+    # request on GitHub
+    param(
+        [string]$Base,
+        [string]$Output,
+        [switch]$Send
+    )
+
+    $Output = if ([string]::IsNullOrWhiteSpace($Output)) { "markdown" } else { $Output.ToLower() }
+    if (!(Test-AICommitOutputFormat -Format $Output -Allowed @($script:AICommitOutputFormatters.Keys))) {
+        return
+    }
+
+    if ([string]::IsNullOrWhiteSpace($Base)) {
+        $Base = Get-AICommitDefaultBase
+    }
+    $mergeBase = git merge-base $Base HEAD 2>$null
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($mergeBase)) {
+        Write-Host "Error: Could not find a common ancestor of '$Base' and HEAD" -ForegroundColor Red
+        Write-Host "Pass the target branch with -base, e.g. aicommit pr-fill -base origin/main" -ForegroundColor Yellow
+        return
+    }
+    $mergeBase = "$mergeBase".Trim()
+
+    $subjects = @(git log --reverse --format=%s "$mergeBase..HEAD")
+    if ($subjects.Count -eq 0) {
+        Write-Host "No commits on this branch since $Base" -ForegroundColor Green
+        return
+    }
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    $template = Get-AICommitPullRequestTemplate
+    $source = if ($template.Path) { $template.Path } else { "the default template" }
+    Write-Host "Filling in $source from $($subjects.Count) commit(s) since $Base..." -ForegroundColor Yellow
+
+    $messages = (git log --reverse --format="--- %h%n%B" "$mergeBase..HEAD") -join "`n"
+    $diff = Get-AICommitPromptDiff -Diff ((git diff -M -C $mergeBase HEAD -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")
+    $window = Get-AICommitContextWindow -Provider $provider
+    if ($null -ne $window) {
+        $diff = Limit-AICommitDiffTokens -Carrier $provider.Carrier -Diff $diff -MaxTokens ([int]($window * 0.6))
+    }
+
+    $prompt = @"
+Fill in this pull request template for the branch described below. Return the completed template as markdown and nothing else.
+
+Rules:
+- Keep every heading of the template, in the same order
+- Summary or description sections: explain what the branch changes and why, for a reviewer who hasn't seen it
+- Testing sections: list the tests that were added or changed in the diff and how to verify the change; do not claim anything was run
+- Screenshot sections: leave a placeholder "<!-- Add screenshots -->"
+- Checklist items: tick ([x]) only what the diff clearly shows (e.g. tests or docs changed); leave the rest unticked
+- Keep HTML comments from the template out of the answer
+- Do not wrap the answer in a code block
+
+TEMPLATE:
+$($template.Text)
+
+COMMITS:
+$messages
+
+DIFF:
+$diff
+"@
+
+    $answer = Invoke-AICommitCompletion -Provider $provider -Prompt $prompt
+    if ($null -eq $answer) {
+        return
+    }
+    # Some models fence the markdown anyway
+    $body = ($answer.Trim() -replace '^```(markdown|md)?\s*\n', '' -replace '\n```\s*$', '').Trim()
+
+    Write-Output (Format-AICommitOutput -Content (New-AICommitOutput -Body $body -Data @{ base = $Base; commits = $subjects.Count; template = $template.Path }) -Format $Output)
+    if ($Send) {
+        Send-AICommitPullRequestBody -Body $body
+    }
+}
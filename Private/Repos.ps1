@@ -0,0 +1,199 @@
+# Repositories aicommit has committed in, kept in ~/.aicommit/repos.json
+# with the defaults remembered for each: the provider and model last chosen
+# with -provider/-model, the last -push choice and a style preset set with
+# 'aicommit repos set'. Remembered defaults sit between the environment and
+# the user config file, so flags, .aicommit.env, .aicommit.yaml and
+# environment variables still win.
+$script:AICommitRememberedSettings = @{}
+$script:AICommitRepoDefaultNames = [ordered]@{
+    provider = "AI_COMMIT_PROVIDER"
+    model    = "AI_COMMIT_MODEL"
+    style    = "AI_COMMIT_STYLE_PRESET"
+    push     = $null
+}
+$script:AICommitRepoHistorySize = 50
+
+function Get-AICommitRepoEntries {
+    # All remembered repositories, most recently used first, as
+    # @{ Path; LastUsed; Commits; Defaults }
+    $path = Get-AICommitDataPath "repos.json"
+    if (!(Test-Path $path)) {
+        return ,@()
+    }
+    try {
+        $stored = Get-Content -Path $path -Raw -Encoding UTF8 | ConvertFrom-Json
+    }
+    catch {
+        Write-Host "Warning: Ignoring unreadable $path" -ForegroundColor Yellow
+        return ,@()
+    }
+
+    $entries = @()
+    foreach ($item in @($stored)) {
+        if (!$item.path) {
+            continue
+        }
+        $defaults = [ordered]@{}
+        if ($item.defaults) {
+            foreach ($property in $item.defaults.PSObject.Properties) {
+                $defaults[$property.Name] = "$($property.Value)"
+            }
+        }
+        $entries += @{
+            Path     = "$($item.path)"
+            LastUsed = "$($item.lastUsed)"
+            Commits  = [int]$item.commits
+            Defaults = $defaults
+        }
+    }
+    return ,@($entries | Sort-Object { $_.LastUsed } -Descending)
+}
+
+function Save-AICommitRepoEntries {
+    param([object[]]$Entries)
+
+    $items = @($Entries | Sort-Object { $_.LastUsed } -Descending | Select-Object -First $script:AICommitRepoHistorySize | ForEach-Object {
+        [ordered]@{
+            path     = $_.Path
+            lastUsed = $_.LastUsed
+            commits  = $_.Commits
+            defaults = $_.Defaults
+        }
+    })
+    Set-Content -Path (Get-AICommitDataPath "repos.json") -Value (ConvertTo-Json -InputObject $items -Depth 4) -Encoding UTF8
+}
+
+function Get-AICommitRepoRoot {
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    if (!$root) {
+        return $null
+    }
+    # One entry per repository however its path is spelled
+    return [System.IO.Path]::GetFullPath($root).TrimEnd('/', '\')
+}
+
+function Import-AICommitRepoDefaults {
+    # Loads this repository's remembered defaults into the settings layer
+    # and returns them (an empty table outside a known repository)
+    $script:AICommitRememberedSettings = @{}
+    $root = Get-AICommitRepoRoot
+    $entries = Get-AICommitRepoEntries
+    $entry = $entries | Where-Object { $_.Path -eq $root } | Select-Object -First 1
+    if (!$entry) {
+        return [ordered]@{}
+    }
+    foreach ($name in $entry.Defaults.Keys) {
+        $setting = $script:AICommitRepoDefaultNames[$name]
+        if ($setting) {
+            $script:AICommitRememberedSettings[$setting] = $entry.Defaults[$name]
+        }
+    }
+    return $entry.Defaults
+}
+
+function Save-AICommitRepoUse {
+    # Records a commit in this repository, remembering the -provider,
+    # -model and -push choices that were given explicitly
+    param([hashtable]$Defaults = @{})
+
+    $root = Get-AICommitRepoRoot
+    if (!$root) {
+        return
+    }
+    $entries = Get-AICommitRepoEntries
+    $entry = $entries | Where-Object { $_.Path -eq $root } | Select-Object -First 1
+    if (!$entry) {
+        $entry = @{ Path = $root; LastUsed = ""; Commits = 0; Defaults = [ordered]@{} }
+        $entries += $entry
+    }
+    $entry.LastUsed = (Get-Date).ToString("yyyy-MM-ddTHH:mm:ss")
+    $entry.Commits++
+
+    # A model belongs to its provider; switching provider drops it
+    if ($Defaults.ContainsKey("provider") -and !$Defaults.ContainsKey("model") -and $entry.Defaults["provider"] -ne $Defaults["provider"]) {
+        $entry.Defaults.Remove("model")
+    }
+    foreach ($name in $Defaults.Keys) {
+        $entry.Defaults[$name] = $Defaults[$name]
+    }
+    Save-AICommitRepoEntries -Entries $entries
+}
+
+function Invoke-AICommitRepos {
+    # aicommit repos [list | set <name> <value> | unset <name> | forget [path] | clear]
+    param([string[]]$Arguments)
+
+    $action = if ($Arguments.Count -gt 0) { $Arguments[0].ToLower() } else { "list" }
+    $name = if ($Arguments.Count -gt 1) { $Arguments[1].ToLower() } else { $null }
+    $entries = Get-AICommitRepoEntries
+    $root = Get-AICommitRepoRoot
+
+    switch ($action) {
+        'list' {
+            if ($entries.Count -eq 0) {
+                Write-Host "No repositories yet - they are listed here after the first commit with aicommit" -ForegroundColor Yellow
+                return
+            }
+            Write-Host "`n--- REPOSITORIES ($(Get-AICommitDataPath 'repos.json')) ---" -ForegroundColor Cyan
+            foreach ($entry in $entries) {
+                $marker = if ($entry.Path -eq $root) { "*" } else { " " }
+                $missing = if (Test-Path -LiteralPath $entry.Path) { "" } else { " (missing)" }
+                $when = if ($entry.LastUsed) { $entry.LastUsed.Replace("T", " ").Substring(0, 16) } else { "-" }
+                Write-Host "$marker $($entry.Path)$missing" -ForegroundColor White
+                $defaults = @($entry.Defaults.Keys | ForEach-Object { "$_=$($entry.Defaults[$_])" }) -join ", "
+                Write-Host "    last used $when, $($entry.Commits) commit(s)$(if ($defaults) { ", $defaults" })" -ForegroundColor Gray
+            }
+            Write-Host "--- END REPOSITORIES ---`n" -ForegroundColor Cyan
+        }
+        { $_ -in @('set', 'unset') } {
+            if (!$name -or !$script:AICommitRepoDefaultNames.Contains($name) -or ($action -eq 'set' -and $Arguments.Count -lt 3)) {
+                $usage = if ($action -eq 'set') { "set <name> <value>" } else { "unset <name>" }
+                Write-Host "Usage: aicommit repos $usage - name is one of $(@($script:AICommitRepoDefaultNames.Keys) -join ', ')" -ForegroundColor Yellow
+                return
+            }
+            if (!$root) {
+                Write-AICommitError -Message "Not in a git repository" -Kind "NotARepo"
+                return
+            }
+            $entry = $entries | Where-Object { $_.Path -eq $root } | Select-Object -First 1
+            if (!$entry) {
+                $entry = @{ Path = $root; LastUsed = (Get-Date).ToString("yyyy-MM-ddTHH:mm:ss"); Commits = 0; Defaults = [ordered]@{} }
+                $entries += $entry
+            }
+            if ($action -eq 'unset') {
+                $entry.Defaults.Remove($name)
+                Save-AICommitRepoEntries -Entries $entries
+                Write-Host "$name is no longer remembered for $root" -ForegroundColor Green
+                return
+            }
+            $value = $Arguments[2..($Arguments.Count - 1)] -join " "
+            if ($name -eq 'push') {
+                if ($value.ToLower() -notin @('on', 'off', 'true', 'false', 'yes', 'no', '1', '0')) {
+                    Write-Host "Error: push is on or off" -ForegroundColor Red
+                    return
+                }
+                $value = if ($value.ToLower() -in @('on', 'true', 'yes', '1')) { "on" } else { "off" }
+            }
+            $entry.Defaults[$name] = $value
+            Save-AICommitRepoEntries -Entries $entries
+            Write-Host "$name=$value remembered for $root" -ForegroundColor Green
+        }
+        'forget' {
+            $target = if ($name) { [System.IO.Path]::GetFullPath($Arguments[1]).TrimEnd('/', '\') } else { $root }
+            $kept = @($entries | Where-Object { $_.Path -ne $target })
+            if ($kept.Count -eq $entries.Count) {
+                Write-Host "$(if ($target) { $target } else { 'This folder' }) is not in the list" -ForegroundColor Yellow
+                return
+            }
+            Save-AICommitRepoEntries -Entries $kept
+            Write-Host "Forgot $target and its defaults" -ForegroundColor Green
+        }
+        'clear' {
+            Remove-Item -Path (Get-AICommitDataPath "repos.json") -Force -ErrorAction SilentlyContinue
+            Write-Host "Forgot all repositories and their defaults" -ForegroundColor Green
+        }
+        default {
+            Write-Host "Error: Unknown repos action '$action' - use list, set, unset, forget or clear" -ForegroundColor Red
+        }
+    }
+}
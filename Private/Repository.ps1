@@ -0,0 +1,52 @@
+# Which repository a run works on. Git itself honors -repo (via the
+# location), GIT_DIR and GIT_WORK_TREE, but aicommit also reads files by
+# path (.clasp.json, wrangler.toml, untracked files, .aicommitignore), so a
+# run whose work tree is not the current directory is moved to its root.
+
+function Enter-AICommitRepository {
+    # Moves to -Path (like git -C) and then to the work tree root when
+    # GIT_WORK_TREE points away from the current directory. Relative
+    # GIT_DIR/GIT_WORK_TREE values are made absolute first so they keep
+    # their meaning. Returns the state for Exit-AICommitRepository, or
+    # $null when the path does not exist.
+    param([string]$Path)
+
+    $state = @{ GitDir = $env:GIT_DIR; WorkTree = $env:GIT_WORK_TREE; Depth = 0 }
+    foreach ($name in @('GIT_DIR', 'GIT_WORK_TREE')) {
+        $value = [Environment]::GetEnvironmentVariable($name)
+        if ($value -and ![System.IO.Path]::IsPathRooted($value)) {
+            [Environment]::SetEnvironmentVariable($name, (Join-Path (Get-Location).ProviderPath $value))
+        }
+    }
+
+    if ($Path) {
+        if (!(Test-Path -Path $Path -PathType Container)) {
+            Exit-AICommitRepository -State $state
+            return $null
+        }
+        Push-Location -Path $Path
+        $state.Depth++
+    }
+
+    # Outside the work tree git still finds it through GIT_WORK_TREE
+    if ("$(git rev-parse --is-inside-work-tree 2>$null)".Trim() -ne "true") {
+        $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+        if ($root) {
+            Push-Location -Path $root
+            $state.Depth++
+        }
+    }
+    return $state
+}
+
+function Exit-AICommitRepository {
+    # Back to where the run started, with the caller's GIT_DIR/GIT_WORK_TREE
+    param([hashtable]$State)
+
+    if (!$State) { return }
+    for ($i = 0; $i -lt $State.Depth; $i++) {
+        Pop-Location
+    }
+    $env:GIT_DIR = $State.GitDir
+    $env:GIT_WORK_TREE = $State.WorkTree
+}
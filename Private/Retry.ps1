@@ -0,0 +1,67 @@
+function ConvertFrom-AICommitRetryAfter {
+    # Retry-After is either a number of seconds or an HTTP date
+    param([string]$Value)
+
+    if ([string]::IsNullOrWhiteSpace($Value)) {
+        return $null
+    }
+    $seconds = 0.0
+    if ([double]::TryParse($Value.Trim(), [System.Globalization.NumberStyles]::Float, [System.Globalization.CultureInfo]::InvariantCulture, [ref]$seconds)) {
+        return [Math]::Max(0, $seconds)
+    }
+    $date = [DateTimeOffset]::MinValue
+    if ([DateTimeOffset]::TryParse($Value.Trim(), [System.Globalization.CultureInfo]::InvariantCulture, [System.Globalization.DateTimeStyles]::AssumeUniversal, [ref]$date)) {
+        return [Math]::Max(0, ($date - [DateTimeOffset]::UtcNow).TotalSeconds)
+    }
+    return $null
+}
+
+function Get-AICommitRetryDelay {
+    # Seconds to wait before the given retry (1, 2, ...): the server's
+    # Retry-After when it sent one, else exponential backoff with jitter.
+    # $null means the wait would exceed AI_COMMIT_RETRY_MAX_WAIT.
+    param([int]$Retry)
+
+    $maxWait = [double](Get-AICommitSetting -Name "AI_COMMIT_RETRY_MAX_WAIT" -Default 30)
+    if ($null -ne $script:AICommitLastRetryAfter) {
+        if ($script:AICommitLastRetryAfter -gt $maxWait) {
+            return $null
+        }
+        return $script:AICommitLastRetryAfter
+    }
+    # 1s, 2s, 4s, ... plus up to a second so parallel runs don't retry in step
+    $delay = [Math]::Pow(2, $Retry - 1) + (Get-Random -Minimum 0.0 -Maximum 1.0)
+    return [Math]::Min($delay, $maxWait)
+}
+
+function Invoke-AICommitRetriedCompletion {
+    # Calls one provider, retrying rate limits (429) and transient server
+    # errors (500/502/503) up to AI_COMMIT_RETRY_ATTEMPTS attempts in total
+    param(
+        [hashtable]$Provider,
+        [string]$Prompt,
+        [hashtable]$Schema
+    )
+
+    # -fast doesn't wait for a retry; a fallback provider is quicker
+    $attempts = if ($script:AICommitFastMode) { 1 } else { [Math]::Max(1, [int](Get-AICommitSetting -Name "AI_COMMIT_RETRY_ATTEMPTS" -Default 3)) }
+    for ($attempt = 1; $attempt -le $attempts; $attempt++) {
+        Write-AICommitProgress -Name "calling_provider" -Data @{ provider = $Provider.Carrier; model = $Provider.Model; attempt = $attempt }
+        $answer = Invoke-AICommitProviderCompletion -Provider $Provider -Prompt $Prompt -Schema $Schema
+        if ($null -ne $answer) {
+            Add-AICommitUsage -Provider $Provider -Usage $script:AICommitLastUsage
+        }
+        if ($null -ne $answer -or $script:AICommitLastFailure -notin @(429, 500, 502, 503) -or $attempt -eq $attempts) {
+            return $answer
+        }
+
+        $delay = Get-AICommitRetryDelay -Retry $attempt
+        if ($null -eq $delay) {
+            Write-Host "Warning: $($Provider.Carrier) asks to wait $([int]$script:AICommitLastRetryAfter)s, more than AI_COMMIT_RETRY_MAX_WAIT - not retrying" -ForegroundColor Yellow
+            return $null
+        }
+        Write-Host "Warning: HTTP $($script:AICommitLastFailure) from $($Provider.Carrier), retrying in $([Math]::Round($delay, 1))s (attempt $($attempt + 1) of $attempts)..." -ForegroundColor Yellow
+        Start-Sleep -Milliseconds ([int]($delay * 1000))
+    }
+    return $null
+}
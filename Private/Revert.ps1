@@ -0,0 +1,65 @@
+function Invoke-AICommitRevert {
+    param([string]$Ref)
+
+    if ([string]::IsNullOrWhiteSpace($Ref)) {
+        Write-Host "Error: Missing commit to revert" -ForegroundColor Red
+        Write-Host "Usage: aicommit revert <ref>" -ForegroundColor Yellow
+        return
+    }
+
+    # Resolve the commit before touching the working tree
+    $fullHash = git rev-parse --verify --quiet "$Ref^{commit}"
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($fullHash)) {
+        Write-Host "Error: '$Ref' is not a valid commit" -ForegroundColor Red
+        return
+    }
+    $fullHash = "$fullHash".Trim()
+    $shortHash = "$(git rev-parse --short $fullHash)".Trim()
+    $originalMessage = (git log -1 --format=%B $fullHash) -join "`n"
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    Write-Host "Reverting $shortHash..." -ForegroundColor Yellow
+    git revert --no-commit $fullHash
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Git revert failed with exit code: $LASTEXITCODE" -ForegroundColor Red
+        Write-Host "Resolve the conflicts and run 'git revert --continue', or undo with 'git revert --abort'" -ForegroundColor Yellow
+        return
+    }
+
+    # The model can't know why the commit is being reverted, so ask
+    $reason = Read-Host "Why are you reverting $($shortHash)? (optional)"
+
+    Write-Host "Analyzing changes..." -ForegroundColor Yellow
+    $revertDiff = Get-AICommitPromptDiff -Diff ((git diff -M -C --cached HEAD -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")
+
+    $context = "The commit being reverted ($shortHash) had this message:`n$originalMessage"
+    if (![string]::IsNullOrWhiteSpace($reason)) {
+        $context += "`n`nReason for the revert, as given by the developer:`n$reason"
+    }
+
+    $prompt = New-AICommitPrompt -Task "This git diff reverts an earlier commit. Suggest a commit message for the revert. The header should start with `"Revert`" and name what is being reverted; the description should explain what is being undone and why." -Context $context -Diff $revertDiff -Provider $provider
+
+    $parsed = Get-AICommitSuggestion -Provider $provider -Prompt $prompt
+    if ($null -eq $parsed) {
+        Write-Host "The revert is still staged. Commit it manually or undo it with 'git revert --abort'" -ForegroundColor Yellow
+        return
+    }
+    $message = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+    $reviewed = Read-AICommitMessage -Message $message -Provider $provider -Diff $revertDiff -Prompt $prompt
+    if ($null -eq $reviewed) {
+        git revert --abort 2>&1 | Out-Null
+        Write-Host "Revert aborted" -ForegroundColor Yellow
+        return
+    }
+
+    # Keep git's line so tooling can still link the revert to its commit
+    $reviewed.Body = "$($reviewed.Body)`n`nThis reverts commit $fullHash.".Trim()
+    $finalMessage = Format-AICommitMessage -Message $reviewed
+
+    Write-Host "Committing..." -ForegroundColor Yellow
+    $null = New-AICommitCommit -Message $finalMessage
+}
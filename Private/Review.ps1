@@ -0,0 +1,476 @@
+# Same limit the prompt asks the model to respect
+$script:AICommitHeaderMaxLength = 50
+
+function Test-AICommitHeader {
+    # Returns the problems found in a header; empty when it looks fine
+    param([string]$Header)
+
+    $problems = @()
+    if ([string]::IsNullOrWhiteSpace($Header)) {
+        return @("Header is empty")
+    }
+    if ($Header.Length -gt $script:AICommitHeaderMaxLength) {
+        $problems += "Header is $($Header.Length) characters, the limit is $($script:AICommitHeaderMaxLength)"
+    }
+    if ($Header.TrimEnd().EndsWith(".")) {
+        $problems += "Header ends with a period"
+    }
+    # Past tense or third person instead of imperative mood (Added/Adds vs Add)
+    $firstWord = ($Header -replace '^[a-z]+(\([^)]*\))?!?:\s*', '').Split(" ")[0]
+    $notPastTense = @('Embed', 'Feed', 'Need', 'Proceed', 'Seed', 'Shed', 'Shred', 'Speed')
+    if (($firstWord -cmatch '^[A-Z][a-z]+ed$' -and $firstWord -notin $notPastTense) -or $firstWord -cmatch '^(Adds|Fixes|Updates|Removes|Changes|Implements|Refactors|Improves)$') {
+        $problems += "Header should use imperative mood ('$firstWord' - e.g. Add, Fix, Update)"
+    }
+    # The team's convention, if one is configured (AI_COMMIT_STYLE_PRESET)
+    $problems += Test-AICommitStylePreset -Header $Header
+    return ,$problems
+}
+
+function ConvertFrom-AICommitEditorText {
+    # Reads a git-style message: '#' lines are comments and are dropped, the
+    # rest is parsed like any commit message (git skips leading blank lines too)
+    param([string]$Text)
+
+    $lines = @($Text -split "`n" | ForEach-Object { $_.TrimEnd("`r") } | Where-Object { !$_.StartsWith("#") })
+    return ConvertFrom-AICommitMessageText -Text ($lines -join "`n")
+}
+
+function Invoke-AICommitEditor {
+    # Opens a file in AI_COMMIT_EDITOR (or notepad) and waits until it is closed
+    param([string]$Path)
+
+    $editor = Get-AICommitSetting -Name "AI_COMMIT_EDITOR"
+    # An editor set by the repository is a command like any other; declined,
+    # the user's own choice applies
+    if ($editor -and !(Test-AICommitCommandAllowed -Name "AI_COMMIT_EDITOR" -Command $editor)) {
+        $editor = [Environment]::GetEnvironmentVariable("AI_COMMIT_EDITOR")
+        if ([string]::IsNullOrWhiteSpace($editor) -and $script:AICommitUserSettings.Contains("AI_COMMIT_EDITOR")) {
+            $editor = $script:AICommitUserSettings["AI_COMMIT_EDITOR"]
+        }
+    }
+
+    Write-Host "`nOpening editor..." -ForegroundColor Yellow
+    if ($editor) {
+        Write-Host "Edit the text, then save and close the editor to continue" -ForegroundColor Cyan
+        # e.g. "code --wait" or "vim"
+        $editorParts = @($editor -split '\s+' | Where-Object { $_ })
+        $editorArgs = @($editorParts | Select-Object -Skip 1)
+        & $editorParts[0] @editorArgs $Path
+    } else {
+        Write-Host "Edit the text, then SAVE (Ctrl+S) and CLOSE notepad to continue" -ForegroundColor Cyan
+        # Open in notepad and wait
+        Start-Process notepad.exe -ArgumentList $Path -Wait
+    }
+}
+
+function Edit-AICommitMessage {
+    param([pscustomobject]$Message)
+
+    # The .gitcommit extension lets editors apply commit message highlighting
+    $tempFile = New-AICommitTempFile -Extension ".gitcommit"
+
+    # Write current message to temp file in the format git itself uses
+    $editContent = @"
+$(Format-AICommitMessage -Message $Message)
+
+# Edit the commit message above. The first line is the subject
+# ($($script:AICommitHeaderMaxLength) characters or less, imperative mood), then a blank line, then
+# the body (wrap at 72 characters) and trailers such as Refs: or Risk:.
+# Lines starting with '#' are ignored. Save and close the editor to continue.
+"@
+    Set-Content -Path $tempFile -Value $editContent -Encoding UTF8
+
+    Invoke-AICommitEditor -Path $tempFile
+
+    # Read back the edited content
+    $editedContent = Get-Content -Path $tempFile -Raw -Encoding UTF8
+
+    # Parse the edited content
+    $edited = ConvertFrom-AICommitEditorText -Text $editedContent
+
+    # Clean up temp file
+    Remove-Item $tempFile -Force -ErrorAction SilentlyContinue
+
+    # An emptied message keeps the previous one, like before the edit
+    if ([string]::IsNullOrWhiteSpace($edited.Subject)) {
+        return $Message
+    }
+    return $edited
+}
+
+function Get-AICommitShortHeader {
+    param(
+        [hashtable]$Provider,
+        [string]$Header,
+        [string]$Description
+    )
+
+    $prompt = @"
+Rewrite this git commit header so it is at most $($script:AICommitHeaderMaxLength) characters, uses imperative mood (Add, Fix, Update) and has no trailing period. Keep its meaning.
+
+Respond with exactly one line in this format and nothing else:
+HEADER: [rewritten header]
+
+Header: $Header
+Description for context: $Description
+"@
+    $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt $prompt
+    if ($null -eq $answer) {
+        return $null
+    }
+    $parsed = ConvertFrom-AICommitSuggestion -Suggestion $answer -Lenient
+    if ([string]::IsNullOrWhiteSpace($parsed.Header)) {
+        return $null
+    }
+    return $parsed.Header
+}
+
+function Update-AICommitMessagePart {
+    # Asks the model again for only the header or only the description and
+    # keeps the rest of the message (including footers) as it is; returns
+    # $false when no new text came back
+    param(
+        [hashtable]$Provider,
+        [pscustomobject]$Message,
+        [string]$Diff,
+        [ValidateSet("header", "description")]
+        [string]$Part
+    )
+
+    $maxLength = [int](Get-AICommitSetting -Name "AI_COMMIT_MAX_DIFF_LENGTH" -Default 30000)
+    if ($Diff.Length -gt $maxLength) {
+        $Diff = $Diff.Substring(0, $maxLength) + "`n... (diff truncated)"
+    }
+    $header = Get-AICommitMessageHeader -Message $Message
+
+    if ($Part -eq "header") {
+        Write-Host "Regenerating the header..." -ForegroundColor Yellow
+        $prompt = @"
+Write a new git commit header for the diff below. The description is final and stays as it is; the header must fit it. Try a different wording than the current header.
+The header must be at most $($script:AICommitHeaderMaxLength) characters, use imperative mood (Add, Fix, Update) and have no trailing period.$(Get-AICommitPresetRules)$(Get-AICommitScopeRules)
+
+Respond with exactly one line in this format and nothing else:
+HEADER: [new header]
+
+Current header: $header
+Description: $($Message.Body)
+
+Diff:
+$Diff
+"@
+    } else {
+        Write-Host "Regenerating the description..." -ForegroundColor Yellow
+        $prompt = @"
+Write a new git commit description for the diff below. The header is final and stays as it is; the description must explain what changed and why in more depth than the header. Try a different approach than the current description.
+Do not use markdown, bullets, or special formatting.$(Get-AICommitDescriptionRules)
+
+Respond with exactly one line in this format and nothing else:
+DESCRIPTION: [new description]
+
+Header: $header
+Current description: $($Message.Body)
+
+Diff:
+$Diff
+"@
+    }
+
+    $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt $prompt
+    if ($null -eq $answer) {
+        return $false
+    }
+    $parsed = ConvertFrom-AICommitSuggestion -Suggestion $answer
+
+    if ($Part -eq "header") {
+        # Without the label the whole answer is taken as the header
+        $newHeader = if ($parsed.Header) { $parsed.Header } else { (ConvertFrom-AICommitSuggestion -Suggestion $answer -Lenient).Header }
+        if ([string]::IsNullOrWhiteSpace($newHeader)) {
+            return $false
+        }
+        Set-AICommitMessageHeader -Message $Message -Header $newHeader
+    } else {
+        $newDescription = if ($parsed.Description) { $parsed.Description } else { $answer.Trim() }
+        if ([string]::IsNullOrWhiteSpace($newDescription)) {
+            return $false
+        }
+        $Message.Body = Limit-AICommitDescription -Description $newDescription
+    }
+    return $true
+}
+
+function Update-AICommitMessageWithFeedback {
+    # Sends the original prompt again with the current message and the
+    # author's instructions ("mention the API rename", "make it shorter")
+    # as a follow-up; earlier instructions of the same review still apply.
+    # Footers stay. Returns $false when no new message came back.
+    param(
+        [hashtable]$Provider,
+        [pscustomobject]$Message,
+        [string]$Prompt,
+        [string[]]$Feedback
+    )
+
+    $followUp = @"
+$Prompt
+
+You suggested this commit message before:
+HEADER: $(Get-AICommitMessageHeader -Message $Message)
+DESCRIPTION: $($Message.Body)
+
+The author wants it revised:
+$(($Feedback | ForEach-Object { "- $_" }) -join "`n")
+
+Write the revised message for the same diff, following these instructions and all the rules above, in the same response format.
+"@
+    Write-Host "Revising the message..." -ForegroundColor Yellow
+    $parsed = Get-AICommitSuggestion -Provider $Provider -Prompt $followUp
+    if ($null -eq $parsed) {
+        return $false
+    }
+    Set-AICommitMessageHeader -Message $Message -Header $parsed.Header
+    $Message.Body = $parsed.Description
+    return $true
+}
+
+function Select-AICommitCandidate {
+    # Numbered list of alternative messages: a number takes that message as
+    # it is, e<number> reviews it first (edit, regenerate). $null when cancelled.
+    param(
+        [object[]]$Messages,
+        [hashtable]$Provider,
+        [string]$Diff,
+        [string]$Prompt,
+        [switch]$KeepHeader
+    )
+
+    Write-Host "`n--- SUGGESTED COMMIT MESSAGES ---" -ForegroundColor Cyan
+    for ($i = 0; $i -lt $Messages.Count; $i++) {
+        $header = Get-AICommitMessageHeader -Message $Messages[$i]
+        $headerColor = if ($header.Length -gt $script:AICommitHeaderMaxLength) { "Yellow" } else { "White" }
+        Write-Host "$($i + 1). $header ($($header.Length)/$($script:AICommitHeaderMaxLength))" -ForegroundColor $headerColor
+        $description = Get-AICommitMessageDescription -Message $Messages[$i]
+        if (![string]::IsNullOrWhiteSpace($description)) {
+            Write-Host "   $($description -replace "`n", "`n   ")" -ForegroundColor Gray
+        }
+    }
+    Write-Host "--- END MESSAGES ---`n" -ForegroundColor Cyan
+
+    Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "pick"; headers = @($Messages | ForEach-Object { Get-AICommitMessageHeader -Message $_ }) }
+    while ($true) {
+        $choice = "$(Read-Host "Use message (1-$($Messages.Count), Enter for 1), e<number> to review it first, or (c)ancel")".Trim().ToLower()
+        if ($choice -eq "") {
+            $choice = "1"
+        }
+        if ($choice -in @('c', 'cancel')) {
+            Write-Host "Commit cancelled" -ForegroundColor Yellow
+            Set-AICommitMetric -Name "review" -Value "cancelled"
+            return $null
+        }
+        if ($choice -match '^(e?)(\d+)$' -and [int]$Matches[2] -ge 1 -and [int]$Matches[2] -le $Messages.Count) {
+            $picked = $Messages[[int]$Matches[2] - 1]
+            if ($Matches[1]) {
+                return Read-AICommitMessage -Message $picked -Provider $Provider -Diff $Diff -Prompt $Prompt -KeepHeader:$KeepHeader
+            }
+            Set-AICommitMetric -Name "review" -Value "picked"
+            return $picked
+        }
+    }
+}
+
+function Read-AICommitMessage {
+    # Interactive review of a message structure (see Message.ps1); returns the
+    # accepted message or $null when cancelled. With -Diff the header or the
+    # description can be regenerated on their own; -Prompt is the original
+    # prompt, used when both are regenerated. -AutoAccept lets
+    # AI_COMMIT_AUTO_ACCEPT skip the review of the first suggestion.
+    # -KeepHeader (the author's -header) only regenerates the description.
+    param(
+        [pscustomobject]$Message,
+        [hashtable]$Provider,
+        [string]$Diff,
+        [string]$Prompt,
+        [switch]$AutoAccept,
+        [switch]$KeepHeader
+    )
+
+    # What Enter means (AI_COMMIT_DEFAULT_ANSWER: yes, edit, regenerate or cancel)
+    $defaultAnswer = "$(Get-AICommitSetting -Name "AI_COMMIT_DEFAULT_ANSWER" -Default "yes")".Trim().ToLower()
+    $defaultChoice = switch -regex ($defaultAnswer) {
+        '^(y|yes)$' { 'y' }
+        '^(e|edit)$' { 'e' }
+        '^(r|regenerate)$' { 'r' }
+        '^(c|cancel)$' { 'c' }
+        default {
+            Write-Host "Warning: Unknown AI_COMMIT_DEFAULT_ANSWER '$defaultAnswer' - use yes, edit, regenerate or cancel" -ForegroundColor Yellow
+            'y'
+        }
+    }
+
+    $current = $Message
+    $keptHeader = if ($KeepHeader) { Get-AICommitMessageHeader -Message $Message } else { $null }
+    $firstRun = $true
+    # For the run metrics: an edit counts over a regeneration
+    $reviewed = "accepted"
+    # Instructions given with "regenerate with feedback" so far
+    $feedback = @()
+
+    while ($true) {
+        # Display current message
+        if ($firstRun) {
+            Write-Host "`n--- SUGGESTED COMMIT MESSAGE ---" -ForegroundColor Cyan
+        } else {
+            Write-Host "`n--- CURRENT COMMIT MESSAGE ---" -ForegroundColor Cyan
+        }
+        $currentHeader = Get-AICommitMessageHeader -Message $current
+        $currentDescription = Get-AICommitMessageDescription -Message $current
+        $headerColor = if ($currentHeader.Length -gt $script:AICommitHeaderMaxLength) { "Yellow" } else { "White" }
+        Write-Host "HEADER: $currentHeader ($($currentHeader.Length)/$($script:AICommitHeaderMaxLength))" -ForegroundColor $headerColor
+        if (![string]::IsNullOrWhiteSpace($currentDescription)) {
+            Write-Host "DESCRIPTION: $currentDescription" -ForegroundColor White
+        }
+        Write-Host "--- END MESSAGE ---`n" -ForegroundColor Cyan
+        $vocabulary = Get-AICommitScopeVocabulary
+        if ($current.Scope -and $vocabulary.Count -gt 0 -and @($vocabulary | Where-Object { $_.Name -eq $current.Scope }).Count -eq 0) {
+            Write-Host "Note: Scope '$($current.Scope)' hasn't been used in this project before - (s)cope shows the usual ones" -ForegroundColor Yellow
+        }
+
+        if ($firstRun -and $AutoAccept -and (Test-AICommitAutoAccept -Message $current)) {
+            Set-AICommitMetric -Name "review" -Value "auto_accepted"
+            return $current
+        }
+        $firstRun = $false
+
+        # Get user decision
+        $canRegenerate = ($null -ne $Provider -and ![string]::IsNullOrWhiteSpace($Diff))
+        $regenerateOption = if ($canRegenerate) { " / (r)egenerate" } else { "" }
+        $validChoices = @('y', 'yes', 'e', 'edit', 'c', 'cancel', '')
+        if ($canRegenerate) {
+            $validChoices += @('r', 'regenerate')
+        }
+        # Conventional headers get the scope picker
+        $scopeOption = if ($current.Type) { " / (s)cope" } else { "" }
+        if ($current.Type) {
+            $validChoices += @('s', 'scope')
+        }
+        $choiceDefault = if ($defaultChoice -eq 'r' -and !$canRegenerate) { 'y' } else { $defaultChoice }
+        $defaultHint = if ($choiceDefault -ne 'y') { " [Enter: $choiceDefault]" } else { "" }
+        Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "review"; header = $currentHeader }
+        do {
+            $choice = Read-Host "Use this message? (y)es / (e)dit$regenerateOption$scopeOption / (c)ancel$defaultHint"
+            $choice = $choice.ToLower()
+        } while ($choice -notin $validChoices)
+
+        # Enter picks the default answer (yes unless configured)
+        if ([string]::IsNullOrWhiteSpace($choice)) {
+            $choice = $choiceDefault
+        }
+
+        # Process user choice
+        switch ($choice) {
+            {$_ -in @('c', 'cancel')} {
+                Write-Host "Commit cancelled" -ForegroundColor Yellow
+                Set-AICommitMetric -Name "review" -Value "cancelled"
+                return $null
+            }
+
+            {$_ -in @('e', 'edit')} {
+                $current = Edit-AICommitMessage -Message $current
+                $reviewed = "edited"
+
+                # Hand-edited headers get the same checks the prompt asks of the AI
+                $currentHeader = Get-AICommitMessageHeader -Message $current
+                $problems = Test-AICommitHeader -Header $currentHeader
+                while ($problems.Count -gt 0) {
+                    Write-Host "`nHEADER: $currentHeader ($($currentHeader.Length)/$($script:AICommitHeaderMaxLength))" -ForegroundColor Yellow
+                    foreach ($problem in $problems) {
+                        Write-Host "Warning: $problem" -ForegroundColor Yellow
+                    }
+
+                    $fixOption = if ($null -ne $Provider) { " / (f)ix with AI" } else { "" }
+                    do {
+                        $fixChoice = (Read-Host "Header has issues: (r)e-edit$fixOption / (k)eep as is").ToLower()
+                    } while ($fixChoice -notin @('r', 'f', 'k', ''))
+
+                    if ($fixChoice -eq 'r') {
+                        $current = Edit-AICommitMessage -Message $current
+                    } elseif ($fixChoice -eq 'f' -and $null -ne $Provider) {
+                        $shortHeader = Get-AICommitShortHeader -Provider $Provider -Header $currentHeader -Description $current.Body
+                        if ($null -ne $shortHeader) {
+                            Set-AICommitMessageHeader -Message $current -Header $shortHeader
+                        } else {
+                            Write-Host "Could not get a shorter header, keeping yours" -ForegroundColor Yellow
+                        }
+                    } else {
+                        break
+                    }
+                    $currentHeader = Get-AICommitMessageHeader -Message $current
+                    $problems = Test-AICommitHeader -Header $currentHeader
+                }
+                # Loop continues to show the edited message
+            }
+
+            {$_ -in @('r', 'regenerate')} {
+                if ($reviewed -ne "edited") {
+                    $reviewed = "regenerated"
+                }
+                # Often only one half needs another pass; the author's own
+                # header is never replaced
+                if ($KeepHeader) {
+                    do {
+                        $part = (Read-Host "Regenerate the (d)escription / with (f)eedback, or (k)eep (the header is yours)").ToLower()
+                    } while ($part -notin @('d', 'description', 'f', 'feedback', 'k', 'keep', ''))
+                } else {
+                    do {
+                        $part = (Read-Host "Regenerate the (h)eader / (d)escription / (b)oth / with (f)eedback, or (k)eep").ToLower()
+                    } while ($part -notin @('h', 'header', 'd', 'description', 'b', 'both', 'f', 'feedback', 'k', 'keep', ''))
+                }
+
+                if ($part -in @('h', 'header')) {
+                    if (!(Update-AICommitMessagePart -Provider $Provider -Message $current -Diff $Diff -Part "header")) {
+                        Write-Host "Could not get a new header, keeping the current one" -ForegroundColor Yellow
+                    }
+                } elseif ($part -in @('d', 'description')) {
+                    if (!(Update-AICommitMessagePart -Provider $Provider -Message $current -Diff $Diff -Part "description")) {
+                        Write-Host "Could not get a new description, keeping the current one" -ForegroundColor Yellow
+                    }
+                } elseif ($part -in @('f', 'feedback')) {
+                    $instruction = "$(Read-Host "What should change? (e.g. mention the API rename, make it shorter)")".Trim()
+                    if ($instruction) {
+                        $feedback += $instruction
+                        $fullPrompt = if ($Prompt) { $Prompt } else { New-AICommitPrompt -Task "Analyze this git diff and suggest a commit message. " -Diff $Diff -Provider $Provider -Template (Get-AICommitPromptTemplate) }
+                        if (!(Update-AICommitMessageWithFeedback -Provider $Provider -Message $current -Prompt $fullPrompt -Feedback $feedback)) {
+                            Write-Host "Could not get a revised message, keeping the current one" -ForegroundColor Yellow
+                        }
+                    }
+                } elseif ($part -in @('b', 'both')) {
+                    Write-Host "Regenerating the message..." -ForegroundColor Yellow
+                    $fullPrompt = if ($Prompt) { $Prompt } else { New-AICommitPrompt -Task "Analyze this git diff and suggest a commit message. " -Diff $Diff -Provider $Provider -Template (Get-AICommitPromptTemplate) }
+                    $parsed = Get-AICommitSuggestion -Provider $Provider -Prompt $fullPrompt
+                    if ($null -ne $parsed) {
+                        # Footers such as Refs: and Risk: stay
+                        Set-AICommitMessageHeader -Message $current -Header $parsed.Header
+                        $current.Body = $parsed.Description
+                    } else {
+                        Write-Host "Could not get a new message, keeping the current one" -ForegroundColor Yellow
+                    }
+                }
+                # Feedback can rewrite the whole message
+                if ($keptHeader) {
+                    Set-AICommitMessageHeader -Message $current -Header $keptHeader
+                }
+                # Loop continues to show the regenerated message
+            }
+
+            {$_ -in @('s', 'scope')} {
+                Select-AICommitScope -Message $current
+                $reviewed = "edited"
+                # Loop continues to show the message with its new scope
+            }
+
+            {$_ -in @('y', 'yes')} {
+                Set-AICommitMetric -Name "review" -Value $reviewed
+                return $current
+            }
+        }
+    }
+}
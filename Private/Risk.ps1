@@ -0,0 +1,60 @@
+# Optional "Risk:" line for reviewers (AI_COMMIT_RISK_SUMMARY). Heuristics
+# decide whether a diff is high-impact; the model then describes the risk.
+$script:AICommitDefaultSensitivePaths = @(
+    "*auth*", "*login*", "*password*", "*secret*", "*token*", "*crypto*", "*permission*",
+    "*security*", "*migration*", ".github/workflows/*", "Dockerfile", "*.tf"
+)
+
+function Get-AICommitSensitivePaths {
+    # Comma-separated wildcard patterns matched against repository paths
+    $configured = Get-AICommitSetting -Name "AI_COMMIT_SENSITIVE_PATHS"
+    if ([string]::IsNullOrWhiteSpace($configured)) {
+        return $script:AICommitDefaultSensitivePaths
+    }
+    return @($configured -split "," | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+}
+
+function Get-AICommitRiskReasons {
+    # Returns why the diff looks high-impact; empty when it doesn't
+    param([string]$Diff)
+
+    $files = ConvertFrom-AICommitDiff -Diff $Diff
+    $patterns = Get-AICommitSensitivePaths
+    $reasons = @()
+
+    $sensitive = @($files | Where-Object { $path = $_.Path; @($patterns | Where-Object { $path -like $_ }).Count -gt 0 } | ForEach-Object { $_.Path })
+    if ($sensitive.Count -gt 0) {
+        $reasons += "touches security-sensitive paths: $(($sensitive | Select-Object -First 5) -join ', ')"
+    }
+
+    $deleted = @($files | Where-Object { $_.Status -eq 'deleted' })
+    if ($deleted.Count -gt 0) {
+        $reasons += "deletes $($deleted.Count) file(s)"
+    }
+
+    $changedLines = ($files | Measure-Object -Property Added -Sum).Sum + ($files | Measure-Object -Property Removed -Sum).Sum
+    $maxLines = [int](Get-AICommitSetting -Name "AI_COMMIT_RISK_MIN_LINES" -Default 400)
+    if ($changedLines -ge $maxLines) {
+        $reasons += "changes $changedLines lines in $($files.Count) file(s)"
+    }
+
+    return ,$reasons
+}
+
+function Add-AICommitRiskLine {
+    # Adds the model's risk sentence as a Risk: footer, or the heuristic
+    # reasons if the model didn't give one
+    param(
+        [pscustomobject]$Message,
+        [string]$Risk,
+        [string[]]$Reasons
+    )
+
+    if ([string]::IsNullOrWhiteSpace($Risk)) {
+        if ($Reasons.Count -eq 0) {
+            return
+        }
+        $Risk = $Reasons -join "; "
+    }
+    Add-AICommitMessageFooter -Message $Message -Token "Risk" -Value $Risk
+}
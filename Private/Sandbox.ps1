@@ -0,0 +1,204 @@
+# Commands from settings (AI_COMMIT_METRICS_COMMAND, AI_COMMIT_VERIFY_COMMAND,
+# AI_COMMIT_EDITOR) are programs that aicommit runs. .aicommit.env and .aicommit.yaml come with the repository,
+# so a cloned repository could run anything; commands set there go through
+# the user's AI_COMMIT_COMMAND_ALLOW/AI_COMMIT_COMMAND_DENY lists and are
+# confirmed once per repository and command. Commands from the environment
+# or the user config file are the user's own and run as before.
+#
+# Settings that decide where requests, keys and diffs are sent are treated
+# the same way: a repository could otherwise point the user's own API key
+# at a server it controls. Files named by the repository (prompt template,
+# style examples, changelog fragments) must stay inside its work tree, or
+# it could have any file the user can read sent to the provider.
+$script:AICommitRoutingSettings = @(
+    "AI_COMMIT_CUSTOM_BASE_URL", "AI_COMMIT_CUSTOM_API_KEY_ENV",
+    "AI_COMMIT_OPENROUTER_URL", "AI_COMMIT_OLLAMA_URL",
+    "AI_COMMIT_OTLP_ENDPOINT", "AI_COMMIT_OTLP_HEADERS", "AI_COMMIT_DIGEST_WEBHOOK"
+)
+# Choosing a provider only routes requests elsewhere for "custom"
+$script:AICommitProviderSettings = @("AI_COMMIT_PROVIDER", "AI_COMMIT_PROVIDER_FALLBACKS")
+# Answers given during this run, so each setting is asked about once
+$script:AICommitRepoSettingDecisions = @{}
+
+function Get-AICommitSettingSource {
+    # Where Get-AICommitSetting would take the value from: repo, team,
+    # environment, remembered, user or $null
+    param([string]$Name)
+
+    if ($script:AICommitRepoSettings.Contains($Name)) {
+        return "repo"
+    }
+    if (![string]::IsNullOrWhiteSpace([Environment]::GetEnvironmentVariable($Name))) {
+        return "environment"
+    }
+    if ($script:AICommitTeamSettings.Contains($Name)) {
+        return "team"
+    }
+    if ($script:AICommitRememberedSettings.Contains($Name)) {
+        return "remembered"
+    }
+    if ($script:AICommitUserSettings.Contains($Name)) {
+        return "user"
+    }
+    return $null
+}
+
+function Get-AICommitCommandPatterns {
+    # The allow and deny lists are only read from the environment and the
+    # user config file; a repository can't allow its own commands
+    param([string]$Name)
+
+    $value = [Environment]::GetEnvironmentVariable($Name)
+    if ([string]::IsNullOrWhiteSpace($value) -and $script:AICommitUserSettings.Contains($Name)) {
+        $value = $script:AICommitUserSettings[$Name]
+    }
+    return ,@("$value" -split ',' | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+}
+
+function Test-AICommitCommandAllowed {
+    # $true when the command in setting -Name may run. Repository commands
+    # matching AI_COMMIT_COMMAND_DENY never run, ones matching
+    # AI_COMMIT_COMMAND_ALLOW (wildcards, comma-separated) always do and the
+    # rest are asked about; the answer is remembered per repository.
+    param(
+        [string]$Name,
+        [string]$Command
+    )
+
+    $source = Get-AICommitSettingSource -Name $Name
+    if ($source -notin @("repo", "team")) {
+        return $true
+    }
+    $file = if ($source -eq "repo") { ".aicommit.env" } else { ".aicommit.yaml" }
+
+    foreach ($pattern in (Get-AICommitCommandPatterns -Name "AI_COMMIT_COMMAND_DENY")) {
+        if ($Command -like $pattern) {
+            Write-Host "Warning: Not running $Name from $file - it matches AI_COMMIT_COMMAND_DENY ($pattern)" -ForegroundColor Yellow
+            return $false
+        }
+    }
+    foreach ($pattern in (Get-AICommitCommandPatterns -Name "AI_COMMIT_COMMAND_ALLOW")) {
+        if ($Command -like $pattern) {
+            return $true
+        }
+    }
+
+    # Approvals are kept per repository root and exact command text, so a
+    # changed command is asked about again
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    $key = Get-AICommitValueHash -Value "$root`n$Name`n$Command"
+    $approvalsPath = Get-AICommitDataPath "approved-commands.txt"
+    $approved = if (Test-Path $approvalsPath) { @(Get-Content -Path $approvalsPath -Encoding UTF8) } else { @() }
+    if ($approved -contains $key) {
+        return $true
+    }
+
+    if ([Console]::IsInputRedirected) {
+        Write-Host "Warning: Not running $Name from $file without confirmation - add it to AI_COMMIT_COMMAND_ALLOW to run it unattended" -ForegroundColor Yellow
+        return $false
+    }
+    Write-Host "`n$file in this repository sets $Name to run:" -ForegroundColor Yellow
+    Write-Host "  $Command" -ForegroundColor White
+    $answer = "$(Read-Host "Run this command? (y)es once, (a)lways in this repository, (N)o")".Trim().ToLower()
+    if ($answer -in @('a', 'always')) {
+        Add-Content -Path $approvalsPath -Value $key -Encoding UTF8
+        return $true
+    }
+    if ($answer -in @('y', 'yes')) {
+        return $true
+    }
+    Write-Host "Skipped $Name" -ForegroundColor Yellow
+    return $false
+}
+
+function Test-AICommitRepoSettingTrusted {
+    # $true when a value from the repository's -File may be used. Endpoint,
+    # webhook and key routing settings (and picking the custom provider)
+    # need the user's confirmation, remembered per repository and value;
+    # without it Get-AICommitSetting falls back to the user's own settings.
+    param(
+        [string]$Name,
+        [string]$Value,
+        [string]$File
+    )
+
+    $routing = $script:AICommitRoutingSettings -contains $Name
+    if (!$routing -and $script:AICommitProviderSettings -contains $Name) {
+        $routing = "$Value" -match '(^|[\s,])custom([\s,]|$)'
+    }
+    if (!$routing) {
+        return $true
+    }
+
+    $decisionKey = "$File`n$Name`n$Value"
+    if ($script:AICommitRepoSettingDecisions.ContainsKey($decisionKey)) {
+        return $script:AICommitRepoSettingDecisions[$decisionKey]
+    }
+
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    $key = Get-AICommitValueHash -Value "$root`n$Name`n$Value"
+    $approvalsPath = Get-AICommitDataPath "approved-settings.txt"
+    $approved = if (Test-Path $approvalsPath) { @(Get-Content -Path $approvalsPath -Encoding UTF8) } else { @() }
+    $trusted = $approved -contains $key
+    if (!$trusted) {
+        if ([Console]::IsInputRedirected) {
+            Write-Host "Warning: Ignoring $Name from $File without confirmation - set it in your environment or config file instead" -ForegroundColor Yellow
+        } else {
+            Write-Host "`n$File in this repository sets $Name, which decides where your requests and API key are sent:" -ForegroundColor Yellow
+            Write-Host "  $Name=$Value" -ForegroundColor White
+            $answer = "$(Read-Host "Use this setting? (y)es once, (a)lways in this repository, (N)o")".Trim().ToLower()
+            if ($answer -in @('a', 'always')) {
+                Add-Content -Path $approvalsPath -Value $key -Encoding UTF8
+                $trusted = $true
+            } elseif ($answer -in @('y', 'yes')) {
+                $trusted = $true
+            } else {
+                Write-Host "Ignoring $Name from $File" -ForegroundColor Yellow
+            }
+        }
+    }
+    $script:AICommitRepoSettingDecisions[$decisionKey] = $trusted
+    return $trusted
+}
+
+function Test-AICommitRepoPathAllowed {
+    # $true when -Path, named by setting -Name, may be read or written. Paths
+    # set by the repository (or the repository's own default file, without
+    # -Name) must resolve inside the work tree and not through a symbolic
+    # link; the user's own settings can point anywhere.
+    param(
+        [string]$Name,
+        [string]$Path
+    )
+
+    if ($Name -and (Get-AICommitSettingSource -Name $Name) -notin @("repo", "team")) {
+        return $true
+    }
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    if (!$root) {
+        return $true
+    }
+    $root = [System.IO.Path]::GetFullPath($root).TrimEnd('/', '\')
+    # .NET resolves relative paths against the process directory, not
+    # PowerShell's current location
+    if (![System.IO.Path]::IsPathRooted($Path)) {
+        $Path = Join-Path (Get-Location).ProviderPath $Path
+    }
+    $full = [System.IO.Path]::GetFullPath($Path)
+    $comparison = if ([System.IO.Path]::DirectorySeparatorChar -eq '\') { [StringComparison]::OrdinalIgnoreCase } else { [StringComparison]::Ordinal }
+    $label = if ($Name) { $Name } else { $Path }
+
+    if (!$full.StartsWith($root + [System.IO.Path]::DirectorySeparatorChar, $comparison)) {
+        Write-Host "Warning: Ignoring $label from this repository - $full is outside the repository" -ForegroundColor Yellow
+        return $false
+    }
+    # A committed symbolic link could still lead out of the work tree
+    for ($current = $full; $current.Length -gt $root.Length; $current = [System.IO.Path]::GetDirectoryName($current)) {
+        $item = Get-Item -LiteralPath $current -Force -ErrorAction SilentlyContinue
+        if ($null -ne $item -and $item.LinkType) {
+            Write-Host "Warning: Ignoring $label from this repository - $current is a link" -ForegroundColor Yellow
+            return $false
+        }
+    }
+    return $true
+}
@@ -0,0 +1,76 @@
+# Scope vocabulary learned from history (AI_COMMIT_SCOPE_LEARNING): the
+# scopes of recent type(scope): headers, ranked by use, so generated scopes
+# follow the project's conventions instead of new inventions. The model gets
+# the list, and the review offers it in the scope picker.
+
+# Learned once per run; history doesn't change while a message is reviewed
+$script:AICommitScopeVocabulary = $null
+
+function Get-AICommitScopeVocabulary {
+    # Scopes ranked by how often they were used, as @{ Name; Count }.
+    # Empty when learning is off or history has no scoped headers.
+    $setting = "$(Get-AICommitSetting -Name "AI_COMMIT_SCOPE_LEARNING" -Default "true")".Trim().ToLower()
+    if ($setting -in @('false', 'no', 'off', '0')) {
+        return ,@()
+    }
+    if ($null -ne $script:AICommitScopeVocabulary) {
+        return ,$script:AICommitScopeVocabulary
+    }
+
+    $history = [int](Get-AICommitSetting -Name "AI_COMMIT_SCOPE_HISTORY" -Default 300)
+    $counts = @{}
+    foreach ($subject in @(git log -n $history --no-merges --format=%s 2>$null)) {
+        if ($subject -match '^[a-z]+\((?<scope>[^)]+)\)!?:') {
+            $scope = $Matches.scope.Trim()
+            $counts[$scope] = 1 + $(if ($counts.ContainsKey($scope)) { $counts[$scope] } else { 0 })
+        }
+    }
+    $script:AICommitScopeVocabulary = @($counts.Keys | Sort-Object -Property @{ Expression = { $counts[$_] }; Descending = $true }, @{ Expression = { $_ } } | ForEach-Object {
+        @{ Name = $_; Count = $counts[$_] }
+    })
+    return ,$script:AICommitScopeVocabulary
+}
+
+function Get-AICommitScopeRules {
+    # Requirement line listing the project's scopes; empty without any
+    $vocabulary = Get-AICommitScopeVocabulary
+    if ($vocabulary.Count -eq 0) {
+        return ""
+    }
+    $top = ($vocabulary | Select-Object -First 20 | ForEach-Object { $_.Name }) -join ", "
+    return "`n- If the header has a type(scope): prefix, take the scope from those this project already uses, most common first: $top. Only use a new scope if none of them fits"
+}
+
+function Select-AICommitScope {
+    # The review's scope picker: a number picks a learned scope, text sets a
+    # new one, - removes it, Enter keeps the current one
+    param([pscustomobject]$Message)
+
+    if ([string]::IsNullOrWhiteSpace($Message.Type)) {
+        Write-Host "The header has no type(scope): prefix to set a scope on" -ForegroundColor Yellow
+        return
+    }
+    $vocabulary = @(Get-AICommitScopeVocabulary | Select-Object -First 15)
+    if ($vocabulary.Count -gt 0) {
+        Write-Host "`n--- SCOPES USED IN THIS PROJECT ---" -ForegroundColor Cyan
+        for ($i = 0; $i -lt $vocabulary.Count; $i++) {
+            $marker = if ($vocabulary[$i].Name -eq $Message.Scope) { " *" } else { "" }
+            Write-Host ("{0,3}. {1} ({2}){3}" -f ($i + 1), $vocabulary[$i].Name, $vocabulary[$i].Count, $marker) -ForegroundColor White
+        }
+        Write-Host "--- END SCOPES ---" -ForegroundColor Cyan
+    } else {
+        Write-Host "No scopes found in recent history" -ForegroundColor Yellow
+    }
+
+    $answer = "$(Read-Host "Scope (number or name, - for none, Enter keeps '$($Message.Scope)')")".Trim()
+    if ($answer -eq "") {
+        return
+    }
+    if ($answer -eq "-") {
+        $Message.Scope = $null
+    } elseif ($answer -match '^\d+$' -and [int]$answer -ge 1 -and [int]$answer -le $vocabulary.Count) {
+        $Message.Scope = $vocabulary[[int]$answer - 1].Name
+    } else {
+        $Message.Scope = $answer
+    }
+}
@@ -0,0 +1,138 @@
+function Invoke-AICommitSelfTest {
+    $script:AICommitSelfTestFailures = 0
+    function Assert-SelfTest {
+        param([string]$Name, [bool]$Condition)
+        if ($Condition) {
+            Write-Host "  PASS  $Name" -ForegroundColor Green
+        } else {
+            Write-Host "  FAIL  $Name" -ForegroundColor Red
+            $script:AICommitSelfTestFailures++
+        }
+    }
+
+    if (!(Get-Command git -ErrorAction SilentlyContinue)) {
+        Write-Host "Error: git was not found on PATH" -ForegroundColor Red
+        return
+    }
+
+    $sandbox = Join-Path ([System.IO.Path]::GetTempPath()) ("aicommit-selftest-" + [guid]::NewGuid().ToString("N").Substring(0, 8))
+    New-Item -ItemType Directory -Path $sandbox -Force | Out-Null
+    Write-Host "Running self-test in $sandbox" -ForegroundColor Yellow
+
+    # The sandbox must not pick up the caller's repo settings
+    $savedRepoSettings = $script:AICommitRepoSettings
+    $savedTeamSettings = $script:AICommitTeamSettings
+    $savedRememberedSettings = $script:AICommitRememberedSettings
+    $savedUserSettings = $script:AICommitUserSettings
+    # The end-to-end run keeps its config, caches and repository list in
+    # the sandbox too
+    $savedHome = $env:AI_COMMIT_HOME
+    $script:AICommitRepoSettings = @{}
+    $script:AICommitTeamSettings = @{}
+    $script:AICommitRememberedSettings = @{}
+    Push-Location $sandbox
+    try {
+        git init -q . 2>&1 | Out-Null
+        git config user.name "aicommit selftest"
+        git config user.email "selftest@aicommit.invalid"
+        git config commit.gpgsign false
+        Set-Content -Path "readme.txt" -Value "first line" -Encoding UTF8
+        git add readme.txt 2>&1 | Out-Null
+        git commit -q -m "Initial commit" 2>&1 | Out-Null
+        Assert-SelfTest "create sandbox repository" ($LASTEXITCODE -eq 0)
+
+        # One modified and one new file, plus a settings file that must stay private
+        Add-Content -Path "readme.txt" -Value "second line" -Encoding UTF8
+        Set-Content -Path "notes.txt" -Value "a new file" -Encoding UTF8
+        Set-Content -Path ".aicommit.env" -Value "AI_COMMIT_MODEL=fake" -Encoding UTF8
+
+        $fullDiff = Get-AICommitFullDiff
+        Assert-SelfTest "diff includes modified file" ($fullDiff -match "readme\.txt")
+        Assert-SelfTest "diff includes new file" ($fullDiff -match "diff --git a/notes\.txt b/notes\.txt")
+        $untracked = @(git ls-files --others --exclude-standard)
+        Assert-SelfTest "index untouched after diffing" ($untracked -contains "notes.txt")
+        Assert-SelfTest "diff excludes .aicommit.env" ($fullDiff -notmatch "\.aicommit\.env")
+
+        $prompt = New-AICommitPrompt -Task "Analyze this git diff and suggest a commit message. " -Diff $fullDiff
+        Assert-SelfTest "prompt contains format contract and diff" ($prompt -match "HEADER: " -and $prompt -match "notes\.txt")
+
+        $fakeProvider = @{ Model = "fake"; Carrier = "fake"; ApiKey = $null }
+        $suggestion = Invoke-AICommitCompletion -Provider $fakeProvider -Prompt $prompt
+        $parsed = ConvertFrom-AICommitSuggestion -Suggestion $suggestion
+        Assert-SelfTest "parse header" ($parsed.Header -eq "Update 2 files")
+        Assert-SelfTest "parse description" ($parsed.Description -match "readme\.txt" -and $parsed.Description -match "notes\.txt")
+
+        $structured = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+        $structured.Tickets.Add("ABC-123")
+        Add-AICommitMessageFooter -Message $structured -Token "Risk" -Value "none"
+        $roundTrip = ConvertFrom-AICommitEditorText -Text "# comment`n$(Format-AICommitMessage -Message $structured)`n# trailing comment"
+        Assert-SelfTest "message round-trip keeps body, footers and tickets" ((Format-AICommitMessage -Message $roundTrip) -eq (Format-AICommitMessage -Message $structured) -and $roundTrip.Tickets -contains "ABC-123")
+        $conventional = New-AICommitMessage -Header "feat(api)!: Drop v1 endpoints" -Description ""
+        Assert-SelfTest "parse conventional header" ($conventional.Type -eq "feat" -and $conventional.Scope -eq "api" -and $conventional.Breaking -and $conventional.Subject -eq "Drop v1 endpoints")
+
+        $message = Format-AICommitMessage -Message (New-AICommitMessage -Header $parsed.Header -Description $parsed.Description)
+        Add-AICommitChanges
+        $staged = @(git diff --cached --name-only)
+        Assert-SelfTest "stage changes" ($staged -contains "readme.txt" -and $staged -contains "notes.txt")
+        Assert-SelfTest "never stage .aicommit.env" ($staged -notcontains ".aicommit.env")
+
+        $committed = New-AICommitCommit -Message $message
+        Assert-SelfTest "commit" $committed
+        $subject = "$(git log -1 --format=%s)".Trim()
+        $body = ((git log -1 --format=%b) -join "`n").Trim()
+        Assert-SelfTest "commit subject matches header" ($subject -eq $parsed.Header)
+        Assert-SelfTest "commit body matches description" ($body -eq $parsed.Description)
+        $remaining = @(git status --porcelain | Where-Object { $_ -notmatch "\.aicommit\.env" })
+        Assert-SelfTest "working tree clean after commit" ($remaining.Count -eq 0)
+
+        # The whole command as a user runs it: the fake provider's message is
+        # auto-accepted on a branch that isn't protected, and the sandbox's
+        # .aicommit.env switches off whatever the user's settings would add
+        git checkout -q -b selftest 2>&1 | Out-Null
+        Add-Content -Path (Join-Path (git rev-parse --git-dir) "info/exclude") -Value @(".aicommit.env", ".aicommit-home/") -Encoding UTF8
+        Set-Content -Path ".aicommit.env" -Encoding UTF8 -Value @(
+            "AI_COMMIT_MODEL=fake"
+            "AI_COMMIT_AUTO_ACCEPT=true"
+            "AI_COMMIT_STYLE_PRESET=none"
+            "AI_COMMIT_SUGGESTIONS=1"
+            "AI_COMMIT_MIXED_CHANGES=all"
+            "AI_COMMIT_SPLIT=never"
+            "AI_COMMIT_DUPLICATE_CHECK=false"
+            "AI_COMMIT_RISK_SUMMARY=false"
+            "AI_COMMIT_REQUIRE_TICKET=false"
+            "AI_COMMIT_BRANCH_TICKET=false"
+            "AI_COMMIT_CHANGELOG_FRAGMENTS=false"
+            "AI_COMMIT_TRAILERS="
+            "AI_COMMIT_CO_AUTHORS="
+            "AI_COMMIT_METRICS_COMMAND="
+        )
+        $env:AI_COMMIT_HOME = Join-Path $sandbox ".aicommit-home"
+        Add-Content -Path "readme.txt" -Value "third line" -Encoding UTF8
+        Set-Content -Path "changes.txt" -Value "another new file" -Encoding UTF8
+        $before = "$(git rev-parse HEAD)".Trim()
+        $null = aicommit -provider fake -noVerify
+        Assert-SelfTest "aicommit commits end to end" ("$(git rev-parse HEAD)".Trim() -ne $before)
+        Assert-SelfTest "aicommit uses the generated header" ("$(git log -1 --format=%s)".Trim() -eq "Update 2 files")
+        $remaining = @(git status --porcelain)
+        Assert-SelfTest "aicommit leaves the working tree clean" ($remaining.Count -eq 0)
+    }
+    catch {
+        Write-Host "  FAIL  unexpected error: $($_.Exception.Message)" -ForegroundColor Red
+        $script:AICommitSelfTestFailures++
+    }
+    finally {
+        Pop-Location
+        $script:AICommitRepoSettings = $savedRepoSettings
+        $script:AICommitTeamSettings = $savedTeamSettings
+        $script:AICommitRememberedSettings = $savedRememberedSettings
+        $script:AICommitUserSettings = $savedUserSettings
+        $env:AI_COMMIT_HOME = $savedHome
+        Remove-Item -Path $sandbox -Recurse -Force -ErrorAction SilentlyContinue
+    }
+
+    if ($script:AICommitSelfTestFailures -eq 0) {
+        Write-Host "`nSelf-test passed" -ForegroundColor Green
+    } else {
+        Write-Host "`nSelf-test failed: $($script:AICommitSelfTestFailures) check(s) failed" -ForegroundColor Red
+    }
+}
@@ -0,0 +1,190 @@
+# 'aicommit series': better messages for every commit of a patch series
+# before it is sent, kernel style. Each commit gets a proposed message from
+# its own diff, a table shows old and new subjects, and the approved ones
+# are applied with a scripted interactive rebase. Trailers of the old
+# message (Signed-off-by:, Reviewed-by:, ...) are kept.
+
+function Get-AICommitSeriesCommits {
+    # The commits of the range, oldest first, as @{ Hash; Message }; $null
+    # (after reporting why) when the range can't be rewritten
+    param([string]$Range)
+
+    $from, $to = $Range -split '\.\.', 2
+    if ([string]::IsNullOrWhiteSpace($from)) {
+        Write-Host "Error: Use -range <base>..HEAD, e.g. -range origin/main..HEAD" -ForegroundColor Red
+        return $null
+    }
+    if ([string]::IsNullOrWhiteSpace($to)) {
+        $to = "HEAD"
+    }
+    # Only the current branch can be rewritten by rebase
+    $toHash = "$(git rev-parse -q --verify "$to^{commit}" 2>$null)".Trim()
+    if ($toHash -ne "$(git rev-parse HEAD)".Trim()) {
+        Write-Host "Error: The range must end at HEAD (check out the series' branch first)" -ForegroundColor Red
+        return $null
+    }
+    git merge-base --is-ancestor $from HEAD 2>$null
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Error: '$from' is not an ancestor of HEAD" -ForegroundColor Red
+        return $null
+    }
+    if (@(git rev-list --merges "$from..HEAD" | Where-Object { $_ }).Count -gt 0) {
+        Write-Host "Error: The series contains merge commits, which can't be reworded by a rebase of the range" -ForegroundColor Red
+        return $null
+    }
+
+    $commits = @()
+    foreach ($hash in @(git rev-list --reverse "$from..HEAD" | Where-Object { $_ })) {
+        $commits += [pscustomobject]@{
+            Hash    = $hash.Trim()
+            Message = ((git log -1 --format=%B $hash) -join "`n").Trim()
+        }
+    }
+    return ,$commits
+}
+
+function Invoke-AICommitSeries {
+    param([string]$Range)
+
+    if ([string]::IsNullOrWhiteSpace($Range)) {
+        $base = Get-AICommitDefaultBase
+        $mergeBase = "$(git merge-base $base HEAD 2>$null)".Trim()
+        if (!$mergeBase) {
+            Write-Host "Error: Could not find where the series starts; pass -range <base>..HEAD" -ForegroundColor Red
+            return
+        }
+        $Range = "$mergeBase..HEAD"
+    }
+    $commits = Get-AICommitSeriesCommits -Range $Range
+    if ($null -eq $commits) {
+        return
+    }
+    if ($commits.Count -eq 0) {
+        Write-Host "No commits in $Range" -ForegroundColor Green
+        return
+    }
+
+    # Rebase needs a clean tree
+    git diff --quiet HEAD -- 2>$null
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Error: Commit or stash your changes first; rewording the series needs a clean working tree" -ForegroundColor Red
+        return
+    }
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    $subjects = ($commits | ForEach-Object { "- $(($_.Message -split "`n")[0])" }) -join "`n"
+    $proposals = @()
+    for ($i = 0; $i -lt $commits.Count; $i++) {
+        $commit = $commits[$i]
+        Write-Host "`nPatch $($i + 1)/$($commits.Count): $(($commit.Message -split "`n")[0])" -ForegroundColor Cyan
+        $diff = Get-AICommitPromptDiff -Diff ((git show -M -C --format= $commit.Hash -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")
+        $context = "This is patch $($i + 1) of $($commits.Count) in a series that will be sent for review. The series has these patches:`n$subjects`n`nThe current message of this patch is:`n$($commit.Message)`n`nImprove it: describe only this patch, say why it is needed, keep what is right about the current message and keep facts you can't see in the diff."
+        $prompt = New-AICommitPrompt -Task "Analyze this git diff and suggest a better commit message for it. " -Context $context -Diff $diff -Provider $provider -Template (Get-AICommitPromptTemplate)
+        $parsed = Get-AICommitSuggestion -Provider $provider -Prompt $prompt
+        if ($null -eq $parsed) {
+            Write-Host "Warning: No suggestion for this patch, keeping its message" -ForegroundColor Yellow
+            $proposals += $null
+            continue
+        }
+
+        # The old trailers stay: sign-offs and review tags must survive
+        $old = ConvertFrom-AICommitMessageText -Text $commit.Message
+        $new = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+        foreach ($footer in $old.Footers) {
+            Add-AICommitMessageFooter -Message $new -Token $footer.Token -Value $footer.Value
+        }
+        foreach ($ticketRef in $old.Tickets) {
+            $new.Tickets.Add($ticketRef)
+        }
+        $proposals += [pscustomobject]@{ Message = $new; Diff = $diff; Prompt = $prompt }
+    }
+
+    while ($true) {
+        Write-Host "`n--- SERIES ---" -ForegroundColor Cyan
+        for ($i = 0; $i -lt $commits.Count; $i++) {
+            $oldSubject = ($commits[$i].Message -split "`n")[0]
+            Write-Host ("{0,3}. {1} {2}" -f ($i + 1), $commits[$i].Hash.Substring(0, 7), $oldSubject) -ForegroundColor Gray
+            $newSubject = if ($null -ne $proposals[$i]) { Get-AICommitMessageHeader -Message $proposals[$i].Message } else { "(unchanged)" }
+            Write-Host "             -> $newSubject" -ForegroundColor White
+        }
+        Write-Host "--- END SERIES ---`n" -ForegroundColor Cyan
+
+        Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "series" }
+        $answer = "$(Read-Host "Apply (a)ll / only some (numbers, e.g. 1,3-4) / (r)eview <n> / (c)ancel")".Trim().ToLower()
+        if ($answer -in @('c', 'cancel')) {
+            Write-Host "Series left unchanged" -ForegroundColor Yellow
+            return
+        }
+        if ($answer -match '^r\s*(?<number>\d+)$') {
+            $index = [int]$Matches.number - 1
+            if ($index -lt 0 -or $index -ge $commits.Count -or $null -eq $proposals[$index]) {
+                Write-Host "No proposal with that number" -ForegroundColor Yellow
+                continue
+            }
+            $reviewed = Read-AICommitMessage -Message $proposals[$index].Message -Provider $provider -Diff $proposals[$index].Diff -Prompt $proposals[$index].Prompt
+            if ($null -eq $reviewed) {
+                # Cancelling the review drops the proposal
+                $proposals[$index] = $null
+            } else {
+                $proposals[$index].Message = $reviewed
+            }
+            continue
+        }
+
+        $selected = @()
+        if ($answer -in @('a', 'all', '')) {
+            $selected = @(0..($commits.Count - 1))
+        } else {
+            foreach ($part in $answer -split '[,\s]+' | Where-Object { $_ }) {
+                if ($part -match '^(\d+)-(\d+)$') {
+                    $selected += @([int]$Matches[1]..[int]$Matches[2] | ForEach-Object { $_ - 1 })
+                } elseif ($part -match '^\d+$') {
+                    $selected += [int]$part - 1
+                }
+            }
+        }
+        $selected = @($selected | Where-Object { $_ -ge 0 -and $_ -lt $commits.Count -and $null -ne $proposals[$_] } | Select-Object -Unique)
+        if ($selected.Count -eq 0) {
+            Write-Host "Nothing selected to apply" -ForegroundColor Yellow
+            continue
+        }
+        break
+    }
+
+    $pushed = @(git branch -r --contains $commits[0].Hash 2>$null | Where-Object { $_ -and $_ -notmatch ' -> ' })
+    if ($pushed.Count -gt 0) {
+        Write-Host "Warning: Part of the series is already on $(($pushed | ForEach-Object { $_.Trim() }) -join ', '); rewording needs a force push" -ForegroundColor Yellow
+    }
+
+    # A prepared todo list: every commit picked as it is, and the approved
+    # ones amended with their new message right after
+    # -gpgSign signs the rewritten commits too
+    $signArgument = if ($script:AICommitSignCommits) { "-S " } else { "" }
+    $todo = New-Object System.Text.StringBuilder
+    for ($i = 0; $i -lt $commits.Count; $i++) {
+        [void]$todo.Append("pick $($commits[$i].Hash)`n")
+        if ($selected -contains $i) {
+            $messageFile = New-AICommitTempFile -Prefix "series" -Extension ".msg"
+            [System.IO.File]::WriteAllText($messageFile, (Format-AICommitMessage -Message $proposals[$i].Message), (New-Object System.Text.UTF8Encoding $false))
+            [void]$todo.Append("exec git commit --amend --allow-empty -q $signArgument-F `"$($messageFile -replace '\\', '/')`"`n")
+        }
+    }
+    $todoFile = New-AICommitTempFile -Prefix "series" -Extension ".todo"
+    [System.IO.File]::WriteAllText($todoFile, $todo.ToString(), (New-Object System.Text.UTF8Encoding $false))
+
+    $base = "$(git rev-parse "$($commits[0].Hash)~1" 2>$null)".Trim()
+    $onto = if ($base) { @($base) } else { @("--root") }
+    Write-Host "Rewording $($selected.Count) commit(s)..." -ForegroundColor Yellow
+    $output = git -c "sequence.editor=cp `"$($todoFile -replace '\\', '/')`"" rebase -i @onto 2>&1
+    if ($LASTEXITCODE -ne 0) {
+        git rebase --abort 2>&1 | Out-Null
+        Write-Host "Error: The rebase failed and was undone - $(($output | Out-String).Trim())" -ForegroundColor Red
+        return
+    }
+    Write-Host "Reworded $($selected.Count) of $($commits.Count) commit(s)" -ForegroundColor Green
+    git log --oneline -n $commits.Count | Out-Host
+}
@@ -0,0 +1,415 @@
+# Splitting one change into several commits, e.g. separate frontend and
+# backend commits in a monorepo. Files are grouped by AI_COMMIT_SPLIT_GROUPS
+# ("name=pattern,pattern;name=pattern", matched with -like against the
+# root-relative path), or by language when it isn't set. Every group gets
+# its own generated message, review and commit. 'aicommit -split' has the
+# model group the hunks into logical commits instead.
+$script:AICommitDefaultSplitGroups = "frontend=*.js,*.jsx,*.mjs,*.ts,*.tsx,*.vue,*.svelte,*.css,*.scss,*.less,*.html;backend=*.py,*.go,*.java,*.kt,*.cs,*.rb,*.php,*.rs,*.c,*.cpp,*.h,*.sql,*.ps1,*.psm1,*.psd1;docs=*.md,*.rst,*.adoc"
+
+function Get-AICommitSplitGroups {
+    # One group per matching area, in the order they are configured; files
+    # matching none end up in "other". Each group has Name, Paths and Diff.
+    param([string]$Diff)
+
+    $definitions = New-Object System.Collections.Generic.List[object]
+    $setting = Get-AICommitSetting -Name "AI_COMMIT_SPLIT_GROUPS" -Default $script:AICommitDefaultSplitGroups
+    foreach ($definition in "$setting" -split ';') {
+        $name, $patterns = $definition -split '=', 2
+        if ([string]::IsNullOrWhiteSpace($name) -or [string]::IsNullOrWhiteSpace($patterns)) {
+            continue
+        }
+        $definitions.Add(@{
+            Name     = $name.Trim()
+            Patterns = @($patterns -split ',' | ForEach-Object { $_.Trim() } | Where-Object { $_ })
+        })
+    }
+
+    $grouped = [ordered]@{}
+    foreach ($file in ConvertFrom-AICommitDiff -Diff $Diff) {
+        $match = $definitions | Where-Object { $path = $file.Path; @($_.Patterns | Where-Object { $path -like $_ }).Count -gt 0 } | Select-Object -First 1
+        $name = if ($null -ne $match) { $match.Name } else { "other" }
+        if (!$grouped.Contains($name)) {
+            $grouped[$name] = New-Object System.Collections.Generic.List[object]
+        }
+        $grouped[$name].Add($file)
+    }
+
+    # Configured order first, "other" last
+    $order = @($definitions | ForEach-Object { $_.Name }) + @("other")
+    $groups = @()
+    foreach ($name in $order) {
+        if (!$grouped.Contains($name)) {
+            continue
+        }
+        $files = $grouped[$name]
+        # A rename moves the old path too
+        $paths = @($files | ForEach-Object { $_.Path; if ($_.OldPath -ne $_.Path) { $_.OldPath } } | Select-Object -Unique)
+        $groups += [pscustomobject]@{
+            Name       = $name
+            Paths      = $paths
+            Diff       = Format-AICommitDiff -Files $files
+            ByHunk     = $false
+            Suggestion = $null
+        }
+    }
+    return ,$groups
+}
+
+function Select-AICommitSplit {
+    # Offers to split a diff that spans several areas. AI_COMMIT_SPLIT: ask
+    # (default), always or never. Returns the groups, or $null for one commit.
+    param([string]$Diff)
+
+    $mode = "$(Get-AICommitSetting -Name "AI_COMMIT_SPLIT" -Default "ask")".Trim().ToLower()
+    if ($mode -in @('never', 'false', 'no', 'off', '0')) {
+        return $null
+    }
+
+    $groups = Get-AICommitSplitGroups -Diff $Diff
+    # Only worth it when at least two real areas are touched
+    if (@($groups | Where-Object { $_.Name -ne "other" }).Count -lt 2) {
+        return $null
+    }
+
+    Write-Host "`n--- SUGGESTED SPLIT ---" -ForegroundColor Cyan
+    for ($i = 0; $i -lt $groups.Count; $i++) {
+        Write-Host ("{0}. {1} ({2} file(s)): {3}" -f ($i + 1), $groups[$i].Name, $groups[$i].Paths.Count, ($groups[$i].Paths -join ', ')) -ForegroundColor White
+    }
+    Write-Host "--- END SPLIT ---`n" -ForegroundColor Cyan
+
+    if ($mode -ne "always") {
+        Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "split"; groups = @($groups | ForEach-Object { $_.Name }) }
+        do {
+            $choice = (Read-Host "This change spans $(@($groups | ForEach-Object { $_.Name }) -join ', '). (s)plit into $($groups.Count) commits / (o)ne commit").ToLower()
+        } while ($choice -notin @('s', 'split', 'o', 'one', ''))
+        if ($choice -notin @('s', 'split')) {
+            return $null
+        }
+    }
+    return $groups
+}
+
+function Invoke-AICommitSplit {
+    # Generates, reviews and commits each group in turn. A group whose
+    # review is cancelled is skipped and stays uncommitted. Returns the
+    # number of commits made.
+    param(
+        [object[]]$Groups,
+        [hashtable]$Provider,
+        [string]$Ticket,
+        [string[]]$CoAuthors,
+        [switch]$Breaking
+    )
+
+    # Hunk groups are staged one by one onto an index that matches HEAD;
+    # the working tree keeps every change
+    if (@($Groups | Where-Object { $_.ByHunk }).Count -gt 0) {
+        git reset -q 2>&1 | Out-Null
+    }
+
+    $committed = 0
+    for ($i = 0; $i -lt $Groups.Count; $i++) {
+        $group = $Groups[$i]
+        Write-Host "`n=== Commit $($i + 1) of $($Groups.Count): $($group.Name) ===" -ForegroundColor Cyan
+
+        $diff = Get-AICommitPromptDiff -Diff $group.Diff
+        $riskReasons = @()
+        if (Test-AICommitSettingEnabled -Name "AI_COMMIT_RISK_SUMMARY") {
+            $riskReasons = Get-AICommitRiskReasons -Diff $diff
+        }
+        $breakingReasons = Get-AICommitBreakingCheck -Diff $diff -Force:$Breaking
+        $prompt = New-AICommitPrompt -Task "Analyze this git diff and suggest a commit message. It is the $($group.Name) part of a larger change; the other parts are committed separately, so describe only this part. $(Get-AICommitTicketHeaderRule -Ticket $Ticket)" -Diff $diff -RiskReasons $riskReasons -BreakingReasons $breakingReasons -Provider $Provider -Template (Get-AICommitPromptTemplate)
+        # The split plan's message is used unless a risk or breaking line is
+        # asked for
+        $parsed = if ($null -ne $group.Suggestion -and $riskReasons.Count -eq 0 -and $breakingReasons.Count -eq 0) {
+            $group.Suggestion
+        } else {
+            Get-AICommitSuggestion -Provider $Provider -Prompt $prompt
+        }
+        if ($null -eq $parsed) {
+            Write-Host "Stopping the split; the remaining changes are not committed" -ForegroundColor Yellow
+            break
+        }
+
+        $message = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+        if ($riskReasons.Count -gt 0) {
+            Add-AICommitRiskLine -Message $message -Risk $parsed.Risk -Reasons $riskReasons
+        }
+        if ($breakingReasons.Count -gt 0) {
+            Add-AICommitBreakingFooter -Message $message -Breaking $parsed.Breaking -Force:$Breaking
+        }
+        Add-AICommitTicketReference -Message $message -Ticket $Ticket
+
+        $reviewed = Read-AICommitMessage -Message $message -Provider $Provider -Diff $diff -Prompt $prompt -AutoAccept
+        if ($null -eq $reviewed) {
+            Write-Host "Skipping the $($group.Name) commit; its changes stay uncommitted" -ForegroundColor Yellow
+            continue
+        }
+        Add-AICommitTicketReference -Message $reviewed -Ticket $Ticket
+        Add-AICommitCoAuthors -Message $reviewed -CoAuthors $CoAuthors
+        # Each commit gets its own values (e.g. a Change-Id)
+        Add-AICommitConfiguredTrailers -Message $reviewed -Trailers (Get-AICommitConfiguredTrailers -Ticket $Ticket)
+
+        if ($group.ByHunk) {
+            if (!(Add-AICommitSplitGroup -Group $group)) {
+                Write-Host "Stopping the split; the remaining changes are not committed" -ForegroundColor Yellow
+                break
+            }
+            if (Invoke-AICommitGuardedCommit -Message (Format-AICommitMessage -Message $reviewed)) {
+                $committed++
+            }
+        } elseif (Invoke-AICommitGuardedCommit -Message (Format-AICommitMessage -Message $reviewed) -Paths $group.Paths -Stage) {
+            $committed++
+        }
+    }
+    return $committed
+}
+
+function Get-AICommitChangeItems {
+    # The pieces the model can group for 'aicommit -split': one item per
+    # hunk, and one per file without hunks (binary files, pure renames, mode
+    # changes). Each has Id, File and Hunk ($null for whole files).
+    param([string]$Diff)
+
+    $items = @()
+    foreach ($file in ConvertFrom-AICommitDiff -Diff $Diff) {
+        if ($file.Hunks.Count -eq 0) {
+            $items += [pscustomobject]@{ Id = $items.Count + 1; File = $file; Hunk = $null }
+            continue
+        }
+        foreach ($hunk in $file.Hunks) {
+            $items += [pscustomobject]@{ Id = $items.Count + 1; File = $file; Hunk = $hunk }
+        }
+    }
+    return ,$items
+}
+
+function ConvertTo-AICommitSplitGroup {
+    # A split group from change items: Paths and Diff as for area groups,
+    # plus Patch (the hunks, for git apply --cached) and WholeFiles (staged
+    # by path) so a file's hunks can go to different commits
+    param(
+        [string]$Name,
+        [object[]]$Items,
+        [hashtable]$Suggestion
+    )
+
+    $files = @($Items | ForEach-Object { $_.File } | Select-Object -Unique)
+    $hunks = @($Items | Where-Object { $null -ne $_.Hunk } | ForEach-Object { $_.Hunk })
+    $hunkFiles = @($files | Where-Object { $_.Hunks.Count -gt 0 })
+    $wholeFiles = @($files | Where-Object { $_.Hunks.Count -eq 0 })
+    $patch = if ($hunkFiles.Count -gt 0) { Format-AICommitDiff -Files $hunkFiles -Hunks $hunks } else { "" }
+    $wholeDiff = if ($wholeFiles.Count -gt 0) { Format-AICommitDiff -Files $wholeFiles } else { "" }
+    return [pscustomobject]@{
+        Name       = $Name
+        ByHunk     = $true
+        Paths      = @($files | ForEach-Object { $_.Path; if ($_.OldPath -ne $_.Path) { $_.OldPath } } | Select-Object -Unique)
+        Diff       = $patch + $wholeDiff
+        Hunks      = $hunks
+        Patch      = $patch
+        WholeFiles = @($wholeFiles | ForEach-Object { $_.Path; if ($_.OldPath -ne $_.Path) { $_.OldPath } } | Select-Object -Unique)
+        Suggestion = $Suggestion
+    }
+}
+
+function Get-AICommitSplitPlan {
+    # Asks the model to sort the change items into logical commits, each
+    # with a proposed message. Items it leaves out or repeats end up in a
+    # last "remaining" group. Returns the groups, or $null when the answer
+    # is unusable.
+    param(
+        [string]$Diff,
+        [hashtable]$Provider
+    )
+
+    $items = Get-AICommitChangeItems -Diff $Diff
+    # Long hunks are cut; the model needs their gist, not every line
+    $maxLines = [int](Get-AICommitSetting -Name "AI_COMMIT_SPLIT_HUNK_LINES" -Default 40)
+    # Files in .aicommitignore and generated files are grouped by name only
+    $ignoredPaths = Get-AICommitIgnoredPaths
+    $generatedPatterns = Get-AICommitGeneratedPatterns
+    $listing = New-Object System.Text.StringBuilder
+    foreach ($item in $items) {
+        if ($ignoredPaths -contains $item.File.Path -or (Test-AICommitGeneratedPath -Path $item.File.Path -Patterns $generatedPatterns)) {
+            [void]$listing.Append("[$($item.Id)] $($item.File.Path) ($($item.File.Status), contents not shown)`n`n")
+            continue
+        }
+        if ($null -eq $item.Hunk) {
+            $kind = if ($item.File.Binary) { "binary file" } else { "$($item.File.Status) file" }
+            [void]$listing.Append("[$($item.Id)] $($item.File.Path) ($kind)`n`n")
+            continue
+        }
+        [void]$listing.Append("[$($item.Id)] $($item.File.Path) ($($item.File.Status))`n$($item.Hunk.Header)`n")
+        $lines = @($item.Hunk.Lines)
+        foreach ($line in $lines | Select-Object -First $maxLines) {
+            [void]$listing.Append("$line`n")
+        }
+        if ($lines.Count -gt $maxLines) {
+            [void]$listing.Append("... ($($lines.Count - $maxLines) more lines)`n")
+        }
+        [void]$listing.Append("`n")
+    }
+
+    $prompt = @"
+This working tree contains changes that may belong to different commits. Group the numbered changes below into logical commits: each commit should make sense on its own (one feature, fix or refactoring), and changes that depend on each other belong together. Use as few commits as make sense; one commit is fine if everything belongs together. List the commits in the order they should be made.
+
+For each commit respond with these lines, and put an empty line between commits:
+GROUP: [a short name for the commit]
+CHANGES: [the numbers of its changes, comma-separated]
+HEADER: [commit header, $($script:AICommitHeaderMaxLength) characters or less, imperative mood]
+DESCRIPTION: [what changed and why, one or two sentences]
+
+Requirements:
+- Every change number belongs to exactly one commit$(Get-AICommitPresetRules)$(Get-AICommitScopeRules)
+
+CHANGES:
+$($listing.ToString())
+"@
+    $answer = Invoke-AICommitCompletion -Provider $Provider -Prompt $prompt
+    if ($null -eq $answer) {
+        return $null
+    }
+
+    $byId = @{}
+    foreach ($item in $items) {
+        $byId[$item.Id] = $item
+    }
+    $assigned = @{}
+    $groups = @()
+    foreach ($block in [regex]::Split($answer.Trim(), '(?m)^\s*(?=GROUP:)') | Where-Object { $_.Trim() }) {
+        if ($block -notmatch '(?m)^\s*GROUP:\s*(?<name>.+)$') {
+            continue
+        }
+        $name = $Matches.name.Trim()
+        $ids = if ($block -match '(?m)^\s*CHANGES:\s*(?<ids>.+)$') { @([regex]::Matches($Matches.ids, '\d+') | ForEach-Object { [int]$_.Value }) } else { @() }
+        $groupItems = @($ids | Where-Object { $byId.ContainsKey($_) -and !$assigned.ContainsKey($_) } | Select-Object -Unique | ForEach-Object { $assigned[$_] = $true; $byId[$_] })
+        if ($groupItems.Count -eq 0) {
+            continue
+        }
+        $parsed = ConvertFrom-AICommitSuggestion -Suggestion $block
+        $suggestion = if (![string]::IsNullOrWhiteSpace($parsed.Header)) { $parsed } else { $null }
+        $groups += ConvertTo-AICommitSplitGroup -Name $name -Items $groupItems -Suggestion $suggestion
+    }
+    if ($groups.Count -eq 0) {
+        Write-Host "Warning: Could not read the suggested split" -ForegroundColor Yellow
+        return $null
+    }
+
+    $remaining = @($items | Where-Object { !$assigned.ContainsKey($_.Id) })
+    if ($remaining.Count -gt 0) {
+        Write-Host "Note: $($remaining.Count) change(s) were not placed in a commit and get one of their own" -ForegroundColor Yellow
+        $groups += ConvertTo-AICommitSplitGroup -Name "remaining" -Items $remaining -Suggestion $null
+    }
+    return ,$groups
+}
+
+function Select-AICommitSplitPlan {
+    # 'aicommit -split': shows the model's grouping with the proposed
+    # messages and asks to go ahead. Returns the groups, $null for one
+    # commit, or an empty list when cancelled.
+    param(
+        [string]$Diff,
+        [hashtable]$Provider
+    )
+
+    while ($true) {
+        Write-Host "Asking the AI how to split the changes..." -ForegroundColor Yellow
+        $groups = Get-AICommitSplitPlan -Diff $Diff -Provider $Provider
+        if ($null -eq $groups) {
+            Write-Host "Making one commit instead" -ForegroundColor Yellow
+            return $null
+        }
+        if ($groups.Count -lt 2) {
+            Write-Host "The AI sees one logical change; making one commit" -ForegroundColor Yellow
+            return $null
+        }
+
+        Write-Host "`n--- SUGGESTED SPLIT ---" -ForegroundColor Cyan
+        for ($i = 0; $i -lt $groups.Count; $i++) {
+            $header = if ($null -ne $groups[$i].Suggestion) { $groups[$i].Suggestion.Header } else { "(message generated when committed)" }
+            Write-Host ("{0}. {1}" -f ($i + 1), $header) -ForegroundColor White
+            Write-Host "   $($groups[$i].Name): $($groups[$i].Paths -join ', ')" -ForegroundColor Gray
+        }
+        Write-Host "--- END SPLIT ---`n" -ForegroundColor Cyan
+
+        Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "split_plan"; groups = @($groups | ForEach-Object { $_.Name }) }
+        do {
+            $choice = (Read-Host "(s)plit into $($groups.Count) commits / (r)egroup / (o)ne commit / (c)ancel").ToLower()
+        } while ($choice -notin @('s', 'split', 'r', 'regroup', 'o', 'one', 'c', 'cancel', ''))
+        if ($choice -in @('o', 'one')) {
+            return $null
+        }
+        if ($choice -in @('c', 'cancel')) {
+            return ,@()
+        }
+        if ($choice -notin @('r', 'regroup')) {
+            return $groups
+        }
+    }
+}
+
+function Write-AICommitSplitPlan {
+    # 'aicommit -split -plan': prints the proposed commits to stdout as a
+    # tree (default) or JSON (-Output json) for review before anything is
+    # staged or committed
+    param(
+        [object[]]$Groups,
+        [string]$Output
+    )
+
+    $Output = if ([string]::IsNullOrWhiteSpace($Output)) { "tree" } else { $Output.ToLower() }
+    $commits = @(for ($i = 0; $i -lt $Groups.Count; $i++) {
+        $group = $Groups[$i]
+        $files = @(foreach ($path in $group.Paths) {
+            $hunks = @($group.Hunks | Where-Object { $_.File -eq $path })
+            [pscustomobject]@{
+                path  = $path
+                hunks = @($hunks | ForEach-Object { $_.Header })
+            }
+        })
+        [pscustomobject]@{
+            order       = $i + 1
+            group       = $group.Name
+            header      = if ($null -ne $group.Suggestion) { $group.Suggestion.Header } else { $null }
+            description = if ($null -ne $group.Suggestion) { $group.Suggestion.Description } else { $null }
+            files       = $files
+        }
+    })
+
+    if ($Output -eq "json") {
+        return Format-AICommitOutput -Content (New-AICommitOutput -Title "Split plan: $($commits.Count) commits" -Data @{ commits = $commits }) -Format json
+    }
+
+    $lines = @("Split plan: $($commits.Count) commits")
+    foreach ($commit in $commits) {
+        $header = if ($commit.header) { $commit.header } else { "(message generated when committed)" }
+        $lines += "$($commit.order). [$($commit.group)] $header"
+        for ($j = 0; $j -lt $commit.files.Count; $j++) {
+            $file = $commit.files[$j]
+            $branch = if ($j -eq $commit.files.Count - 1) { "``-- " } else { "|-- " }
+            $detail = if ($file.hunks.Count -gt 0) { "$($file.hunks.Count) hunk(s)" } else { "whole file" }
+            $lines += "   $branch$($file.path) ($detail)"
+        }
+    }
+    return ($lines -join "`n")
+}
+
+function Add-AICommitSplitGroup {
+    # Stages a group's hunks (and whole files) into an index holding only
+    # earlier groups' commits; $false when git refuses the patch
+    param([pscustomobject]$Group)
+
+    if ($Group.Patch) {
+        $patchFile = New-AICommitTempFile -Prefix "split" -Extension ".patch"
+        [System.IO.File]::WriteAllText($patchFile, $Group.Patch, (New-Object System.Text.UTF8Encoding $false))
+        $output = git apply --cached --whitespace=nowarn -- $patchFile 2>&1
+        if ($LASTEXITCODE -ne 0) {
+            Write-Host "Error: git could not stage the $($Group.Name) changes - $(($output | Out-String).Trim())" -ForegroundColor Red
+            return $false
+        }
+    }
+    if ($Group.WholeFiles.Count -gt 0) {
+        Add-AICommitChanges -Paths $Group.WholeFiles
+    }
+    return $true
+}
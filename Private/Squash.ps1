@@ -0,0 +1,83 @@
+function Get-AICommitDefaultBase {
+    # The branch the remote's HEAD points to, else main
+    $configured = Get-AICommitSetting -Name "AI_COMMIT_SQUASH_BASE"
+    if ($configured) {
+        return $configured
+    }
+    $remoteHead = git symbolic-ref --quiet --short refs/remotes/origin/HEAD 2>$null
+    if ($LASTEXITCODE -eq 0 -and ![string]::IsNullOrWhiteSpace($remoteHead)) {
+        return "$remoteHead".Trim()
+    }
+    return "main"
+}
+
+function Invoke-AICommitSquashMessage {
+    # One message for squash-merging the current branch: a header for the
+    # whole branch, a summary and the list of its commits, as GitHub lays
+    # out its squash merge box. -Output text, markdown, json or trailer print
+    # to stdout for scripts (see Output.ps1).
+    param(
+        [string]$Base,
+        [string]$Output
+    )
+
+    $Output = if ([string]::IsNullOrWhiteSpace($Output)) { "github" } else { $Output.ToLower() }
+    if (!(Test-AICommitOutputFormat -Format $Output -Allowed (@('github') + @($script:AICommitOutputFormatters.Keys)))) {
+        return
+    }
+    if ([string]::IsNullOrWhiteSpace($Base)) {
+        $Base = Get-AICommitDefaultBase
+    }
+
+    $mergeBase = git merge-base $Base HEAD 2>$null
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($mergeBase)) {
+        Write-Host "Error: Could not find a common ancestor of '$Base' and HEAD" -ForegroundColor Red
+        Write-Host "Pass the target branch with -base, e.g. aicommit squash-message -base origin/main" -ForegroundColor Yellow
+        return
+    }
+    $mergeBase = "$mergeBase".Trim()
+
+    # Oldest first, like GitHub lists them
+    $subjects = @(git log --reverse --format=%s "$mergeBase..HEAD")
+    if ($subjects.Count -eq 0) {
+        Write-Host "No commits on this branch since $Base" -ForegroundColor Green
+        return
+    }
+
+    $provider = Get-AICommitProvider
+    if ($null -eq $provider) {
+        return
+    }
+
+    Write-Host "Summarizing $($subjects.Count) commit(s) since $Base..." -ForegroundColor Yellow
+    $messages = (git log --reverse --format="--- %h%n%B" "$mergeBase..HEAD") -join "`n"
+    $diff = Get-AICommitPromptDiff -Diff ((git diff -M -C $mergeBase HEAD -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")
+
+    $prompt = New-AICommitPrompt -Task "This diff contains all changes of a branch that will be squash-merged into $Base. Suggest the commit message for the squash merge: the header summarizes what the branch as a whole achieves, the description explains the combined change and why. Do not list the individual commits, they are added separately." -Context "The branch has these commits:`n$messages" -Diff $diff -Provider $provider
+    $parsed = Get-AICommitSuggestion -Provider $provider -Prompt $prompt
+    if ($null -eq $parsed) {
+        return
+    }
+
+    $message = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+    $commitList = ($subjects | ForEach-Object { "* $_" }) -join "`n"
+    $message.Body = "$($message.Body)`n`n$commitList".Trim()
+
+    # GitHub credits everyone who committed on the branch
+    $me = "$(git config user.email)".Trim()
+    $authors = @(git log --format="%an <%ae>" "$mergeBase..HEAD" | Select-Object -Unique | Where-Object { $_ -notmatch "<$([regex]::Escape($me))>$" })
+    foreach ($author in $authors) {
+        Add-AICommitMessageFooter -Message $message -Token "Co-authored-by" -Value $author
+    }
+
+    if ($Output -ne 'github') {
+        return Format-AICommitOutput -Content (ConvertTo-AICommitOutput -Message $message -Data @{ base = $Base; commits = $subjects.Count }) -Format $Output
+    }
+
+    Write-Host "`n--- SQUASH MERGE TITLE ---" -ForegroundColor Cyan
+    Write-Host (Get-AICommitMessageHeader -Message $message) -ForegroundColor White
+    Write-Host "--- SQUASH MERGE DESCRIPTION ---" -ForegroundColor Cyan
+    Write-Host (Get-AICommitMessageDescription -Message $message) -ForegroundColor White
+    Write-Host "--- END SQUASH MERGE ---`n" -ForegroundColor Cyan
+    Write-Host "Paste the title and description into GitHub's squash merge box" -ForegroundColor Yellow
+}
@@ -0,0 +1,232 @@
+# Staging editor ('aicommit -interactive', or (i) when changes are partly
+# staged): staged and unstaged files side by side, with commands to move
+# whole files or single hunks between them before the message is generated.
+# 'aicommit -patch' walks through the hunks one by one instead.
+# Hunks come from ConvertFrom-AICommitDiff and go to the index with git
+# apply --cached, so the working tree is never touched.
+
+function Get-AICommitStageEntries {
+    # One entry per file and side: Side (staged or unstaged), Path and File,
+    # the parsed diff ($null for untracked files, which have no hunks yet)
+    $entries = @()
+    foreach ($file in ConvertFrom-AICommitDiff -Diff ((git diff --cached -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")) {
+        $entries += [pscustomobject]@{ Side = "staged"; Path = $file.Path; File = $file }
+    }
+    foreach ($file in ConvertFrom-AICommitDiff -Diff ((git diff -- ':(top)' ':(top,exclude).aicommit.env') -join "`n")) {
+        $entries += [pscustomobject]@{ Side = "unstaged"; Path = $file.Path; File = $file }
+    }
+    $untracked = @(((git ls-files -z --others --exclude-standard --full-name) -join "") -split "`0" | Where-Object { $_ -and (Split-Path $_ -Leaf) -ne ".aicommit.env" })
+    foreach ($path in $untracked) {
+        $entries += [pscustomobject]@{ Side = "unstaged"; Path = $path; File = $null }
+    }
+    return ,$entries
+}
+
+function Show-AICommitStageEntries {
+    # Staged files on the left, unstaged on the right, numbered together
+    param([object[]]$Entries)
+
+    $width = 44
+    $columns = @{ staged = @(); unstaged = @() }
+    for ($i = 0; $i -lt $Entries.Count; $i++) {
+        $entry = $Entries[$i]
+        $stats = if ($null -eq $entry.File) { "new" } elseif ($entry.File.Binary) { "binary" } else { "+$($entry.File.Added) -$($entry.File.Removed)" }
+        $text = "{0,3}. {1} ({2})" -f ($i + 1), $entry.Path, $stats
+        if ($text.Length -gt $width) {
+            $text = "..." + $text.Substring($text.Length - $width + 3)
+        }
+        $columns[$entry.Side] += $text
+    }
+
+    Write-Host "`n--- STAGING ---" -ForegroundColor Cyan
+    Write-Host ("{0,-$width} | {1}" -f "STAGED (will be committed)", "UNSTAGED") -ForegroundColor White
+    $rows = [Math]::Max($columns.staged.Count, $columns.unstaged.Count)
+    if ($rows -eq 0) {
+        Write-Host "No changes" -ForegroundColor Yellow
+    }
+    for ($row = 0; $row -lt $rows; $row++) {
+        $left = if ($row -lt $columns.staged.Count) { $columns.staged[$row] } else { "" }
+        $right = if ($row -lt $columns.unstaged.Count) { $columns.unstaged[$row] } else { "" }
+        Write-Host ("{0,-$width}" -f $left) -ForegroundColor Green -NoNewline
+        Write-Host " | " -ForegroundColor White -NoNewline
+        Write-Host $right -ForegroundColor Red
+    }
+    Write-Host "--- END STAGING ---" -ForegroundColor Cyan
+}
+
+function Show-AICommitHunk {
+    param([pscustomobject]$Hunk)
+
+    Write-Host $Hunk.Header -ForegroundColor Cyan
+    foreach ($line in $Hunk.Lines) {
+        $color = if ($line.StartsWith('+')) { "Green" } elseif ($line.StartsWith('-')) { "Red" } else { "Gray" }
+        Write-Host $line -ForegroundColor $color
+    }
+}
+
+function Move-AICommitHunks {
+    # Stages the given hunks of an unstaged entry, or unstages those of a
+    # staged one; $false when git refuses the patch
+    param(
+        [pscustomobject]$Entry,
+        [object[]]$Hunks
+    )
+
+    $patchFile = New-AICommitTempFile -Prefix "hunks" -Extension ".patch"
+    $patch = Format-AICommitDiff -Files @($Entry.File) -Hunks $Hunks
+    [System.IO.File]::WriteAllText($patchFile, $patch, (New-Object System.Text.UTF8Encoding $false))
+    $reverse = if ($Entry.Side -eq "staged") { @("-R") } else { @() }
+    $output = git apply --cached @reverse --whitespace=nowarn -- $patchFile 2>&1
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Error: git could not apply the hunks - $(($output | Out-String).Trim())" -ForegroundColor Red
+        return $false
+    }
+    return $true
+}
+
+function Move-AICommitFile {
+    # Stages or unstages a whole file
+    param([pscustomobject]$Entry)
+
+    $pathspec = ConvertTo-AICommitPathspec -Paths @($Entry.Path)
+    if ($Entry.Side -eq "staged") {
+        git reset -q -- @pathspec 2>&1 | Out-Null
+    } else {
+        git add -A -- @pathspec 2>&1 | Out-Null
+    }
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "Error: Could not move $($Entry.Path)" -ForegroundColor Red
+    }
+}
+
+function Invoke-AICommitStageEditor {
+    # Returns $true to go on with what is staged, $false when cancelled
+    while ($true) {
+        $entries = Get-AICommitStageEntries
+        Show-AICommitStageEntries -Entries $entries
+        Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "stage_editor" }
+        $answer = "$(Read-Host "Move a (f)ile <n> / pick (h)unks <n> / (d)iff <n> / Enter to write the message / (c)ancel")".Trim().ToLower()
+
+        if ($answer -eq "") {
+            if (@($entries | Where-Object { $_.Side -eq "staged" }).Count -eq 0) {
+                Write-Host "Nothing is staged yet" -ForegroundColor Yellow
+                continue
+            }
+            return $true
+        }
+        if ($answer -in @('c', 'cancel')) {
+            Write-Host "Commit cancelled" -ForegroundColor Yellow
+            return $false
+        }
+        if ($answer -notmatch '^(?<action>[fhd])\s*(?<number>\d+)$' -or [int]$Matches.number -lt 1 -or [int]$Matches.number -gt $entries.Count) {
+            Write-Host "Use e.g. 'f 2' to move file 2, 'h 2' to pick its hunks, 'd 2' to see its diff" -ForegroundColor Yellow
+            continue
+        }
+        $action = $Matches.action
+        $entry = $entries[[int]$Matches.number - 1]
+
+        if ($action -eq "f") {
+            Move-AICommitFile -Entry $entry
+            continue
+        }
+        if ($null -eq $entry.File -or $entry.File.Hunks.Count -eq 0) {
+            Write-Host "$($entry.Path) has no hunks to show; move the whole file with 'f'" -ForegroundColor Yellow
+            continue
+        }
+        if ($action -eq "d") {
+            foreach ($hunk in $entry.File.Hunks) {
+                Show-AICommitHunk -Hunk $hunk
+            }
+            continue
+        }
+
+        # Hunk by hunk, like git add -p, applied together at the end
+        $verb = if ($entry.Side -eq "staged") { "Unstage" } else { "Stage" }
+        $picked = @()
+        $hunks = @($entry.File.Hunks)
+        for ($i = 0; $i -lt $hunks.Count; $i++) {
+            Write-Host "`n$($entry.Path) - hunk $($i + 1) of $($hunks.Count)" -ForegroundColor White
+            Show-AICommitHunk -Hunk $hunks[$i]
+            do {
+                $choice = (Read-Host "$verb this hunk? (y)es / (n)o / (q)uit").ToLower()
+            } while ($choice -notin @('y', 'yes', 'n', 'no', 'q', 'quit'))
+            if ($choice -in @('q', 'quit')) {
+                break
+            }
+            if ($choice -in @('y', 'yes')) {
+                $picked += $hunks[$i]
+            }
+        }
+        if ($picked.Count -gt 0 -and (Move-AICommitHunks -Entry $entry -Hunks $picked)) {
+            Write-Host "$($verb)d $($picked.Count) hunk(s) of $($entry.Path)" -ForegroundColor Green
+        }
+    }
+}
+
+function Invoke-AICommitPatchSelection {
+    # 'aicommit -patch': every unstaged hunk in turn, like git add -p; the
+    # picked ones are staged and the message describes only them. Returns
+    # $true to go on with what is staged, $false when cancelled.
+    $entries = Get-AICommitStageEntries
+    $alreadyStaged = @($entries | Where-Object { $_.Side -eq "staged" })
+    if ($alreadyStaged.Count -gt 0) {
+        Write-Host "Note: $($alreadyStaged.Count) file(s) already staged are committed too: $(($alreadyStaged | ForEach-Object { $_.Path }) -join ', ')" -ForegroundColor Yellow
+    }
+
+    $pickedAny = $alreadyStaged.Count -gt 0
+    $unstaged = @($entries | Where-Object { $_.Side -eq "unstaged" })
+    Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "patch" }
+    foreach ($entry in $unstaged) {
+        # New files have no hunks until they are tracked
+        if ($null -eq $entry.File -or $entry.File.Hunks.Count -eq 0) {
+            do {
+                $choice = (Read-Host "Add $($entry.Path) (new file)? (y)es / (n)o / (q)uit").ToLower()
+            } while ($choice -notin @('y', 'yes', 'n', 'no', 'q', 'quit'))
+            if ($choice -in @('q', 'quit')) {
+                break
+            }
+            if ($choice -in @('y', 'yes')) {
+                Move-AICommitFile -Entry $entry
+                $pickedAny = $true
+            }
+            continue
+        }
+
+        $picked = @()
+        $hunks = @($entry.File.Hunks)
+        $quit = $false
+        for ($i = 0; $i -lt $hunks.Count; $i++) {
+            Write-Host "`n$($entry.Path) - hunk $($i + 1) of $($hunks.Count)" -ForegroundColor White
+            Show-AICommitHunk -Hunk $hunks[$i]
+            do {
+                $choice = (Read-Host "Include this hunk? (y)es / (n)o / (a)ll of this file / (d)one with this file / (q)uit").ToLower()
+            } while ($choice -notin @('y', 'yes', 'n', 'no', 'a', 'all', 'd', 'done', 'q', 'quit'))
+            if ($choice -in @('y', 'yes')) {
+                $picked += $hunks[$i]
+            } elseif ($choice -in @('a', 'all')) {
+                $picked += $hunks[$i..($hunks.Count - 1)]
+                break
+            } elseif ($choice -in @('d', 'done')) {
+                break
+            } elseif ($choice -in @('q', 'quit')) {
+                $quit = $true
+                break
+            }
+        }
+        if ($picked.Count -gt 0) {
+            if (!(Move-AICommitHunks -Entry $entry -Hunks $picked)) {
+                return $false
+            }
+            $pickedAny = $true
+        }
+        if ($quit) {
+            break
+        }
+    }
+
+    if (!$pickedAny) {
+        Write-Host "No hunks selected, commit cancelled" -ForegroundColor Yellow
+        return $false
+    }
+    return $true
+}
@@ -0,0 +1,112 @@
+function Get-AICommitChangedFiles {
+    # Changed files relative to the repository root, split into what is
+    # staged and what is not (modified or untracked). The per-repo settings
+    # file is never listed.
+    $staged = @(((git diff --cached --name-only -z) -join "") -split "`0" | Where-Object { $_ })
+    $unstaged = @(((git diff --name-only -z) -join "") -split "`0" | Where-Object { $_ })
+    $unstaged += @(((git ls-files -z --others --exclude-standard --full-name) -join "") -split "`0" | Where-Object { $_ })
+
+    $notSettings = { (Split-Path $_ -Leaf) -ne ".aicommit.env" }
+    return @{
+        Staged   = @($staged | Where-Object $notSettings)
+        Unstaged = @($unstaged | Select-Object -Unique | Where-Object $notSettings)
+    }
+}
+
+function Select-AICommitChanges {
+    # When some changes are staged and others are not, asks what to commit
+    # instead of staging everything over the user's partial staging.
+    # AI_COMMIT_MIXED_CHANGES=staged|all skips the question. -Staged (or
+    # AI_COMMIT_STAGED) always commits the index as it is; -Interactive opens
+    # the staging editor first, -Patch asks hunk by hunk. Returns
+    # @{ Mode = all|staged|files; Paths } or $null when cancelled.
+    param(
+        [switch]$Staged,
+        [switch]$Interactive,
+        [switch]$Patch
+    )
+
+    if ($Patch) {
+        if (!(Invoke-AICommitPatchSelection)) {
+            return $null
+        }
+        return @{ Mode = "staged"; Paths = @() }
+    }
+    if ($Interactive) {
+        if (!(Invoke-AICommitStageEditor)) {
+            return $null
+        }
+        return @{ Mode = "staged"; Paths = @() }
+    }
+    if ($Staged -or (Test-AICommitSettingEnabled -Name "AI_COMMIT_STAGED")) {
+        return @{ Mode = "staged"; Paths = @() }
+    }
+
+    $changes = Get-AICommitChangedFiles
+    if ($changes.Staged.Count -eq 0 -or $changes.Unstaged.Count -eq 0) {
+        return @{ Mode = "all"; Paths = @() }
+    }
+
+    $preset = (Get-AICommitSetting -Name "AI_COMMIT_MIXED_CHANGES" -Default "ask").ToLower()
+    if ($preset -in @('staged', 'all')) {
+        return @{ Mode = $preset; Paths = @() }
+    }
+
+    Write-Host "`nSome changes are staged and others are not:" -ForegroundColor Yellow
+    Write-Host "Staged:   $($changes.Staged -join ', ')" -ForegroundColor Green
+    Write-Host "Unstaged: $($changes.Unstaged -join ', ')" -ForegroundColor Red
+    Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "staging" }
+    do {
+        $choice = (Read-Host "Commit (s)taged only / stage (a)ll / select (f)iles / move files and hunks (i)nteractively / (c)ancel").ToLower()
+    } while ($choice -notin @('s', 'staged', 'a', 'all', 'f', 'files', 'i', 'interactive', 'c', 'cancel', ''))
+
+    switch ($choice) {
+        {$_ -in @('c', 'cancel')} {
+            Write-Host "Commit cancelled" -ForegroundColor Yellow
+            return $null
+        }
+        {$_ -in @('a', 'all')} {
+            return @{ Mode = "all"; Paths = @() }
+        }
+        {$_ -in @('i', 'interactive')} {
+            if (!(Invoke-AICommitStageEditor)) {
+                return $null
+            }
+            return @{ Mode = "staged"; Paths = @() }
+        }
+        {$_ -in @('f', 'files')} {
+            $files = @($changes.Staged + $changes.Unstaged | Select-Object -Unique)
+            for ($i = 0; $i -lt $files.Count; $i++) {
+                $marker = if ($changes.Staged -contains $files[$i]) { "staged" } else { "unstaged" }
+                Write-Host ("{0,3}. {1} ({2})" -f ($i + 1), $files[$i], $marker) -ForegroundColor White
+            }
+            $answer = Read-Host "Files to commit (numbers, e.g. 1,3-5)"
+            $paths = @()
+            foreach ($part in $answer -split '[,\s]+' | Where-Object { $_ }) {
+                if ($part -match '^(\d+)-(\d+)$') {
+                    $paths += @([int]$Matches[1]..[int]$Matches[2] | Where-Object { $_ -ge 1 -and $_ -le $files.Count } | ForEach-Object { $files[$_ - 1] })
+                } elseif ($part -match '^\d+$' -and [int]$part -ge 1 -and [int]$part -le $files.Count) {
+                    $paths += $files[[int]$part - 1]
+                }
+            }
+            $paths = @($paths | Select-Object -Unique)
+            if ($paths.Count -eq 0) {
+                Write-Host "No files selected, commit cancelled" -ForegroundColor Yellow
+                return $null
+            }
+            return @{ Mode = "files"; Paths = $paths }
+        }
+        default {
+            # Enter keeps the user's staging decisions
+            return @{ Mode = "staged"; Paths = @() }
+        }
+    }
+}
+
+function ConvertTo-AICommitPathspec {
+    # Root-relative paths as literal pathspecs, so they work from any
+    # subdirectory and names with wildcards are not expanded
+    param([string[]]$Paths)
+
+    return @($Paths | ForEach-Object { ":(top,literal)$_" })
+}
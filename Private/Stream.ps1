@@ -0,0 +1,141 @@
+function Test-AICommitStreaming {
+    # On by default for providers that can stream; AI_COMMIT_STREAM=false
+    # goes back to waiting for the whole answer
+    param([hashtable]$Provider)
+
+    if ((Get-AICommitSetting -Name "AI_COMMIT_STREAM" -Default "true").ToLower() -in @('false', 'no', 'off', '0')) {
+        return $false
+    }
+    return (Test-AICommitCapability -Provider $Provider -Name "Streaming")
+}
+
+function Get-AICommitStreamText {
+    # The answer text in one streamed event, per provider format
+    param(
+        [string]$Carrier,
+        $StreamEvent
+    )
+
+    # Ollama reports errors as {"error": "..."}
+    if ($StreamEvent.error -is [string]) {
+        throw "Stream error: $($StreamEvent.error)"
+    }
+    if ($StreamEvent.type -eq "error") {
+        $detail = if ($StreamEvent.error.message) { $StreamEvent.error.message } else { $StreamEvent.message }
+        throw "Stream error: $detail"
+    }
+
+    switch ($Carrier) {
+        'anthropic' {
+            # thinking_delta events are not part of the answer; a structured
+            # answer arrives as the tool call's JSON
+            if ($StreamEvent.type -eq "content_block_delta" -and $StreamEvent.delta.type -eq "text_delta") {
+                return $StreamEvent.delta.text
+            }
+            if ($StreamEvent.type -eq "content_block_delta" -and $StreamEvent.delta.type -eq "input_json_delta") {
+                return $StreamEvent.delta.partial_json
+            }
+        }
+        'openai' {
+            if ($StreamEvent.type -eq "response.output_text.delta") {
+                return $StreamEvent.delta
+            }
+        }
+        'ollama' {
+            return $StreamEvent.message.content
+        }
+        { $_ -in @("google", "vertex") } {
+            if ($StreamEvent.candidates) {
+                return (@($StreamEvent.candidates[0].content.parts | Where-Object { !$_.thought } | ForEach-Object { $_.text }) -join "")
+            }
+        }
+        default {
+            # Chat completions (mistral, groq, custom, openrouter)
+            if ($StreamEvent.choices) {
+                return $StreamEvent.choices[0].delta.content
+            }
+        }
+    }
+    return $null
+}
+
+function Invoke-AICommitStream {
+    # POSTs the request and reads the answer while it arrives: server-sent
+    # events ("data: {...}") or, for Ollama, one JSON object per line.
+    # Returns @{ Text; StatusCode; ErrorBody; RetryAfter; RequestId; Usage }.
+    param(
+        [string]$Uri,
+        [hashtable]$Headers,
+        [byte[]]$Body,
+        [string]$Carrier
+    )
+
+    Add-Type -AssemblyName System.Net.Http
+    $client = New-Object System.Net.Http.HttpClient
+    $client.Timeout = [TimeSpan]::FromMinutes(5)
+    $request = New-Object System.Net.Http.HttpRequestMessage([System.Net.Http.HttpMethod]::Post, $Uri)
+    $request.Content = New-Object System.Net.Http.ByteArrayContent(, $Body)
+    $request.Content.Headers.ContentType = [System.Net.Http.Headers.MediaTypeHeaderValue]::Parse("application/json; charset=utf-8")
+    foreach ($name in $Headers.Keys) {
+        if ($name -ne "Content-Type") {
+            [void]$request.Headers.TryAddWithoutValidation($name, $Headers[$name])
+        }
+    }
+
+    $response = $null
+    $reader = $null
+    try {
+        # ResponseHeadersRead returns as soon as the headers are in
+        $response = $client.SendAsync($request, [System.Net.Http.HttpCompletionOption]::ResponseHeadersRead).GetAwaiter().GetResult()
+        $stream = $response.Content.ReadAsStreamAsync().GetAwaiter().GetResult()
+        $reader = New-Object System.IO.StreamReader($stream, [System.Text.Encoding]::UTF8)
+
+        $statusCode = [int]$response.StatusCode
+        $requestId = Get-AICommitRequestId -Headers $response.Headers
+        if ($statusCode -ge 400) {
+            $retryAfter = if ($response.Headers.RetryAfter) { "$($response.Headers.RetryAfter)" } else { $null }
+            return @{ Text = $null; StatusCode = $statusCode; ErrorBody = $reader.ReadToEnd(); RetryAfter = $retryAfter; RequestId = $requestId; Usage = $null }
+        }
+
+        $text = New-Object System.Text.StringBuilder
+        $usage = $null
+        while ($null -ne ($line = $reader.ReadLine())) {
+            if ($line.StartsWith("data:")) {
+                $data = $line.Substring(5).Trim()
+            } elseif ($line.StartsWith("{")) {
+                $data = $line
+            } else {
+                # event: names, comments and keep-alive blank lines
+                continue
+            }
+            if ($data -eq "[DONE]") {
+                break
+            }
+
+            $streamEvent = $data | ConvertFrom-Json
+            # Usage arrives in pieces (Anthropic) or grows with each event
+            $eventUsage = Get-AICommitUsage -Carrier $Carrier -Response $streamEvent
+            if ($null -ne $eventUsage) {
+                $usage = if ($null -eq $usage) { $eventUsage } else {
+                    @{ Input = [Math]::Max($usage.Input, $eventUsage.Input); Output = [Math]::Max($usage.Output, $eventUsage.Output) }
+                }
+            }
+
+            $chunk = Get-AICommitStreamText -Carrier $Carrier -StreamEvent $streamEvent
+            if (![string]::IsNullOrEmpty($chunk)) {
+                Write-Host $chunk -NoNewline -ForegroundColor DarkGray
+                [void]$text.Append($chunk)
+                Write-AICommitProgress -Name "tokens_streamed" -Data @{ text = $chunk; total_chars = $text.Length }
+            }
+        }
+        Write-Host ""
+
+        return @{ Text = $text.ToString(); StatusCode = $statusCode; ErrorBody = $null; RetryAfter = $null; RequestId = $requestId; Usage = $usage }
+    }
+    finally {
+        if ($reader) { $reader.Dispose() }
+        if ($response) { $response.Dispose() }
+        $request.Dispose()
+        $client.Dispose()
+    }
+}
@@ -0,0 +1,157 @@
+# Few-shot examples of the team's own commit messages, so suggestions match
+# its voice. They come from AI_COMMIT_STYLE_EXAMPLES (commit refs) and the
+# examples file written by 'aicommit style learn'.
+$script:AICommitStyleFile = ".aicommit-examples.txt"
+$script:AICommitStyleSeparator = "---"
+
+function Get-AICommitStyleFilePath {
+    # In the repository root, so the team can commit and share it; $null
+    # when the repository names a file outside of it
+    $configured = Get-AICommitSetting -Name "AI_COMMIT_STYLE_FILE"
+    if ($configured) {
+        $path = $configured.Trim() -replace '^~', $HOME
+        if (!(Test-AICommitRepoPathAllowed -Name "AI_COMMIT_STYLE_FILE" -Path $path)) {
+            return $null
+        }
+        return $configured
+    }
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    $path = Join-Path $root $script:AICommitStyleFile
+    if ((Test-Path $path) -and !(Test-AICommitRepoPathAllowed -Path $path)) {
+        return $null
+    }
+    return $path
+}
+
+function Get-AICommitStyleExamples {
+    # The example messages, at most AI_COMMIT_STYLE_COUNT of them
+    $examples = @()
+
+    $refs = Get-AICommitSetting -Name "AI_COMMIT_STYLE_EXAMPLES"
+    foreach ($ref in @("$refs" -split "," | ForEach-Object { $_.Trim() } | Where-Object { $_ })) {
+        $text = (git log -1 --format=%B $ref 2>$null) -join "`n"
+        if ($LASTEXITCODE -eq 0 -and ![string]::IsNullOrWhiteSpace($text)) {
+            $examples += $text.Trim()
+        } else {
+            Write-Host "Warning: Style example '$ref' is not a valid commit, skipping it" -ForegroundColor Yellow
+        }
+    }
+
+    $styleFile = Get-AICommitStyleFilePath
+    if ($styleFile -and (Test-Path $styleFile)) {
+        $content = Get-Content -Path $styleFile -Raw -Encoding UTF8
+        $examples += @($content -split "(?m)^$([regex]::Escape($script:AICommitStyleSeparator))\s*$" | ForEach-Object { $_.Trim() } | Where-Object { $_ -and !$_.StartsWith("#") })
+    }
+
+    $count = [int](Get-AICommitSetting -Name "AI_COMMIT_STYLE_COUNT" -Default 5)
+    return @($examples | Select-Object -First $count)
+}
+
+function Get-AICommitStylePrompt {
+    # Prompt section with the examples; empty when there are none
+    $examples = Get-AICommitStyleExamples
+    if ($examples.Count -eq 0) {
+        return ""
+    }
+    $joined = ($examples | ForEach-Object { "$script:AICommitStyleSeparator`n$_" }) -join "`n"
+    return @"
+Commit messages written by this team, to match in tone, wording and level of detail (still answer in the format above):
+$joined
+$script:AICommitStyleSeparator
+"@
+}
+
+function Find-AICommitStyleExamples {
+    # Picks good exemplars from a range of commits: headers that pass the
+    # same checks as suggestions, a short body, no WIP/fixup/merge commits,
+    # and different leading verbs so the set isn't one kind of change
+    param(
+        [string]$Range,
+        [int]$Count
+    )
+
+    $separator = [char]0x1f
+    $log = (git log --no-merges --format="%x1e%h%x1f%B" $Range 2>$null) -join "`n"
+    if ($LASTEXITCODE -ne 0) {
+        return $null
+    }
+
+    $picked = @()
+    $verbs = @{}
+    foreach ($record in $log -split [char]0x1e) {
+        if ([string]::IsNullOrWhiteSpace($record)) {
+            continue
+        }
+        $null, $text = $record -split $separator, 2
+        $message = ConvertFrom-AICommitMessageText -Text $text
+        $header = Get-AICommitMessageHeader -Message $message
+        if ($header -match '^(wip|fixup!|squash!|amend!|revert|merge)\b' -or (Test-AICommitHeader -Header $header).Count -gt 0) {
+            continue
+        }
+        $bodyLines = @($message.Body -split "`n" | Where-Object { $_.Trim() })
+        if ($bodyLines.Count -eq 0 -or $bodyLines.Count -gt 8) {
+            continue
+        }
+        $verb = $message.Subject.Split(" ")[0].ToLower()
+        if ($verbs.ContainsKey($verb)) {
+            continue
+        }
+        $verbs[$verb] = $true
+        $picked += $text.Trim()
+        if ($picked.Count -ge $Count) {
+            break
+        }
+    }
+    return ,$picked
+}
+
+function Invoke-AICommitStyle {
+    # aicommit style learn [-from <range>] | show
+    param(
+        [string[]]$Arguments,
+        [string]$From
+    )
+
+    $action = if ($Arguments.Count -gt 0) { $Arguments[0].ToLower() } else { "show" }
+    switch ($action) {
+        'learn' {
+            if ([string]::IsNullOrWhiteSpace($From)) {
+                $From = "HEAD~100..HEAD"
+            }
+            $count = [int](Get-AICommitSetting -Name "AI_COMMIT_STYLE_COUNT" -Default 5)
+            $examples = Find-AICommitStyleExamples -Range $From -Count $count
+            if ($null -eq $examples) {
+                Write-Host "Error: '$From' is not a valid range of commits" -ForegroundColor Red
+                return
+            }
+            if ($examples.Count -eq 0) {
+                Write-Host "No suitable examples in $From - good examples have a short imperative header and a body" -ForegroundColor Yellow
+                return
+            }
+
+            $styleFile = Get-AICommitStyleFilePath
+            if (!$styleFile) {
+                return
+            }
+            $content = "# Example commit messages for aicommit, separated by '$script:AICommitStyleSeparator' lines.`n# Learned from $From - edit freely.`n"
+            $content += ($examples | ForEach-Object { "$script:AICommitStyleSeparator`n$_" }) -join "`n"
+            Set-Content -Path $styleFile -Value $content -Encoding UTF8
+            Write-Host "Saved $($examples.Count) example(s) to $styleFile" -ForegroundColor Green
+            Write-Host "Commit the file to share the style with your team" -ForegroundColor Cyan
+        }
+        'show' {
+            $examples = Get-AICommitStyleExamples
+            if ($examples.Count -eq 0) {
+                Write-Host "No style examples configured. Run 'aicommit style learn' or set AI_COMMIT_STYLE_EXAMPLES" -ForegroundColor Yellow
+                return
+            }
+            foreach ($example in $examples) {
+                Write-Host "`n$example" -ForegroundColor White
+            }
+            Write-Host ""
+        }
+        default {
+            Write-Host "Error: Unknown style action: $action - use learn or show" -ForegroundColor Red
+        }
+    }
+}
@@ -0,0 +1,69 @@
+function Get-AICommitPromptTemplate {
+    # The team's commit prompt from AI_COMMIT_PROMPT_TEMPLATE, or $null to
+    # use the built-in one. Relative paths start at the repository root.
+    $path = Get-AICommitSetting -Name "AI_COMMIT_PROMPT_TEMPLATE"
+    if ([string]::IsNullOrWhiteSpace($path)) {
+        return $null
+    }
+    $path = $path.Trim() -replace '^~', $HOME
+    if (![System.IO.Path]::IsPathRooted($path)) {
+        $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+        $path = Join-Path $root $path
+    }
+    if (!(Test-AICommitRepoPathAllowed -Name "AI_COMMIT_PROMPT_TEMPLATE" -Path $path)) {
+        return $null
+    }
+    if (!(Test-Path $path -PathType Leaf)) {
+        Write-Host "Warning: Prompt template $path not found, using the built-in prompt" -ForegroundColor Yellow
+        return $null
+    }
+    return (Get-Content -Path $path -Raw -Encoding UTF8)
+}
+
+function Expand-AICommitPromptTemplate {
+    # Fills {{diff}}, {{branch}}, {{files}}, {{recent_commits}}, {{context}},
+    # {{project}}, {{examples}}, {{task}} and {{format}}. Go-style {{.Diff}} and
+    # {{.RecentCommits}} work as well. The answer format is appended when the
+    # template leaves {{format}} out, since the reply still has to be parsed.
+    param(
+        [string]$Template,
+        [hashtable]$Values
+    )
+
+    $pattern = '\{\{\s*\.?([A-Za-z_]+)\s*\}\}'
+    $used = @([regex]::Matches($Template, $pattern) | ForEach-Object { ($_.Groups[1].Value -replace '_', '').ToLower() })
+
+    # Only what the template asks for is looked up
+    $known = @{
+        task     = $Values.task
+        format   = $Values.format
+        examples = $Values.examples
+        project  = $Values.project
+        context  = $Values.context
+        diff     = $Values.diff
+    }
+    if ($used -contains "branch") {
+        $known.branch = "$(git rev-parse --abbrev-ref HEAD 2>$null)".Trim()
+    }
+    if ($used -contains "files") {
+        $known.files = (@(ConvertFrom-AICommitDiff -Diff $Values.diff) | ForEach-Object { "$($_.Status): $($_.Path)" }) -join "`n"
+    }
+    if ($used -contains "recentcommits") {
+        $known.recentcommits = (git log -n 10 --no-merges --format=%s 2>$null) -join "`n"
+    }
+
+    $unknown = @($used | Where-Object { !$known.ContainsKey($_) } | Select-Object -Unique)
+    if ($unknown.Count -gt 0) {
+        Write-Host "Warning: Unknown prompt template variables left as they are: $($unknown -join ', ')" -ForegroundColor Yellow
+    }
+
+    $prompt = [regex]::Replace($Template, $pattern, {
+        param($match)
+        $name = ($match.Groups[1].Value -replace '_', '').ToLower()
+        if ($known.ContainsKey($name)) { "$($known[$name])" } else { $match.Value }
+    })
+    if ($used -notcontains "format") {
+        $prompt = "$($prompt.TrimEnd())`n`n$($Values.format)"
+    }
+    return $prompt
+}
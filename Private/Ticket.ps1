@@ -0,0 +1,123 @@
+# Issue/ticket references such as ABC-123 or #42. With AI_COMMIT_REQUIRE_TICKET
+# (usually set in a repository's .aicommit.env) nothing is committed without one.
+# A ticket in the branch name is used whenever there is one, unless
+# AI_COMMIT_BRANCH_TICKET is off; AI_COMMIT_TICKET_POSITION puts it in a
+# Refs: footer or the header.
+$script:AICommitDefaultTicketPattern = '[A-Z][A-Z0-9]+-\d+|#\d+'
+
+function Get-AICommitTicketPattern {
+    return (Get-AICommitSetting -Name "AI_COMMIT_TICKET_PATTERN" -Default $script:AICommitDefaultTicketPattern)
+}
+
+function Test-AICommitBranchTicketEnabled {
+    # On unless AI_COMMIT_BRANCH_TICKET turns it off; a required ticket is
+    # always looked for in the branch name
+    if (Test-AICommitSettingEnabled -Name "AI_COMMIT_REQUIRE_TICKET") {
+        return $true
+    }
+    return ("$(Get-AICommitSetting -Name "AI_COMMIT_BRANCH_TICKET" -Default "true")".Trim().ToLower() -notin @('false', 'no', 'off', '0'))
+}
+
+function Get-AICommitBranchTicket {
+    # feature/ABC-123-login -> ABC-123
+    $branch = git rev-parse --abbrev-ref HEAD 2>$null
+    if ($LASTEXITCODE -ne 0 -or [string]::IsNullOrWhiteSpace($branch)) {
+        return $null
+    }
+    $match = [regex]::Match("$branch".Trim(), (Get-AICommitTicketPattern))
+    if ($match.Success) {
+        return $match.Value
+    }
+    return $null
+}
+
+function Resolve-AICommitTicket {
+    # Returns the ticket to reference: the -ticket hint, one found in the
+    # branch name or, when tickets are required, one typed in. $null means
+    # none.
+    param([string]$Hint)
+
+    $pattern = Get-AICommitTicketPattern
+    if (![string]::IsNullOrWhiteSpace($Hint)) {
+        if ($Hint.Trim() -notmatch "^($pattern)$") {
+            Write-Host "Warning: Ticket '$($Hint.Trim())' does not match AI_COMMIT_TICKET_PATTERN ($pattern)" -ForegroundColor Yellow
+        }
+        return $Hint.Trim()
+    }
+
+    $required = Test-AICommitSettingEnabled -Name "AI_COMMIT_REQUIRE_TICKET"
+    if (!(Test-AICommitBranchTicketEnabled)) {
+        return $null
+    }
+
+    $ticket = Get-AICommitBranchTicket
+    if ($null -ne $ticket) {
+        Write-Host "Using ticket $ticket from the branch name" -ForegroundColor Cyan
+        return $ticket
+    }
+    if (!$required) {
+        return $null
+    }
+
+    Write-Host "This repository requires a ticket reference in every commit" -ForegroundColor Yellow
+    while ($true) {
+        $answer = Read-Host "Ticket (e.g. ABC-123 or #42), or press Enter to cancel"
+        if ([string]::IsNullOrWhiteSpace($answer)) {
+            return $null
+        }
+        if ($answer.Trim() -match "^($pattern)$") {
+            return $answer.Trim()
+        }
+        Write-Host "Warning: '$($answer.Trim())' does not look like a ticket ($pattern)" -ForegroundColor Yellow
+    }
+}
+
+function Get-AICommitTicketPosition {
+    return "$(Get-AICommitSetting -Name "AI_COMMIT_TICKET_POSITION" -Default "footer")".Trim().ToLower()
+}
+
+function Get-AICommitTicketHeaderRule {
+    # Prompt text that leaves room in the header for a prefix/suffix ticket,
+    # empty when the ticket goes in a footer
+    param([string]$Ticket)
+
+    if ([string]::IsNullOrWhiteSpace($Ticket)) {
+        return ""
+    }
+    $room = switch (Get-AICommitTicketPosition) {
+        'prefix' { $Ticket.Length + 1 }
+        'suffix' { $Ticket.Length + 3 }
+        default { 0 }
+    }
+    if ($room -eq 0) {
+        return ""
+    }
+    return "The ticket $Ticket is added to the header afterwards, so the header must be at most $($script:AICommitHeaderMaxLength - $room) characters. "
+}
+
+function Add-AICommitTicketReference {
+    # Adds the ticket unless the message already mentions it: as a Refs:
+    # footer, or with AI_COMMIT_TICKET_POSITION prefix/suffix in the header
+    # ("ABC-123 Add login" / "Add login (ABC-123)")
+    param(
+        [pscustomobject]$Message,
+        [string]$Ticket
+    )
+
+    if ([string]::IsNullOrWhiteSpace($Ticket) -or (Format-AICommitMessage -Message $Message) -match [regex]::Escape($Ticket)) {
+        return
+    }
+    switch (Get-AICommitTicketPosition) {
+        'prefix' { $Message.Subject = "$Ticket $($Message.Subject)" }
+        'suffix' { $Message.Subject = "$($Message.Subject) ($Ticket)" }
+        default {
+            $Message.Tickets.Add($Ticket)
+            return
+        }
+    }
+    # The prompt leaves room for it, but a model or an author may not have
+    $header = Get-AICommitMessageHeader -Message $Message
+    if ($header.Length -gt $script:AICommitHeaderMaxLength) {
+        Write-Host "Warning: With the ticket the header is $($header.Length) characters, the limit is $($script:AICommitHeaderMaxLength)" -ForegroundColor Yellow
+    }
+}
@@ -0,0 +1,66 @@
+# Prompt strategies by diff size (AI_COMMIT_PROMPT_TIERS). A tiny diff gets
+# asked for a precise, modest message; a huge one is sent as a per-file
+# summary (status, line counts, touched functions) instead of a truncated
+# diff, which would only show the model its first files.
+
+function Get-AICommitDiffTier {
+    # tiny, medium or huge by the number of changed lines; always medium
+    # when tiers are off
+    param([string]$Diff)
+
+    $setting = "$(Get-AICommitSetting -Name "AI_COMMIT_PROMPT_TIERS" -Default "true")".Trim().ToLower()
+    if ($setting -in @('false', 'no', 'off', '0')) {
+        return "medium"
+    }
+    $changed = 0
+    foreach ($file in ConvertFrom-AICommitDiff -Diff $Diff) {
+        $changed += $file.Added + $file.Removed
+    }
+    if ($changed -lt [int](Get-AICommitSetting -Name "AI_COMMIT_TINY_DIFF_LINES" -Default 30)) {
+        return "tiny"
+    }
+    if ($changed -gt [int](Get-AICommitSetting -Name "AI_COMMIT_HUGE_DIFF_LINES" -Default 3000)) {
+        return "huge"
+    }
+    return "medium"
+}
+
+function ConvertTo-AICommitDiffStat {
+    # The stat-only view of a huge diff: one line per file with its status
+    # and line counts, plus the functions git names in its hunk headers
+    param([string]$Diff)
+
+    $files = ConvertFrom-AICommitDiff -Diff $Diff
+    $added = 0
+    $removed = 0
+    $lines = @()
+    foreach ($file in $files) {
+        $added += $file.Added
+        $removed += $file.Removed
+        $name = if ($file.Status -eq "renamed") { "$($file.OldPath) -> $($file.Path)" } else { $file.Path }
+        $counts = if ($file.Binary) { "binary" } else { "+$($file.Added) -$($file.Removed)" }
+        $line = "$($file.Status): $name ($counts)"
+        $functions = @($file.Hunks | ForEach-Object { "$($_.Context)".Trim() } | Where-Object { $_ } | Select-Object -Unique)
+        if ($functions.Count -gt 0) {
+            $more = if ($functions.Count -gt 5) { ", ..." } else { "" }
+            $line += " in: $(($functions | Select-Object -First 5) -join '; ')$more"
+        }
+        $lines += $line
+    }
+    return "$($files.Count) files changed, +$added -$removed lines`n$($lines -join "`n")"
+}
+
+function Get-AICommitTierRules {
+    # Extra requirement lines for the tier; none for medium diffs
+    param([string]$Tier)
+
+    switch ($Tier) {
+        'tiny' {
+            return "`n- This is a small change: name exactly what changed (the function, setting or text) in the header, keep the description to one sentence and don't make the change sound bigger than it is"
+        }
+        'huge' {
+            return "`n- The change is too large to show in full: you get a summary with each file's status, changed line counts and touched functions instead of the diff. Describe the overall purpose that the file names and functions point to, mention the main areas, and don't invent details you can't see"
+        }
+    }
+    return ""
+}
@@ -0,0 +1,212 @@
+# Input context windows by model name, for models whose provider's model
+# list doesn't say (or can't be fetched). First match wins.
+$script:AICommitContextWindows = [ordered]@{
+    '*claude-*'   = 200000
+    '*gemini-*'   = 1048576
+    '*gpt-4.1*'   = 1047576
+    '*gpt-5*'     = 400000
+    '*gpt-4o*'    = 128000
+    'o[1-9]*'     = 200000
+    'mistral-*'   = 128000
+    'codestral-*' = 256000
+    'llama-3*'    = 131072
+}
+
+# Tokens kept free for the answer (and thinking)
+$script:AICommitOutputReserve = 4000
+
+function Measure-AICommitTokens {
+    # Estimated token count. OpenAI text is split the way tiktoken's
+    # cl100k/o200k pre-tokenizer does and long pieces count extra; other
+    # providers' tokenizers are not public, so ~3.5 characters per token
+    # (code and diffs are denser than prose).
+    param(
+        [string]$Text,
+        [string]$Carrier
+    )
+
+    if ([string]::IsNullOrEmpty($Text)) {
+        return 0
+    }
+    if ($Carrier -ne "openai") {
+        return [int][Math]::Ceiling($Text.Length / 3.5)
+    }
+
+    $pattern = "'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+"
+    $tokens = 0
+    foreach ($piece in [regex]::Matches($Text, $pattern)) {
+        # Common words are one token, rare and long ones split further
+        $tokens += [Math]::Max(1, [Math]::Ceiling($piece.Length / 6))
+    }
+    return [int]$tokens
+}
+
+function Get-AICommitContextWindow {
+    # AI_COMMIT_CONTEXT_WINDOW, else the provider's model list, else the
+    # table above; $null when unknown. -fast only looks at a cached list.
+    param([hashtable]$Provider)
+
+    $configured = Get-AICommitSetting -Name "AI_COMMIT_CONTEXT_WINDOW"
+    if ($configured) {
+        return [int]$configured
+    }
+
+    $info = Get-AICommitModelInfo -Provider $Provider -CacheOnly:$script:AICommitFastMode
+    if ($null -ne $info -and $info.context_window -gt 0) {
+        return [int]$info.context_window
+    }
+
+    foreach ($pattern in $script:AICommitContextWindows.Keys) {
+        if ($Provider.Model -like $pattern) {
+            return $script:AICommitContextWindows[$pattern]
+        }
+    }
+    return $null
+}
+
+function Compress-AICommitDiff {
+    # Shrinks a diff to about -MaxTokens: small files are kept whole (they
+    # carry the most information per token) and the rest are summarized as
+    # one line each with their status and line counts
+    param(
+        [string]$Diff,
+        [int]$MaxTokens,
+        [string]$Carrier
+    )
+
+    $files = ConvertFrom-AICommitDiff -Diff $Diff
+    # Some room for the summary lines
+    $budget = [int]($MaxTokens * 0.9)
+    $used = 0
+    $kept = New-Object System.Collections.Generic.List[object]
+    foreach ($file in $files | Sort-Object { $_.Added + $_.Removed }) {
+        $cost = Measure-AICommitTokens -Text (Format-AICommitDiff -Files @($file)) -Carrier $Carrier
+        if ($used + $cost -le $budget) {
+            $kept.Add($file)
+            $used += $cost
+        }
+    }
+
+    # Original order, so related files stay together
+    $keptFiles = @($files | Where-Object { $kept.Contains($_) })
+    $summarized = @($files | Where-Object { !$kept.Contains($_) })
+    $output = Format-AICommitDiff -Files $keptFiles
+    if ($summarized.Count -gt 0) {
+        $summary = $summarized | ForEach-Object { "$($_.Path): $($_.Status), +$($_.Added) -$($_.Removed) lines" }
+        $output += "`n... (diff too large for the model, these files are summarized)`n$($summary -join "`n")"
+    }
+    return $output
+}
+
+function Limit-AICommitDiffTokens {
+    # Fits a diff into -MaxTokens: summarized (AI_COMMIT_CONTEXT_OVERFLOW=
+    # summarize, default) or cut (truncate)
+    param(
+        [string]$Diff,
+        [int]$MaxTokens,
+        [string]$Carrier
+    )
+
+    $overflow = (Get-AICommitSetting -Name "AI_COMMIT_CONTEXT_OVERFLOW" -Default "summarize").ToLower()
+    if ($overflow -eq "truncate") {
+        $tokens = Measure-AICommitTokens -Text $Diff -Carrier $Carrier
+        $length = [Math]::Min($Diff.Length, [int]($Diff.Length * $MaxTokens / [Math]::Max(1, $tokens)))
+        return $Diff.Substring(0, $length) + "`n... (diff truncated)"
+    }
+    return Compress-AICommitDiff -Diff $Diff -MaxTokens $MaxTokens -Carrier $Carrier
+}
+
+function Limit-AICommitSectionTokens {
+    # Plain text sections (history, examples) are cut at a line boundary
+    param(
+        [string]$Text,
+        [int]$MaxTokens,
+        [string]$Carrier
+    )
+
+    $tokens = Measure-AICommitTokens -Text $Text -Carrier $Carrier
+    $length = [Math]::Min($Text.Length, [int]($Text.Length * $MaxTokens / [Math]::Max(1, $tokens)))
+    $cut = $Text.Substring(0, $length)
+    $lastNewline = $cut.LastIndexOf("`n")
+    if ($lastNewline -gt 0) {
+        $cut = $cut.Substring(0, $lastNewline)
+    }
+    return "$cut`n..."
+}
+
+function Resolve-AICommitPromptBudget {
+    # Shares the context window between prompt sections instead of letting
+    # them overflow it together. -Sections is a list of
+    # @{ Name; Text; Priority (1 = most important); Share (0-1) }. Every
+    # section is first given up to its Share of the budget in priority
+    # order, then what is left goes to them in the same order. A section
+    # that doesn't fit is shrunk or dropped, and what happened is reported;
+    # the diff is never dropped, only summarized. -Reserved is what the
+    # fixed instructions take. Returns a hashtable of Name = text, or $null
+    # (with an error) when not even the summary of the diff fits.
+    param(
+        [hashtable]$Provider,
+        [int]$Window,
+        [int]$Reserved,
+        [string]$FixedText,
+        [object[]]$Sections
+    )
+
+    $carrier = $Provider.Carrier
+    $budget = $Window - $Reserved - $script:AICommitOutputReserve - (Measure-AICommitTokens -Text $FixedText -Carrier $carrier)
+    $ordered = @($Sections | Where-Object { ![string]::IsNullOrWhiteSpace($_.Text) } | Sort-Object { $_.Priority })
+
+    $need = @{}
+    $granted = @{}
+    foreach ($section in $ordered) {
+        $need[$section.Name] = Measure-AICommitTokens -Text $section.Text -Carrier $carrier
+        $granted[$section.Name] = 0
+    }
+
+    # Guaranteed shares first, then the rest by priority
+    $left = [Math]::Max(0, $budget)
+    foreach ($section in $ordered) {
+        $grant = [Math]::Min($need[$section.Name], [int]($budget * $section.Share))
+        $grant = [Math]::Max(0, [Math]::Min($grant, $left))
+        $granted[$section.Name] = $grant
+        $left -= $grant
+    }
+    foreach ($section in $ordered) {
+        $extra = [Math]::Min($need[$section.Name] - $granted[$section.Name], $left)
+        $granted[$section.Name] += $extra
+        $left -= $extra
+    }
+
+    $result = @{}
+    $changes = @()
+    foreach ($section in $Sections) {
+        $name = $section.Name
+        if (!$need.ContainsKey($name)) {
+            $result[$name] = $section.Text
+            continue
+        }
+        if ($granted[$name] -ge $need[$name]) {
+            $result[$name] = $section.Text
+        } elseif ($name -eq "diff") {
+            # Without the diff the model would make the message up
+            $result[$name] = Limit-AICommitDiffTokens -Diff $section.Text -MaxTokens $granted[$name] -Carrier $carrier
+            if ($granted[$name] -le 0 -or (Measure-AICommitTokens -Text $result[$name] -Carrier $carrier) -gt $budget) {
+                Write-AICommitError -Message "Not even a summary of the diff fits the $Window token context window of $($Provider.Model) - nothing was sent" -Kind "ContextTooSmall"
+                return $null
+            }
+            $changes += "shrunk diff from ~$($need[$name]) to ~$($granted[$name]) tokens"
+        } elseif ($granted[$name] -lt 200) {
+            # Too small a slice to be useful
+            $result[$name] = ""
+            $changes += "dropped $name (~$($need[$name]) tokens)"
+        } else {
+            $result[$name] = Limit-AICommitSectionTokens -Text $section.Text -MaxTokens $granted[$name] -Carrier $carrier
+            $changes += "shortened $name from ~$($need[$name]) to ~$($granted[$name]) tokens"
+        }
+    }
+
+    if ($changes.Count -gt 0) {
+        Write-Host "Note: The prompt didn't fit the $Window token context window of $($Provider.Model): $($changes -join ', ')" -ForegroundColor Yellow
+    }
+    return $result
+}
@@ -0,0 +1,82 @@
+# Trailers the team wants on every commit, from AI_COMMIT_TRAILERS:
+# "Token: value" pairs separated by ';'. Values may use {{branch}},
+# {{ticket}}, {{user_name}}, {{user_email}}, {{date}}, {{change_id}}
+# (a Gerrit-style Change-Id) and {{env:NAME}}. A trailer whose value comes
+# out empty (e.g. no ticket) is left out.
+
+function Get-AICommitConfiguredTrailers {
+    # The configured trailers as @{ Token; Value } with the expressions
+    # filled in. -Ticket is the run's ticket; -Previous the message being
+    # amended, whose values are kept (a Change-Id must not change).
+    param(
+        [string]$Ticket,
+        [pscustomobject]$Previous
+    )
+
+    $setting = Get-AICommitSetting -Name "AI_COMMIT_TRAILERS"
+    if ([string]::IsNullOrWhiteSpace($setting)) {
+        return ,@()
+    }
+
+    $known = @{}
+    $trailers = @()
+    foreach ($entry in "$setting" -split ';') {
+        if ([string]::IsNullOrWhiteSpace($entry)) {
+            continue
+        }
+        if ($entry.Trim() -notmatch '^(?<token>[A-Za-z][A-Za-z0-9-]*)\s*:\s*(?<value>.*)$') {
+            Write-Host "Warning: Ignoring trailer '$($entry.Trim())' in AI_COMMIT_TRAILERS - use 'Token: value'" -ForegroundColor Yellow
+            continue
+        }
+        $token = $Matches.token
+        $template = $Matches.value.Trim()
+
+        $kept = @($Previous.Footers | Where-Object { $_.Token -eq $token } | Select-Object -First 1)
+        if ($kept.Count -gt 0) {
+            $trailers += @{ Token = $token; Value = $kept[0].Value }
+            continue
+        }
+
+        $value = [regex]::Replace($template, '\{\{\s*([A-Za-z_]+(?::[A-Za-z0-9_]+)?)\s*\}\}', {
+            param($match)
+            $name = $match.Groups[1].Value.ToLower()
+            if ($name.StartsWith("env:")) {
+                return "$([Environment]::GetEnvironmentVariable($match.Groups[1].Value.Substring(4)))"
+            }
+            if (!$known.ContainsKey($name)) {
+                $known[$name] = switch ($name) {
+                    'branch' { "$(git rev-parse --abbrev-ref HEAD 2>$null)".Trim() }
+                    'ticket' { "$Ticket".Trim() }
+                    'user_name' { "$(git config user.name 2>$null)".Trim() }
+                    'user_email' { "$(git config user.email 2>$null)".Trim() }
+                    'date' { (Get-Date).ToString("yyyy-MM-dd") }
+                    'change_id' { "I" + (Get-AICommitValueHash -Value ([guid]::NewGuid().ToString())).Substring(0, 40) }
+                    default {
+                        Write-Host "Warning: Unknown trailer variable {{$name}} in AI_COMMIT_TRAILERS" -ForegroundColor Yellow
+                        ""
+                    }
+                }
+            }
+            return $known[$name]
+        })
+        if (![string]::IsNullOrWhiteSpace($value)) {
+            $trailers += @{ Token = $token; Value = $value.Trim() }
+        }
+    }
+    return ,$trailers
+}
+
+function Add-AICommitConfiguredTrailers {
+    # Adds each trailer the message doesn't already have (e.g. kept through
+    # an edit in review)
+    param(
+        [pscustomobject]$Message,
+        [object[]]$Trailers
+    )
+
+    foreach ($trailer in $Trailers) {
+        if (@($Message.Footers | Where-Object { $_.Token -eq $trailer.Token }).Count -eq 0) {
+            Add-AICommitMessageFooter -Message $Message -Token $trailer.Token -Value $trailer.Value
+        }
+    }
+}
@@ -0,0 +1,73 @@
+# Optional check before committing: AI_COMMIT_VERIFY_COMMAND (e.g.
+# "go build ./..." or "npm test") runs first, and a failure warns or, with
+# AI_COMMIT_VERIFY_MODE=block, stops the commit. With
+# AI_COMMIT_VERIFY_TRAILER on, a passing run is noted in the message as
+# "Verified: <command> passes". -noVerify skips it for one run.
+
+function Invoke-AICommitVerification {
+    # Runs the command from the repository root and returns
+    # @{ Command; Passed; Blocked }, or $null when there is nothing to run
+    $command = "$(Get-AICommitSetting -Name "AI_COMMIT_VERIFY_COMMAND")".Trim()
+    if (!$command) {
+        return $null
+    }
+    # .aicommit.yaml is the natural place for it, and it comes with the
+    # repository
+    if (!(Test-AICommitCommandAllowed -Name "AI_COMMIT_VERIFY_COMMAND" -Command $command)) {
+        return $null
+    }
+    $block = "$(Get-AICommitSetting -Name "AI_COMMIT_VERIFY_MODE" -Default "warn")".Trim().ToLower() -eq "block"
+
+    Write-Host "Verifying: $command" -ForegroundColor Cyan
+    Write-AICommitProgress -Name "verifying" -Data @{ command = $command }
+    $root = "$(git rev-parse --show-toplevel 2>$null)".Trim()
+    $global:LASTEXITCODE = 0
+    $passed = $true
+    $output = @()
+    Push-Location $root
+    try {
+        $output = @(& ([scriptblock]::Create($command)) 2>&1 | ForEach-Object { "$_" })
+        $passed = $LASTEXITCODE -eq 0
+    }
+    catch {
+        $output += $_.Exception.Message
+        $passed = $false
+    }
+    finally {
+        Pop-Location
+    }
+
+    if ($passed) {
+        Write-Host "Verified: $command passes" -ForegroundColor Green
+        return @{ Command = $command; Passed = $true; Blocked = $false }
+    }
+
+    # The end of the output is usually where the failure is
+    if ($output.Count -gt 0) {
+        Write-Host "`n--- VERIFY OUTPUT (last 20 lines) ---" -ForegroundColor Yellow
+        $output | Select-Object -Last 20 | ForEach-Object { Write-Host $_ -ForegroundColor Gray }
+        Write-Host "--- END VERIFY OUTPUT ---`n" -ForegroundColor Yellow
+    }
+    if ($block) {
+        Write-AICommitError -Message "$command failed, nothing was committed (AI_COMMIT_VERIFY_MODE=block)" -Kind "VerifyFailed"
+    } else {
+        Write-Host "Warning: $command failed - committing anyway (AI_COMMIT_VERIFY_MODE=block stops the commit)" -ForegroundColor Yellow
+    }
+    return @{ Command = $command; Passed = $false; Blocked = $block }
+}
+
+function Add-AICommitVerificationTrailer {
+    # "Verified: <command> passes" for a passing run, when
+    # AI_COMMIT_VERIFY_TRAILER is on
+    param(
+        [pscustomobject]$Message,
+        [hashtable]$Verification
+    )
+
+    if (!$Verification -or !$Verification.Passed -or !(Test-AICommitSettingEnabled -Name "AI_COMMIT_VERIFY_TRAILER")) {
+        return
+    }
+    if (@($Message.Footers | Where-Object { $_.Token -eq "Verified" }).Count -eq 0) {
+        Add-AICommitMessageFooter -Message $Message -Token "Verified" -Value "$($Verification.Command) passes"
+    }
+}
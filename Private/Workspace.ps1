@@ -0,0 +1,84 @@
+# Per-run temp directory and deferred cleanup. PowerShell runs finally
+# blocks when Ctrl+C stops a command, so aicommit calls
+# Stop-AICommitWorkspace from its finally: temp files, a git index.lock left
+# by an interrupted git command and half-done index changes are undone
+# there, however the run ends.
+$script:AICommitWorkspace = $null
+$script:AICommitCleanup = New-Object System.Collections.Generic.List[object]
+
+function Start-AICommitWorkspace {
+    $temp = [System.IO.Path]::GetTempPath()
+
+    # Leftovers of runs that were killed outright (closed window, kill -9)
+    foreach ($stale in @(Get-ChildItem -Path $temp -Directory -Filter "aicommit-run-*" -ErrorAction SilentlyContinue)) {
+        if ($stale.Name -match '^aicommit-run-(\d+)-' -and !(Get-Process -Id ([int]$Matches[1]) -ErrorAction SilentlyContinue)) {
+            Remove-Item -Path $stale.FullName -Recurse -Force -ErrorAction SilentlyContinue
+        }
+    }
+
+    $script:AICommitWorkspace = Join-Path $temp ("aicommit-run-$PID-" + [guid]::NewGuid().ToString("N").Substring(0, 8))
+    New-Item -ItemType Directory -Path $script:AICommitWorkspace -Force | Out-Null
+    $script:AICommitCleanup.Clear()
+
+    # A lock that exists now belongs to someone else and is left alone
+    $gitDir = "$(git rev-parse --absolute-git-dir 2>$null)".Trim()
+    if ($gitDir) {
+        $indexLock = Join-Path $gitDir "index.lock"
+        if (!(Test-Path $indexLock)) {
+            $null = Register-AICommitCleanup -Action {
+                # Only stale when no git command is still running
+                if ((Test-Path $indexLock) -and !(Get-Process -Name git -ErrorAction SilentlyContinue)) {
+                    Remove-Item $indexLock -Force -ErrorAction SilentlyContinue
+                    Write-Host "Removed the git index.lock left by the interrupted run" -ForegroundColor Yellow
+                }
+            }.GetNewClosure()
+        }
+    }
+}
+
+function New-AICommitTempFile {
+    # A file path in this run's workspace, removed at the end of the run.
+    # Outside a run (e.g. key decryption on import) the system temp is used
+    # and the caller removes the file itself.
+    param(
+        [string]$Prefix = "aicommit",
+        [string]$Extension = ".txt"
+    )
+
+    $name = "$Prefix-" + [guid]::NewGuid().ToString("N").Substring(0, 8) + $Extension
+    $directory = if ($script:AICommitWorkspace -and (Test-Path $script:AICommitWorkspace)) { $script:AICommitWorkspace } else { [System.IO.Path]::GetTempPath() }
+    return (Join-Path $directory $name)
+}
+
+function Register-AICommitCleanup {
+    # Runs -Action when the run ends, newest first. Returns the entry for
+    # Unregister-AICommitCleanup once the work it guards is done.
+    param([scriptblock]$Action)
+
+    $entry = [pscustomobject]@{ Action = $Action }
+    $script:AICommitCleanup.Add($entry)
+    return $entry
+}
+
+function Unregister-AICommitCleanup {
+    param([object]$Entry)
+
+    $null = $script:AICommitCleanup.Remove($Entry)
+}
+
+function Stop-AICommitWorkspace {
+    for ($i = $script:AICommitCleanup.Count - 1; $i -ge 0; $i--) {
+        try {
+            & $script:AICommitCleanup[$i].Action
+        }
+        catch {
+            Write-Host "Warning: Cleanup step failed - $($_.Exception.Message)" -ForegroundColor Yellow
+        }
+    }
+    $script:AICommitCleanup.Clear()
+
+    if ($script:AICommitWorkspace) {
+        Remove-Item -Path $script:AICommitWorkspace -Recurse -Force -ErrorAction SilentlyContinue
+        $script:AICommitWorkspace = $null
+    }
+}
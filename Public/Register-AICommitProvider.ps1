@@ -0,0 +1,54 @@
+function Register-AICommitProvider {
+    <#
+    .SYNOPSIS
+    Adds a provider (e.g. an internal gateway) without changing the module.
+
+    .DESCRIPTION
+    The registered name can be used like a built-in one: -provider <name>,
+    AI_COMMIT_PROVIDER=<name> or as a format fallback. -Completion receives
+    the provider (Model, Carrier, ApiKey) and the prompt and returns the
+    model's answer as text, or $null after printing an error. -ListModels is
+    optional and returns objects with id, name, context_window and
+    output_limit for 'aicommit models'.
+
+    .EXAMPLE
+    Register-AICommitProvider -Name gateway -KeyName GATEWAY_API_KEY_AICOMMIT -DefaultModel "claude-sonnet" -Completion {
+        param($Provider, $Prompt)
+        $body = @{ model = $Provider.Model; prompt = $Prompt } | ConvertTo-Json
+        (Invoke-RestMethod -Uri "https://llm.example.internal/v1/generate" -Method Post -Body $body -ContentType "application/json" -Headers @{ Authorization = "Bearer $($Provider.ApiKey)" }).text
+    }
+    #>
+    param(
+        [Parameter(Mandatory = $true)]
+        [string]$Name,
+        [Parameter(Mandatory = $true)]
+        [scriptblock]$Completion,
+        [string]$KeyName,
+        [string]$DefaultModel,
+        [string[]]$ModelPatterns = @(),
+        [string[]]$Capabilities = @(),
+        [scriptblock]$ListModels,
+        [switch]$Force
+    )
+
+    $Name = $Name.ToLower()
+    if ($script:AICommitProviders.ContainsKey($Name) -and !$Force) {
+        Write-Host "Error: Provider '$Name' is already registered (use -Force to replace it)" -ForegroundColor Red
+        return
+    }
+
+    # A model list is only offered when there is a way to fetch it
+    $declared = @($Capabilities | Where-Object { $_ -ne "ModelList" })
+    if ($ListModels) {
+        $declared += "ModelList"
+    }
+
+    $script:AICommitProviders[$Name] = @{
+        KeyName       = if ($KeyName) { $KeyName } else { $null }
+        DefaultModel  = $DefaultModel
+        ModelPatterns = $ModelPatterns
+        Capabilities  = $declared
+        Completion    = $Completion
+        ListModels    = $ListModels
+    }
+}
@@ -0,0 +1,562 @@
+function aicommit {
+    param(
+        [Parameter(Position = 0)]
+        [string]$command,
+        [Parameter(Position = 1, ValueFromRemainingArguments = $true)]
+        [string[]]$arguments,
+        [switch]$push,
+        [switch]$clasp,
+        [string]$claspEnv,
+        [switch]$wrangler,
+        [switch]$export,
+        [switch]$refresh,
+        [string]$provider,
+        [string]$model,
+        [switch]$fast,
+        [switch]$breaking,
+        [switch]$staged,
+        [switch]$interactive,
+        [switch]$patch,
+        [switch]$split,
+        [switch]$plan,
+        [switch]$amend,
+        [switch]$gpgSign,
+        [switch]$noVerify,
+        [switch]$noPushOnClaspFailure,
+        [string]$ticket,
+        [string[]]$coAuthor,
+        [string]$base,
+        [string]$range,
+        [string]$output,
+        [string]$since,
+        [switch]$send,
+        [string]$progress,
+        [string]$from,
+        [int]$n,
+        [Alias('m')]
+        [string]$message,
+        [string]$header,
+        [string]$repo
+    )
+    # -repo <path>, or a GIT_WORK_TREE away from here: the whole run works
+    # from that work tree's root, then returns to where it started
+    if (!$script:AICommitRepositoryEntered) {
+        $repository = Enter-AICommitRepository -Path $repo
+        if (!$repository) {
+            Write-AICommitError -Message "Repository path not found: $repo" -Kind "NotARepo"
+            return
+        }
+        $null = $PSBoundParameters.Remove('repo')
+        $script:AICommitRepositoryEntered = $true
+        try {
+            aicommit @PSBoundParameters
+        }
+        finally {
+            $script:AICommitRepositoryEntered = $false
+            Exit-AICommitRepository -State $repository
+        }
+        return
+    }
+
+    # Check if we're in a git repository (git reports failure by exit code);
+    # help, setup and the self-test work from anywhere
+    $needsRepository = [string]::IsNullOrWhiteSpace($command) -or $command.ToLower() -notin $script:AICommitRepositoryFreeCommands
+    git rev-parse --git-dir 2>$null | Out-Null
+    if ($needsRepository -and $LASTEXITCODE -ne 0) {
+        Write-AICommitError -Message "Not in a git repository" -Kind "NotARepo"
+        return
+    }
+
+    # Ensure console and HTTP body use UTF-8
+    [Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+
+    # One-off provider/model choice and speed preference for this run only
+    $script:AICommitProviderOverride = $provider
+    $script:AICommitModelOverride = $model
+    $script:AICommitFastMode = [bool]$fast
+    # -gpgSign signs the commits of this run (commit.gpgsign signs them anyway)
+    $script:AICommitSignCommits = [bool]$gpgSign
+
+    # Machine-readable progress on stderr for wrappers
+    $script:AICommitProgressFormat = if ($progress) { $progress.ToLower() } else { $null }
+    if ($progress -and $script:AICommitProgressFormat -ne "json") {
+        Write-Host "Error: Unknown progress format '$progress' - use json" -ForegroundColor Red
+        return
+    }
+
+    # User config file, then per-repository settings and keys
+    Import-AICommitUserSettings
+    Import-AICommitRepoSettings
+    # Provider, model, style and push choice remembered for this repository
+    $repoDefaults = Import-AICommitRepoDefaults
+
+    # Token usage of this run, reported at the end however it ends
+    $script:AICommitRunUsage = New-Object System.Collections.Generic.List[object]
+    # Temp files and interrupted git state are cleaned up the same way
+    Start-AICommitWorkspace
+    # Opt-in run metrics (AI_COMMIT_METRICS_COMMAND, AI_COMMIT_OTLP_ENDPOINT)
+    Start-AICommitMetrics -Command $command
+    try {
+        # Subcommands
+        if (![string]::IsNullOrWhiteSpace($command)) {
+            switch ($command.ToLower()) {
+                'revert' {
+                    Invoke-AICommitRevert -Ref ($arguments | Select-Object -First 1)
+                }
+                'note' {
+                    Invoke-AICommitNote -Ref ($arguments | Select-Object -First 1) -Push:$push
+                }
+                'squash-message' {
+                    Invoke-AICommitSquashMessage -Base $base -Output $output
+                }
+                'series' {
+                    Invoke-AICommitSeries -Range $range
+                }
+                'pr-fill' {
+                    Invoke-AICommitPullRequestFill -Base $base -Output $output -Send:$send
+                }
+                'digest' {
+                    Invoke-AICommitDigest -Since $since -Format $output -Send:$send
+                }
+                'style' {
+                    Invoke-AICommitStyle -Arguments $arguments -From $from
+                }
+                'models' {
+                    Show-AICommitModels -Refresh:$refresh -Select:(($arguments | Select-Object -First 1) -eq "select")
+                }
+                'encrypt-key' {
+                    Protect-AICommitApiKey
+                }
+                'hook' {
+                    Invoke-AICommitHook -Arguments $arguments
+                }
+                'config' {
+                    Invoke-AICommitConfig -Arguments $arguments
+                }
+                'repos' {
+                    Invoke-AICommitRepos -Arguments $arguments
+                }
+                'selftest' {
+                    Invoke-AICommitSelfTest
+                }
+                'help' {
+                    Show-AICommitHelp -Command ($arguments | Select-Object -First 1)
+                }
+                'examples' {
+                    Show-AICommitExamples -Command ($arguments | Select-Object -First 1)
+                }
+                default {
+                    Write-Host "Error: Unknown command: $command" -ForegroundColor Red
+                    Write-Host "Available commands: $((Get-AICommitCommandNames) -join ', ')" -ForegroundColor Yellow
+                    Write-Host "Run 'aicommit help' for an overview" -ForegroundColor Yellow
+                }
+            }
+            return
+        }
+
+        # -claspEnv pushes to one of several clasp projects
+        if (![string]::IsNullOrWhiteSpace($claspEnv)) {
+            $clasp = $true
+            if (!(Use-AICommitClaspEnvironment -Environment $claspEnv)) {
+                return
+            }
+        }
+
+        # Check for clasp if flag is set
+        if ($clasp) {
+            # Check if .clasp.json exists
+            if (!(Test-Path ".clasp.json")) {
+                Write-AICommitError -Message "Not in a clasp repository (.clasp.json not found)" -Kind "NotClasp"
+                return
+            }
+
+            # Ask if clasp has been pulled
+            $claspPulled = Read-Host "Have you pulled from clasp? (y/n)"
+            if ($claspPulled.ToLower() -notin @('y', 'yes')) {
+                Write-Host "Please run 'clasp pull' first, then try again" -ForegroundColor Yellow
+                return
+            }
+        }
+
+        # The last -push choice in this repository applies until -push:$false
+        if (!$PSBoundParameters.ContainsKey('push') -and $repoDefaults['push'] -eq 'on') {
+            Write-Host "Pushing after the commit, as remembered for this repository (-push:`$false to skip)" -ForegroundColor Cyan
+            $push = $true
+        }
+
+        # Without a remote there is nothing to push to; offer to add one now
+        if ($push -and !(Confirm-AICommitRemote)) {
+            $push = $false
+        }
+
+        # Check for wrangler if flag is set
+        if ($wrangler) {
+            # Check if wrangler.toml exists
+            if (!(Test-Path "wrangler.toml")) {
+                Write-AICommitError -Message "Not in a wrangler project (wrangler.toml not found)" -Kind "NotWrangler"
+                return
+            }
+        }
+
+        # -plan is a dry run of -split and needs the AI's grouping
+        if ($plan) {
+            if ($amend -or ![string]::IsNullOrWhiteSpace($message)) {
+                Write-Host "Error: -plan can't be combined with -amend or -m" -ForegroundColor Red
+                return
+            }
+            $split = $true
+        }
+
+        # A message given with -message needs no provider at all
+        $aiProvider = $null
+        if ([string]::IsNullOrWhiteSpace($message)) {
+            $aiProvider = Get-AICommitProvider
+            if ($null -eq $aiProvider) {
+                return
+            }
+            if (!$provider -and (Get-AICommitSettingSource -Name "AI_COMMIT_PROVIDER") -eq "remembered") {
+                Write-Host "Using $($aiProvider.Carrier), as remembered for this repository (aicommit repos)" -ForegroundColor Cyan
+            }
+            if ($fast) {
+                # Cheapest model and no extra lookups (model list, history)
+                $aiProvider = Get-AICommitFastProvider -Provider $aiProvider
+            } else {
+                Test-AICommitModel -Provider $aiProvider
+            }
+        }
+
+        # -amend rewrites HEAD with what is staged on top; never a pushed HEAD
+        $amendContext = $null
+        if ($amend) {
+            if (!(Test-AICommitAmendSafe)) {
+                return
+            }
+            $amendContext = Get-AICommitAmendContext
+        }
+
+        # Partial staging is kept unless the user chooses otherwise
+        $selection = Select-AICommitChanges -Staged:($staged -or $amend) -Interactive:$interactive -Patch:$patch
+        if ($null -eq $selection) {
+            return
+        }
+
+        # AI_COMMIT_VERIFY_COMMAND runs before anything is generated, so a
+        # blocked commit costs no tokens
+        $verification = $null
+        if (!$noVerify -and !$plan -and !$export) {
+            $verification = Invoke-AICommitVerification
+            if ($verification -and $verification.Blocked) {
+                Set-AICommitMetric -Name "outcome" -Value "failed"
+                return
+            }
+        }
+
+        Write-Host "Analyzing changes..." -ForegroundColor Yellow
+        Write-AICommitProgress -Name "collecting_diff" -Data @{ mode = $selection.Mode }
+
+        # Generated again if the changes move under the message (see below)
+        $splitGroups = $null
+        $splitChecked = $false
+        while ($true) {
+            $fullDiff = Get-AICommitFullDiff -Staged:($selection.Mode -eq "staged") -Paths $selection.Paths -Amend:$amend
+            $diffHash = Get-AICommitDiffHash -Diff $fullDiff
+
+            # Check if there are any changes at all
+            if ([string]::IsNullOrWhiteSpace($fullDiff)) {
+                Write-Host "No changes to commit" -ForegroundColor Green
+                $emptyKind = if ($selection.Mode -eq "staged") { "NoStagedChanges" } else { "NoChanges" }
+                Write-AICommitHint -Kind $emptyKind
+                Set-AICommitMetric -Name "outcome" -Value "no_changes"
+                return
+            }
+
+            # Split groups need every file, including ones only ignored hunks touch
+            $unfilteredDiff = $fullDiff
+            # Ignored files, noise such as version bumps and lock files are
+            # committed but not shown to the model as they are
+            $fullDiff = Get-AICommitPromptDiff -Diff $fullDiff
+
+            # Export diff to file if requested
+            if ($export) {
+                $exportFile = "git-diff-export.txt"
+                $fullDiff | Out-File -FilePath $exportFile -Encoding UTF8
+                Write-Host "Diff exported to: $exportFile" -ForegroundColor Green
+                return
+            }
+
+            # Catch a reverted or already committed change being applied again
+            # (an amended HEAD would always match itself)
+            if (!$fast -and !$amend) {
+                Test-AICommitDuplicate -Diff $fullDiff
+            }
+
+            # Ticket reference; some repositories refuse commits without one
+            $ticketRef = Resolve-AICommitTicket -Hint $ticket
+            if ($null -eq $ticketRef -and (Test-AICommitSettingEnabled -Name "AI_COMMIT_REQUIRE_TICKET")) {
+                Write-AICommitError -Message "This repository requires a ticket reference (AI_COMMIT_REQUIRE_TICKET), nothing was committed" -Kind "TicketRequired"
+                Write-Host "Or include it in the branch name, e.g. feature/ABC-123-login" -ForegroundColor Yellow
+                return
+            }
+            # Not asked for again when the message is regenerated
+            $ticket = $ticketRef
+            # Co-authored-by trailers from -coAuthor and AI_COMMIT_CO_AUTHORS
+            $coAuthors = Get-AICommitCoAuthors -CoAuthor $coAuthor
+            # The team's AI_COMMIT_TRAILERS; an amended commit keeps its values
+            $previousMessage = if ($amend) { ConvertFrom-AICommitMessageText -Text ((git log -1 --format=%B HEAD) -join "`n") } else { $null }
+            $configuredTrailers = Get-AICommitConfiguredTrailers -Ticket $ticketRef -Previous $previousMessage
+
+            # -message skips generation and review; the header checks and
+            # trailers still apply
+            if (![string]::IsNullOrWhiteSpace($message)) {
+                $reviewed = ConvertFrom-AICommitMessageText -Text $message
+                foreach ($problem in Test-AICommitHeader -Header (Get-AICommitMessageHeader -Message $reviewed)) {
+                    Write-Host "Warning: $problem" -ForegroundColor Yellow
+                }
+                Add-AICommitTicketReference -Message $reviewed -Ticket $ticketRef
+                if ($claspEnv) {
+                    Add-AICommitMessageFooter -Message $reviewed -Token "Clasp-Environment" -Value $claspEnv
+                }
+                if ($breaking) {
+                    Add-AICommitBreakingFooter -Message $reviewed -Force
+                }
+                Add-AICommitCoAuthors -Message $reviewed -CoAuthors $coAuthors
+                Add-AICommitConfiguredTrailers -Message $reviewed -Trailers $configuredTrailers
+                Add-AICommitVerificationTrailer -Message $reviewed -Verification $verification
+                $finalMessage = Format-AICommitMessage -Message $reviewed
+                Set-AICommitMetric -Name "review" -Value "manual"
+                break
+            }
+
+            # Only dependency manifests and lock files changed: the package
+            # bumps read from them replace the lock file diff
+            $dependencyChanges = $null
+            if (!$amend -and [string]::IsNullOrWhiteSpace($header)) {
+                $dependencyChanges = Get-AICommitDependencyChanges -Diff $unfilteredDiff -Staged:($selection.Mode -eq "staged")
+                if ($null -ne $dependencyChanges) {
+                    Write-Host "Note: Only dependencies changed, $($dependencyChanges.Count) package(s) read from the manifests and lock files" -ForegroundColor Yellow
+                    $fullDiff = Format-AICommitDependencyChanges -Changes $dependencyChanges
+                }
+            }
+
+            # -split has the model group the hunks into logical commits; that
+            # works for staged-only runs too
+            if (!$splitChecked -and $split -and !$amend) {
+                $splitChecked = $true
+                if (![string]::IsNullOrWhiteSpace($header)) {
+                    Write-Host "Warning: -split proposes a header per commit, ignoring -header" -ForegroundColor Yellow
+                    $header = $null
+                }
+                # -plan only prints the proposal; the index stays as it is
+                if ($plan) {
+                    if ($output -and $output.ToLower() -notin @('tree', 'json')) {
+                        Write-Host "Error: Unknown output '$output' - use tree or json" -ForegroundColor Red
+                        return
+                    }
+                    Write-Host "Asking the AI how to split the changes..." -ForegroundColor Yellow
+                    $planGroups = Get-AICommitSplitPlan -Diff $unfilteredDiff -Provider $aiProvider
+                    if ($null -eq $planGroups) {
+                        Write-Host "Error: No split plan could be made" -ForegroundColor Red
+                        return
+                    }
+                    Write-AICommitSplitPlan -Groups $planGroups -Output $output
+                    Set-AICommitMetric -Name "outcome" -Value "planned"
+                    return
+                }
+                $splitGroups = Select-AICommitSplitPlan -Diff $unfilteredDiff -Provider $aiProvider
+                if ($null -ne $splitGroups -and $splitGroups.Count -eq 0) {
+                    Write-Host "Commit cancelled" -ForegroundColor Yellow
+                    Set-AICommitMetric -Name "review" -Value "cancelled"
+                    return
+                }
+                if ($null -ne $splitGroups) {
+                    break
+                }
+            }
+
+            # Changes spanning e.g. frontend and backend can become one commit
+            # per area. Committing a path takes its whole file, so staged-only
+            # runs are not split.
+            if (!$splitChecked -and $selection.Mode -ne "staged" -and [string]::IsNullOrWhiteSpace($header) -and $null -eq $dependencyChanges) {
+                $splitChecked = $true
+                $splitGroups = Select-AICommitSplit -Diff $unfilteredDiff
+                if ($null -ne $splitGroups) {
+                    break
+                }
+            }
+
+            # Optional reviewer risk line for high-impact diffs
+            $riskReasons = @()
+            if (Test-AICommitSettingEnabled -Name "AI_COMMIT_RISK_SUMMARY") {
+                $riskReasons = Get-AICommitRiskReasons -Diff $fullDiff
+                if ($riskReasons.Count -gt 0) {
+                    Write-Host "High-impact change, asking for a risk summary: $($riskReasons -join '; ')" -ForegroundColor Yellow
+                }
+            }
+
+            # Removed or changed public API and major version bumps ask for a
+            # BREAKING CHANGE footer; -breaking asks for it regardless
+            $breakingReasons = Get-AICommitBreakingCheck -Diff $fullDiff -Force:$breaking
+
+            # -header: the author's header is kept and only the description
+            # is generated to fit it
+            $task = "Analyze this git diff and suggest a commit message. "
+            if (![string]::IsNullOrWhiteSpace($header)) {
+                foreach ($problem in Test-AICommitHeader -Header $header) {
+                    Write-Host "Warning: $problem" -ForegroundColor Yellow
+                }
+                $task = "Analyze this git diff and write the commit description for it. The author already wrote the header and it is final: `"$header`". Answer with exactly that header and a description that fits it. "
+            } else {
+                # A ticket prefix/suffix comes out of the header's length
+                $task += Get-AICommitTicketHeaderRule -Ticket $ticketRef
+            }
+
+            # Build the complete prompt
+            $promptContent = New-AICommitPrompt -Task $task -Context $amendContext -Diff $fullDiff -RiskReasons $riskReasons -BreakingReasons $breakingReasons -Provider $aiProvider -Template (Get-AICommitPromptTemplate)
+
+            # Get and parse the suggestion, or several to pick from
+            $suggestionCount = if ($n -gt 0) { $n } else { [int](Get-AICommitSetting -Name "AI_COMMIT_SUGGESTIONS" -Default 1) }
+            $parsedList = if ($null -ne $dependencyChanges -and (Get-AICommitDependencyMode) -eq "local") {
+                # Written from the package list, no request needed
+                @(New-AICommitDependencySuggestion -Changes $dependencyChanges)
+            } elseif ($suggestionCount -gt 1 -and !$fast) {
+                @(Get-AICommitSuggestions -Provider $aiProvider -Prompt $promptContent -Count $suggestionCount)
+            } else {
+                @(Get-AICommitSuggestion -Provider $aiProvider -Prompt $promptContent | Where-Object { $null -ne $_ })
+            }
+            if ($parsedList.Count -eq 0) {
+                $failureKind = Get-AICommitFailureKind
+                if ($null -ne $failureKind) {
+                    $keyName = $script:AICommitProviders[$aiProvider.Carrier].KeyName
+                    Write-AICommitHint -Kind $failureKind -Arguments @($(if ($keyName) { $keyName } else { "<API key variable>" }))
+                }
+                return
+            }
+
+            # One message structure from here on; risk line and ticket are footers
+            $messages = @(foreach ($parsed in $parsedList) {
+                $candidate = New-AICommitMessage -Header $parsed.Header -Description $parsed.Description
+                if (![string]::IsNullOrWhiteSpace($header)) {
+                    Set-AICommitMessageHeader -Message $candidate -Header $header
+                }
+                if ($riskReasons.Count -gt 0) {
+                    Add-AICommitRiskLine -Message $candidate -Risk $parsed.Risk -Reasons $riskReasons
+                }
+                if ($breakingReasons.Count -gt 0) {
+                    Add-AICommitBreakingFooter -Message $candidate -Breaking $parsed.Breaking -Force:$breaking
+                }
+                Add-AICommitTicketReference -Message $candidate -Ticket $ticketRef
+                # Which clasp environment this commit goes to
+                if ($claspEnv) {
+                    Add-AICommitMessageFooter -Message $candidate -Token "Clasp-Environment" -Value $claspEnv
+                }
+                $candidate
+            })
+
+            $reviewed = if ($messages.Count -gt 1) {
+                Select-AICommitCandidate -Messages $messages -Provider $aiProvider -Diff $fullDiff -Prompt $promptContent -KeepHeader:(![string]::IsNullOrWhiteSpace($header))
+            } else {
+                Read-AICommitMessage -Message $messages[0] -Provider $aiProvider -Diff $fullDiff -Prompt $promptContent -AutoAccept -KeepHeader:(![string]::IsNullOrWhiteSpace($header))
+            }
+            if ($null -eq $reviewed) {
+                return
+            }
+            # Re-added if it was edited out
+            Add-AICommitTicketReference -Message $reviewed -Ticket $ticketRef
+            Add-AICommitCoAuthors -Message $reviewed -CoAuthors $coAuthors
+            Add-AICommitConfiguredTrailers -Message $reviewed -Trailers $configuredTrailers
+            Add-AICommitVerificationTrailer -Message $reviewed -Verification $verification
+            $finalMessage = Format-AICommitMessage -Message $reviewed
+
+            # Files can change while the message is reviewed; make sure it
+            # still describes what will be committed
+            $currentDiff = Get-AICommitFullDiff -Staged:($selection.Mode -eq "staged") -Paths $selection.Paths -Amend:$amend
+            if ((Get-AICommitDiffHash -Diff $currentDiff) -eq $diffHash) {
+                break
+            }
+            Write-Host "`nWarning: The changes were modified after the message was generated" -ForegroundColor Yellow
+            Write-AICommitProgress -Name "awaiting_user" -Data @{ prompt = "changes_modified" }
+            do {
+                $changedChoice = (Read-Host "(r)egenerate the message / (k)eep it anyway / (c)ancel").ToLower()
+            } while ($changedChoice -notin @('r', 'regenerate', 'k', 'keep', 'c', 'cancel', ''))
+            if ($changedChoice -in @('c', 'cancel')) {
+                Write-Host "Commit cancelled" -ForegroundColor Yellow
+                return
+            }
+            if ($changedChoice -in @('k', 'keep')) {
+                break
+            }
+            Write-Host "Analyzing changes again..." -ForegroundColor Yellow
+        }
+
+        # Towncrier-style news file (AI_COMMIT_CHANGELOG_FRAGMENTS), committed
+        # along with the change
+        $fragment = $null
+        if ($null -eq $splitGroups -and !$amend) {
+            $fragment = Add-AICommitChangelogFragment -Message $finalMessage -Ticket $ticketRef -Selection $selection
+        }
+
+        # Stage the chosen changes and commit
+        try {
+            $committed = if ($null -ne $splitGroups) {
+                (Invoke-AICommitSplit -Groups $splitGroups -Provider $aiProvider -Ticket $ticketRef -CoAuthors $coAuthors -Breaking:$breaking) -gt 0
+            } else {
+                Invoke-AICommitGuardedCommit -Message $finalMessage -Paths $selection.Paths -Stage:($selection.Mode -ne "staged") -Amend:$amend
+            }
+            if (!$committed -and $fragment) {
+                Remove-AICommitChangelogFragment -Path $fragment
+            }
+            Set-AICommitMetric -Name "outcome" -Value $(if ($committed) { "committed" } else { "failed" })
+            if ($committed) {
+                # 'aicommit repos' lists this repository with the choices
+                # given as flags this time
+                $remember = @{}
+                if ($provider) { $remember["provider"] = $provider }
+                if ($model) { $remember["model"] = $model }
+                if ($PSBoundParameters.ContainsKey('push')) { $remember["push"] = if ([bool]$PSBoundParameters['push']) { "on" } else { "off" } }
+                Save-AICommitRepoUse -Defaults $remember
+
+                $claspPushed = $false
+                if ($push -and $clasp) {
+                    if ($noPushOnClaspFailure) {
+                        # Apps Script first; only publish to git if it went through
+                        $claspPushed = Invoke-AICommitClaspPush
+                        if ($claspPushed) {
+                            $null = Invoke-AICommitGitPush
+                        } else {
+                            Write-Host "Skipping git push because clasp push failed" -ForegroundColor Yellow
+                        }
+                    } else {
+                        $claspPushed = Invoke-AICommitParallelPush
+                    }
+                } else {
+                    # Push if requested
+                    if ($push) {
+                        $null = Invoke-AICommitGitPush
+                    }
+                    # Push to clasp if flag was set
+                    if ($clasp) {
+                        $claspPushed = Invoke-AICommitClaspPush
+                    }
+                }
+                # The environment's deployment gets a description of its own
+                if ($claspEnv -and $claspPushed) {
+                    $null = Invoke-AICommitClaspDeploy -Environment $claspEnv -Message "$(git log -1 --format=%B)" -Provider $aiProvider
+                }
+                # Deploy to wrangler if flag was set
+                if ($wrangler) {
+                    $null = Invoke-AICommitWranglerDeploy
+                }
+            }
+        }
+        catch {
+            Write-Host "Error during commit: $($_.Exception.Message)" -ForegroundColor Red
+            Set-AICommitMetric -Name "outcome" -Value "failed"
+        }
+    }
+    finally {
+        Stop-AICommitWorkspace
+        # Before the summary, which clears the run's usage
+        Send-AICommitMetrics
+        Show-AICommitCostSummary
+    }
+}
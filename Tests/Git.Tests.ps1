@@ -0,0 +1,218 @@
+# Git helpers (Private/Git.ps1) against throwaway repositories.
+# Run with: Invoke-Pester ./Tests
+# InModuleScope needs the module while the tests are discovered
+BeforeDiscovery {
+    Import-Module (Join-Path (Split-Path $PSScriptRoot -Parent) "AICommit.psd1") -Force
+}
+
+AfterAll {
+    Remove-Module AICommit -Force -ErrorAction SilentlyContinue
+}
+
+InModuleScope AICommit {
+    BeforeAll {
+        function New-TestRepository {
+            # An empty repository with one commit, entered with Push-Location
+            $path = Join-Path ([System.IO.Path]::GetTempPath()) ("aicommit-test-" + [guid]::NewGuid().ToString("N").Substring(0, 8))
+            New-Item -ItemType Directory -Path $path -Force | Out-Null
+            Push-Location $path
+            git init -q 2>&1 | Out-Null
+            git config user.email "test@example.com"
+            git config user.name "aicommit test"
+            git config commit.gpgsign false
+            Set-Content -Path "readme.txt" -Value "first line" -Encoding UTF8
+            New-Item -ItemType Directory -Path "src" -Force | Out-Null
+            Set-Content -Path "src/app.txt" -Value @("one", "two", "three") -Encoding UTF8
+            git add -A 2>&1 | Out-Null
+            git commit -q -m "Initial commit" 2>&1 | Out-Null
+            return $path
+        }
+
+        function Remove-TestRepository {
+            param([string]$Path)
+
+            Pop-Location
+            Remove-Item -Path $Path -Recurse -Force -ErrorAction SilentlyContinue
+        }
+    }
+
+    Describe "ConvertFrom-AICommitDiff" {
+        It "reads a <Name> file" -ForEach @(
+            @{
+                Name = "modified"; Path = "a.txt"; OldPath = "a.txt"; Status = "modified"; Binary = $false; Added = 1; Removed = 1
+                Diff = "diff --git a/a.txt b/a.txt`nindex 1111111..2222222 100644`n--- a/a.txt`n+++ b/a.txt`n@@ -1,2 +1,2 @@`n-old`n+new`n same"
+            }
+            @{
+                Name = "added"; Path = "b.txt"; OldPath = "b.txt"; Status = "added"; Binary = $false; Added = 2; Removed = 0
+                Diff = "diff --git a/b.txt b/b.txt`nnew file mode 100644`nindex 0000000..3333333`n--- /dev/null`n+++ b/b.txt`n@@ -0,0 +1,2 @@`n+one`n+two"
+            }
+            @{
+                Name = "deleted"; Path = "c.txt"; OldPath = "c.txt"; Status = "deleted"; Binary = $false; Added = 0; Removed = 1
+                Diff = "diff --git a/c.txt b/c.txt`ndeleted file mode 100644`nindex 4444444..0000000`n--- a/c.txt`n+++ /dev/null`n@@ -1 +0,0 @@`n-gone"
+            }
+            @{
+                Name = "renamed"; Path = "new/d.txt"; OldPath = "old/d.txt"; Status = "renamed"; Binary = $false; Added = 0; Removed = 0
+                Diff = "diff --git a/old/d.txt b/new/d.txt`nsimilarity index 100%`nrename from old/d.txt`nrename to new/d.txt"
+            }
+            @{
+                Name = "binary"; Path = "e.png"; OldPath = "e.png"; Status = "added"; Binary = $true; Added = 0; Removed = 0
+                Diff = "diff --git a/e.png b/e.png`nnew file mode 100644`nindex 0000000..5555555`nBinary files /dev/null and b/e.png differ"
+            }
+        ) {
+            $files = ConvertFrom-AICommitDiff -Diff $Diff
+            $files.Count | Should -Be 1
+            $files[0].Path | Should -Be $Path
+            $files[0].OldPath | Should -Be $OldPath
+            $files[0].Status | Should -Be $Status
+            $files[0].Binary | Should -Be $Binary
+            $files[0].Added | Should -Be $Added
+            $files[0].Removed | Should -Be $Removed
+        }
+
+        It "keeps the function context of each hunk" {
+            $diff = "diff --git a/f.ps1 b/f.ps1`n--- a/f.ps1`n+++ b/f.ps1`n@@ -10,3 +10,3 @@ function Get-Thing {`n-old`n+new`n@@ -40,2 +40,3 @@ function Set-Thing {`n+added"
+            $files = ConvertFrom-AICommitDiff -Diff $diff
+            $files[0].Hunks.Count | Should -Be 2
+            $files[0].Hunks[0].Context | Should -Be "function Get-Thing {"
+            $files[0].Hunks[1].NewStart | Should -Be 40
+            $files[0].Hunks[1].NewLines | Should -Be 3
+        }
+
+        It "returns an empty list for an empty diff" {
+            $files = ConvertFrom-AICommitDiff -Diff ""
+            $files.Count | Should -Be 0
+        }
+    }
+
+    Describe "Format-AICommitDiff" {
+        BeforeAll {
+            $diff = "diff --git a/a.txt b/a.txt`n--- a/a.txt`n+++ b/a.txt`n@@ -1 +1 @@`n-old`n+new`n@@ -9 +9 @@`n-nine`n+NINE`ndiff --git a/b.txt b/b.txt`n--- a/b.txt`n+++ b/b.txt`n@@ -1 +1 @@`n-x`n+y`n"
+        }
+
+        It "renders parsed files back into the same diff" {
+            Format-AICommitDiff -Files (ConvertFrom-AICommitDiff -Diff $diff) | Should -Be $diff
+        }
+
+        It "renders only the chosen hunks and skips files without any" {
+            $files = ConvertFrom-AICommitDiff -Diff $diff
+            $text = Format-AICommitDiff -Files $files -Hunks @($files[0].Hunks[1])
+            $text | Should -Be "diff --git a/a.txt b/a.txt`n--- a/a.txt`n+++ b/a.txt`n@@ -9 +9 @@`n-nine`n+NINE`n"
+        }
+    }
+
+    Describe "Get-AICommitFullDiff" {
+        BeforeEach {
+            $repository = New-TestRepository
+        }
+
+        AfterEach {
+            Remove-TestRepository -Path $repository
+        }
+
+        It "includes untracked files without staging them" {
+            Set-Content -Path "new.txt" -Value "brand new" -Encoding UTF8
+            $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff)
+            ($files | Where-Object { $_.Path -eq "new.txt" }).Status | Should -Be "added"
+            @(git diff --cached --name-only) | Should -BeNullOrEmpty
+            @(git ls-files --others --exclude-standard) | Should -Contain "new.txt"
+        }
+
+        It "detects a moved file as a rename" {
+            git mv src/app.txt src/main.txt 2>&1 | Out-Null
+            $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff)
+            $files.Count | Should -Be 1
+            $files[0].Status | Should -Be "renamed"
+            $files[0].OldPath | Should -Be "src/app.txt"
+            $files[0].Path | Should -Be "src/main.txt"
+        }
+
+        It "marks binary files" {
+            [System.IO.File]::WriteAllBytes((Join-Path (Get-Location).ProviderPath "image.bin"), [byte[]](0, 1, 2, 0, 255))
+            $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff)
+            ($files | Where-Object { $_.Path -eq "image.bin" }).Binary | Should -BeTrue
+        }
+
+        It "never includes .aicommit.env" {
+            Set-Content -Path ".aicommit.env" -Value "ANTHROPIC_API_KEY=secret" -Encoding UTF8
+            Get-AICommitFullDiff | Should -Not -Match "aicommit\.env"
+        }
+
+        It "covers the whole repository from a subdirectory" {
+            Add-Content -Path "readme.txt" -Value "second line" -Encoding UTF8
+            Push-Location "src"
+            try {
+                $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff)
+            }
+            finally {
+                Pop-Location
+            }
+            $files.Path | Should -Contain "readme.txt"
+        }
+
+        It "returns only the staged changes with -Staged" {
+            Add-Content -Path "readme.txt" -Value "staged" -Encoding UTF8
+            Add-Content -Path "src/app.txt" -Value "not staged" -Encoding UTF8
+            git add readme.txt 2>&1 | Out-Null
+            $files = ConvertFrom-AICommitDiff -Diff (Get-AICommitFullDiff -Staged)
+            @($files.Path) -join "," | Should -Be "readme.txt"
+        }
+    }
+
+    Describe "Add-AICommitChanges" {
+        BeforeEach {
+            $repository = New-TestRepository
+            Add-Content -Path "readme.txt" -Value "second line" -Encoding UTF8
+            Add-Content -Path "src/app.txt" -Value "four" -Encoding UTF8
+            Set-Content -Path ".aicommit.env" -Value "ANTHROPIC_API_KEY=secret" -Encoding UTF8
+        }
+
+        AfterEach {
+            Remove-TestRepository -Path $repository
+        }
+
+        It "stages <Name>" -ForEach @(
+            @{ Name = "everything but .aicommit.env"; Location = "."; Paths = @(); Expected = @("readme.txt", "src/app.txt") }
+            @{ Name = "everything from a subdirectory"; Location = "src"; Paths = @(); Expected = @("readme.txt", "src/app.txt") }
+            @{ Name = "only the given paths"; Location = "."; Paths = @("src/app.txt"); Expected = @("src/app.txt") }
+        ) {
+            Push-Location $Location
+            try {
+                Add-AICommitChanges -Paths $Paths
+            }
+            finally {
+                Pop-Location
+            }
+            @(git diff --cached --name-only | Sort-Object) -join "," | Should -Be ($Expected -join ",")
+        }
+    }
+
+    Describe "Invoke-AICommitGuardedCommit" {
+        BeforeEach {
+            $repository = New-TestRepository
+            Add-Content -Path "readme.txt" -Value "second line" -Encoding UTF8
+        }
+
+        AfterEach {
+            Remove-TestRepository -Path $repository
+        }
+
+        It "stages and commits with the message" {
+            Invoke-AICommitGuardedCommit -Message "Add a second line`n`nMore detail" -Stage | Should -BeTrue
+            "$(git log -1 --format=%s)".Trim() | Should -Be "Add a second line"
+            @(git status --porcelain) | Should -BeNullOrEmpty
+            $script:AICommitCleanup.Count | Should -Be 0
+        }
+
+        It "reports a commit stopped by a hook and leaves HEAD alone" {
+            $before = "$(git rev-parse HEAD)".Trim()
+            $hook = Join-Path (git rev-parse --git-dir) "hooks/pre-commit"
+            Set-Content -Path $hook -Value @("#!/bin/sh", "exit 1") -Encoding ASCII
+            if (Get-Command chmod -ErrorAction SilentlyContinue) {
+                chmod +x $hook
+            }
+            Invoke-AICommitGuardedCommit -Message "Add a second line" -Stage | Should -BeFalse
+            "$(git rev-parse HEAD)".Trim() | Should -Be $before
+            $script:AICommitCleanup.Count | Should -Be 0
+        }
+    }
+}